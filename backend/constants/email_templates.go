@@ -4,12 +4,23 @@ package constants
 const (
 	TemplateTypeEvent   = "event"
 	TemplateTypeWelcome = "welcome"
+	TemplateTypeRelease = "release"
+	TemplateTypeDigest  = "digest"
+)
+
+// DigestItemsBlockStart and DigestItemsBlockEnd delimit the block of a digest
+// template repeated for each item
+const (
+	DigestItemsBlockStart = "{{#items}}"
+	DigestItemsBlockEnd   = "{{/items}}"
 )
 
 // Email template subjects
 const (
 	SubjectEvent   = "{{status}}: {{event_name}} - {{project_name}}"
 	SubjectWelcome = "Welcome to {{project_name}}!"
+	SubjectRelease = "{{release_name}} ({{release_version}}) - {{project_name}}"
+	SubjectDigest  = "Your {{digest_period}} digest - {{project_name}}"
 )
 
 // Email template content
@@ -42,7 +53,8 @@ const (
     <div style="text-align: center; font-size: 12px; color: #666;">
         <p style="margin: 5px 0;">
             <a href="{{project_url}}" style="color: #2563eb; text-decoration: none;">{{project_name}}</a>
-            <br><a href="{{unsubscribe_url}}" style="color: #2563eb; text-decoration: none;">Unsubscribe</a>
+            <br><a href="{{preferences_url}}" style="color: #2563eb; text-decoration: none;">{{preferences_text}}</a>
+            · <a href="{{unsubscribe_url}}" style="color: #2563eb; text-decoration: none;">{{unsubscribe_text}}</a>
         </p>
     </div>
 </body>`
@@ -63,7 +75,75 @@ const (
     <div style="text-align: center; font-size: 12px; color: #666;">
         <p style="margin: 5px 0;">
             <a href="{{project_url}}" style="color: #2563eb; text-decoration: none;">{{project_name}}</a>
-            <br><a href="{{unsubscribe_url}}" style="color: #2563eb; text-decoration: none;">Unsubscribe</a>
+            <br><a href="{{unsubscribe_url}}" style="color: #2563eb; text-decoration: none;">{{unsubscribe_text}}</a>
+        </p>
+    </div>
+</body>`
+
+	TemplateRelease = `<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; padding: 20px;">
+    <h1 style="color: #3B82F6; text-align: center; font-size: 28px; font-weight: bold; margin: 20px 0;">Version {{release_version}}</h1>
+
+    <div style="padding: 20px; margin-bottom: 20px;">
+        <h2 style="color: #000000; margin-top: 0; font-size: 36px; font-weight: bold; margin-bottom: 15px; text-align: center;">{{release_name}}</h2>
+
+        <div style="margin-bottom: 20px; text-align: center; color: #6b7280; font-size: 14px;">
+            {{release_date}}
+        </div>
+
+        <div style="margin: 15px 0; font-size: 16px; line-height: 1.6;">
+            {{release_notes}}
+        </div>
+
+        <div style="margin: 25px 0;">
+            {{release_events}}
+        </div>
+
+        <div style="text-align: center; margin-top: 30px;">
+            <a href="{{release_url}}" style="background: #3b82f6; color: white; padding: 14px 28px; text-decoration: none; border-radius: 6px; display: inline-block; font-weight: bold; font-size: 16px;">See Release</a>
+        </div>
+    </div>
+
+    <hr style="border: none; border-top: 1px solid #eee; margin: 30px 0;">
+
+    <div style="text-align: center; font-size: 12px; color: #666;">
+        <p style="margin: 5px 0;">
+            <a href="{{project_url}}" style="color: #2563eb; text-decoration: none;">{{project_name}}</a>
+            <br><a href="{{preferences_url}}" style="color: #2563eb; text-decoration: none;">{{preferences_text}}</a>
+            · <a href="{{unsubscribe_url}}" style="color: #2563eb; text-decoration: none;">{{unsubscribe_text}}</a>
+        </p>
+    </div>
+</body>`
+
+	TemplateDigest = `<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; padding: 20px;">
+    <h1 style="color: #3B82F6; text-align: center; font-size: 28px; font-weight: bold; margin: 20px 0;">{{project_name}}</h1>
+
+    <div style="padding: 20px; margin-bottom: 20px;">
+        <h2 style="color: #000000; margin-top: 0; font-size: 24px; font-weight: bold; margin-bottom: 5px; text-align: center;">What's new</h2>
+
+        <div style="margin-bottom: 25px; text-align: center; color: #6b7280; font-size: 14px;">
+            {{digest_start}} - {{digest_end}}
+        </div>
+
+        {{#items}}
+        <div style="border-bottom: 1px solid #eee; padding: 15px 0;">
+            <div style="color: #6b7280; font-size: 12px; font-weight: 600; text-transform: uppercase; margin-bottom: 4px;">{{event_status}}</div>
+            <a href="{{event_url}}" style="color: #000000; font-size: 18px; font-weight: bold; text-decoration: none;">{{event_name}}</a>
+            <div style="margin-top: 6px;">{{event_tags}} {{event_date}}</div>
+        </div>
+        {{/items}}
+
+        <div style="text-align: center; margin-top: 30px;">
+            <a href="{{changelog_url}}" style="background: #3b82f6; color: white; padding: 14px 28px; text-decoration: none; border-radius: 6px; display: inline-block; font-weight: bold; font-size: 16px;">See All Updates</a>
+        </div>
+    </div>
+
+    <hr style="border: none; border-top: 1px solid #eee; margin: 30px 0;">
+
+    <div style="text-align: center; font-size: 12px; color: #666;">
+        <p style="margin: 5px 0;">
+            <a href="{{project_url}}" style="color: #2563eb; text-decoration: none;">{{project_name}}</a>
+            <br><a href="{{preferences_url}}" style="color: #2563eb; text-decoration: none;">{{preferences_text}}</a>
+            · <a href="{{unsubscribe_url}}" style="color: #2563eb; text-decoration: none;">{{unsubscribe_text}}</a>
         </p>
     </div>
 </body>`
@@ -89,6 +169,16 @@ func GetDefaultTemplates() []EmailTemplateData {
 			Subject: SubjectWelcome,
 			Content: TemplateWelcome,
 		},
+		{
+			Type:    TemplateTypeRelease,
+			Subject: SubjectRelease,
+			Content: TemplateRelease,
+		},
+		{
+			Type:    TemplateTypeDigest,
+			Subject: SubjectDigest,
+			Content: TemplateDigest,
+		},
 	}
 }
 
@@ -1,6 +1,8 @@
 package database
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
 	"os"
 
@@ -63,11 +65,18 @@ func migrate() error {
 		log.Printf("Warning: Failed to cleanup removed columns and tables: %v", err)
 	}
 
+	// Let several email templates share a type, before AutoMigrate recreates the type index
+	if err := migrateEmailTemplatesToNamed(DB); err != nil {
+		log.Printf("Warning: Failed to migrate email templates: %v", err)
+	}
+
 	// Auto-migrate the schema
 	if err := DB.AutoMigrate(
+		&models.TagGroup{},
 		&models.Tag{},
 		&models.EventStatusDefinition{},
 		&models.Event{},
+		&models.EventTranslation{},
 		&models.EventPublication{},
 		&models.EventEmailHistory{},
 		&models.ProjectSettings{},
@@ -76,10 +85,17 @@ func migrate() error {
 		&models.MailSettings{},
 		&models.NewsletterSubscriber{},
 		&models.NewsletterHistory{},
+		&models.EmailDelivery{},
+		&models.HashKey{},
 		&models.EmailTemplate{},
+		&models.EmailTemplateTranslation{},
 		&models.NewsletterAutomationSettings{},
 		&models.StatusCategoryMapping{},
 		&models.ThemeSettingValue{},
+		&models.StatusTransition{},
+		&models.StatusWorkflowSettings{},
+		&models.EventStatusChange{},
+		&models.Release{},
 	); err != nil {
 		// If AutoMigrate fails on project_settings, it's likely corrupted
 		log.Printf("AutoMigrate failed: %v", err)
@@ -91,6 +107,16 @@ func migrate() error {
 		}
 	}
 
+	// Subscribers who signed up through the form before consent was recorded agreed when they subscribed
+	if err := migrateSubscriberConsent(DB); err != nil {
+		log.Printf("Warning: Failed to record subscriber consent: %v", err)
+	}
+
+	// Encrypt secrets stored in plaintext by earlier versions
+	if err := encryptPlaintextSecrets(DB); err != nil {
+		log.Printf("Warning: Failed to encrypt stored secrets: %v", err)
+	}
+
 	// Initialize default email templates
 	if err := models.InitializeDefaultEmailTemplates(DB); err != nil {
 		log.Printf("Warning: Failed to initialize default email templates: %v", err)
@@ -98,9 +124,19 @@ func migrate() error {
 		log.Println("Successfully initialized default email templates")
 	}
 
-	// Seed status definitions (reserved + legacy)
-	if err := models.SeedStatusDefinitions(DB); err != nil {
-		log.Printf("Warning: Failed to seed status definitions: %v", err)
+	// Let the footer links of stored templates follow each email's language
+	if err := migrateEmailTemplateLinkLabels(DB); err != nil {
+		log.Printf("Warning: Failed to localize email template links: %v", err)
+	}
+
+	// Move events and automation triggers from status names to status definition IDs
+	if err := migrateEventStatusesToIDs(DB); err != nil {
+		log.Printf("Warning: Failed to migrate event statuses to IDs: %v", err)
+	}
+
+	// Replace free-form event dates with a structured date and precision
+	if err := migrateEventDates(DB); err != nil {
+		log.Printf("Warning: Failed to migrate event dates: %v", err)
 	}
 
 	// Ensure newsletter automation settings table exists (manual fallback)
@@ -188,6 +224,56 @@ func createNewsletterAutomationTableIfNotExists(db *gorm.DB) error {
 	return nil
 }
 
+// migrateEmailTemplatesToNamed drops the unique index on email template types, which allowed a
+// single template per type, and marks the existing templates as the defaults of their type
+func migrateEmailTemplatesToNamed(db *gorm.DB) error {
+	var uniqueIndexCount int64
+	err := db.Raw("SELECT count(*) FROM sqlite_master WHERE type='index' AND name='idx_email_templates_type' AND sql LIKE 'CREATE UNIQUE%'").Scan(&uniqueIndexCount).Error
+	if err != nil || uniqueIndexCount == 0 {
+		return err
+	}
+
+	log.Println("Migrating email templates to named templates...")
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DROP INDEX idx_email_templates_type").Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("ALTER TABLE email_templates ADD COLUMN is_default numeric NOT NULL DEFAULT false").Error; err != nil {
+			return err
+		}
+		return tx.Exec("UPDATE email_templates SET is_default = true").Error
+	})
+}
+
+// migrateEmailTemplateLinkLabels replaces the English link texts in the footer of stored
+// templates with placeholders, so they follow the language of each email
+func migrateEmailTemplateLinkLabels(db *gorm.DB) error {
+	result := db.Exec(`UPDATE email_templates SET content = REPLACE(REPLACE(content,
+		'text-decoration: none;">Unsubscribe</a>', 'text-decoration: none;">{{unsubscribe_text}}</a>'),
+		'text-decoration: none;">Manage preferences</a>', 'text-decoration: none;">{{preferences_text}}</a>')
+		WHERE content LIKE '%text-decoration: none;">Unsubscribe</a>%' OR content LIKE '%text-decoration: none;">Manage preferences</a>%'`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Localized the footer links of %d email templates", result.RowsAffected)
+	}
+	return nil
+}
+
+// migrateSubscriberConsent sets the consent time of form subscribers who have none to their subscription time
+func migrateSubscriberConsent(db *gorm.DB) error {
+	result := db.Exec("UPDATE newsletter_subscribers SET consent_at = subscribed_at WHERE consent_at IS NULL AND source = ? AND is_active = ?",
+		models.SubscriberSourceForm, true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Recorded the consent time of %d newsletter subscribers", result.RowsAffected)
+	}
+	return nil
+}
+
 // cleanupRemovedColumnsAndTables removes deprecated tables and columns
 func cleanupRemovedColumnsAndTables(db *gorm.DB) error {
 	// Drop footer_links table if it exists
@@ -283,6 +369,142 @@ func cleanupRemovedColumnsAndTables(db *gorm.DB) error {
 	return nil
 }
 
+// migrateEventDates converts the free-form events.date column into a structured date and precision.
+// Dates that can't be read unambiguously are kept in events.legacy_date for an admin to fix.
+func migrateEventDates(db *gorm.DB) error {
+	var columnCount int64
+	if err := db.Raw("SELECT count(*) FROM pragma_table_info('events') WHERE name = 'date'").Scan(&columnCount).Error; err != nil {
+		return err
+	}
+	if columnCount == 0 {
+		return nil
+	}
+
+	log.Println("Migrating event dates to structured dates...")
+
+	type legacyDate struct {
+		ID   uint
+		Date string
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Raw SQL so soft-deleted events are migrated too
+		var rows []legacyDate
+		if err := tx.Raw("SELECT id, date FROM events WHERE date IS NOT NULL AND TRIM(date) != ''").Scan(&rows).Error; err != nil {
+			return err
+		}
+
+		migrated := 0
+		for _, row := range rows {
+			date, err := models.ParseLegacyEventDate(row.Date)
+			if err != nil {
+				log.Printf("Warning: Could not read date %q of event %d (%v), keeping it as its legacy date", row.Date, row.ID, err)
+				if err := tx.Exec("UPDATE events SET legacy_date = ? WHERE id = ?", row.Date, row.ID).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if err := tx.Exec("UPDATE events SET date_at = ?, date_precision = ? WHERE id = ?", *date.At, date.Precision, row.ID).Error; err != nil {
+				return err
+			}
+			migrated++
+		}
+
+		if err := tx.Exec("ALTER TABLE events DROP COLUMN date").Error; err != nil {
+			return err
+		}
+
+		log.Printf("✓ Migrated %d of %d event dates", migrated, len(rows))
+		return nil
+	})
+}
+
+// migrateEventStatusesToIDs converts the legacy events.status name column to status_id
+// and the automation trigger list from status names to status definition IDs
+func migrateEventStatusesToIDs(db *gorm.DB) error {
+	var columnCount int64
+	if err := db.Raw("SELECT count(*) FROM pragma_table_info('events') WHERE name = 'status'").Scan(&columnCount).Error; err != nil {
+		return err
+	}
+
+	if columnCount > 0 {
+		log.Println("Migrating events from status names to status IDs...")
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var names []string
+			if err := tx.Raw("SELECT DISTINCT status FROM events WHERE status IS NOT NULL AND status != ''").Scan(&names).Error; err != nil {
+				return err
+			}
+
+			for _, name := range names {
+				def, err := models.GetOrCreateStatusDefinition(tx, name)
+				if err != nil {
+					return fmt.Errorf("failed to resolve status %q: %w", name, err)
+				}
+				// Raw SQL so soft-deleted events are migrated too
+				if err := tx.Exec("UPDATE events SET status_id = ? WHERE status = ?", def.ID, name).Error; err != nil {
+					return err
+				}
+			}
+
+			// Keep the names if any event was left without a status ID, rather than lose its status
+			var unresolved int64
+			if err := tx.Raw("SELECT count(*) FROM events WHERE status IS NOT NULL AND status != '' AND (status_id IS NULL OR status_id = 0)").Scan(&unresolved).Error; err != nil {
+				return err
+			}
+			if unresolved > 0 {
+				return fmt.Errorf("%d events have no status ID after the back-fill, keeping the status column", unresolved)
+			}
+
+			return tx.Exec("ALTER TABLE events DROP COLUMN status").Error
+		})
+		if err != nil {
+			return err
+		}
+
+		log.Println("✓ Migrated events to status IDs")
+	}
+
+	// Trigger statuses used to be stored as a JSON array of names
+	var automationSettings []models.NewsletterAutomationSettings
+	if err := db.Find(&automationSettings).Error; err != nil {
+		return err
+	}
+
+	for _, settings := range automationSettings {
+		var entries []interface{}
+		if err := json.Unmarshal([]byte(settings.TriggerStatuses), &entries); err != nil {
+			continue
+		}
+
+		hasNames := false
+		ids := []uint{}
+		for _, entry := range entries {
+			switch value := entry.(type) {
+			case float64:
+				ids = append(ids, uint(value))
+			case string:
+				hasNames = true
+				def, err := models.ResolveStatusRef(db, models.StatusRef{Name: value}, false)
+				if err != nil {
+					log.Printf("Warning: Dropping unknown automation trigger status %q", value)
+					continue
+				}
+				ids = append(ids, def.ID)
+			}
+		}
+
+		if hasNames {
+			if err := db.Model(&settings).Update("trigger_statuses", models.EncodeTriggerStatusIDs(ids)).Error; err != nil {
+				return err
+			}
+			log.Println("✓ Migrated newsletter automation trigger statuses to status IDs")
+		}
+	}
+
+	return nil
+}
+
 func GetDB() *gorm.DB {
 	return DB
 }
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"fmt"
+	"log"
+
+	"shipshipship/models"
+	"shipshipship/utils"
+
+	"gorm.io/gorm"
+)
+
+// secretRow is one stored value of a secret column
+type secretRow struct {
+	ID    uint
+	Value string
+}
+
+// loadSecretRows returns the non-empty values of a secret column
+func loadSecretRows(db *gorm.DB, column models.SecretColumn) ([]secretRow, error) {
+	var rows []secretRow
+	err := db.Table(column.Table).
+		Select(fmt.Sprintf("id, %s AS value", column.Column)).
+		Where(fmt.Sprintf("%s IS NOT NULL AND %s != ''", column.Column, column.Column)).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// encryptPlaintextSecrets encrypts secrets stored before encryption at rest was added
+func encryptPlaintextSecrets(db *gorm.DB) error {
+	encrypted := 0
+	for _, column := range models.SecretColumns {
+		rows, err := loadSecretRows(db, column)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if utils.IsEncryptedSecret(row.Value) {
+				continue
+			}
+			value, err := utils.EncryptSecret(row.Value)
+			if err != nil {
+				return err
+			}
+			if err := db.Table(column.Table).Where("id = ?", row.ID).UpdateColumn(column.Column, value).Error; err != nil {
+				return err
+			}
+			encrypted++
+		}
+	}
+
+	if encrypted > 0 {
+		log.Printf("Encrypted %d stored secrets", encrypted)
+	}
+	return nil
+}
+
+// RekeySecrets moves every stored secret from the old master key to the new one, in one transaction.
+// Only the per-value data keys are re-encrypted. Returns the number of values rewrapped.
+func RekeySecrets(db *gorm.DB, oldKey, newKey []byte) (int, error) {
+	rewrapped := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, column := range models.SecretColumns {
+			rows, err := loadSecretRows(tx, column)
+			if err != nil {
+				return err
+			}
+			for _, row := range rows {
+				var value string
+				if utils.IsEncryptedSecret(row.Value) {
+					value, err = utils.RewrapSecret(row.Value, oldKey, newKey)
+				} else {
+					value, err = utils.EncryptSecretWithKey(row.Value, newKey)
+				}
+				if err != nil {
+					return fmt.Errorf("%s.%s of row %d: %v", column.Table, column.Column, row.ID, err)
+				}
+				if value == row.Value {
+					continue
+				}
+				if err := tx.Table(column.Table).Where("id = ?", row.ID).UpdateColumn(column.Column, value).Error; err != nil {
+					return err
+				}
+				rewrapped++
+			}
+		}
+		return nil
+	})
+	return rewrapped, err
+}
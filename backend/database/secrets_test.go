@@ -0,0 +1,71 @@
+package database
+
+import (
+	"testing"
+
+	"shipshipship/models"
+	"shipshipship/utils"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestRekeySecrets(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.MailSettings{}, &models.HashKey{}); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := make([][]byte, 3)
+	for i := range keys {
+		encoded, _ := utils.GenerateSecretsKey()
+		keys[i], _ = utils.ParseSecretsKey(encoded)
+	}
+	oldKey, newKey, otherKey := keys[0], keys[1], keys[2]
+
+	password, _ := utils.EncryptSecretWithKey("smtp-password", oldKey)
+	if err := db.Exec("INSERT INTO mail_settings (id, smtp_password, api_key) VALUES (1, ?, ?)", password, "plaintext-api-key").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// A value the old key can't open aborts the whole rekey
+	stray, _ := utils.EncryptSecretWithKey("stray", otherKey)
+	db.Exec("INSERT INTO mail_settings (id, smtp_password) VALUES (2, ?)", stray)
+	if _, err := RekeySecrets(db, oldKey, newKey); err == nil {
+		t.Fatal("RekeySecrets succeeded with a value under another key")
+	}
+	var unchanged string
+	db.Raw("SELECT smtp_password FROM mail_settings WHERE id = 1").Scan(&unchanged)
+	if unchanged != password {
+		t.Fatal("a failed rekey changed stored values")
+	}
+	db.Exec("DELETE FROM mail_settings WHERE id = 2")
+
+	count, err := RekeySecrets(db, oldKey, newKey)
+	if err != nil {
+		t.Fatalf("RekeySecrets: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("RekeySecrets rewrapped %d values, want 2", count)
+	}
+
+	var row struct {
+		SMTPPassword string
+		APIKey       string
+	}
+	db.Raw("SELECT smtp_password, api_key FROM mail_settings WHERE id = 1").Scan(&row)
+	for stored, want := range map[string]string{row.SMTPPassword: "smtp-password", row.APIKey: "plaintext-api-key"} {
+		if value, err := utils.DecryptSecretWithKey(stored, newKey); err != nil || value != want {
+			t.Errorf("after rekey, got %q, %v, want %q", value, err, want)
+		}
+	}
+
+	// Running it again finds nothing left to do
+	if count, err := RekeySecrets(db, oldKey, newKey); err != nil || count != 0 {
+		t.Errorf("second RekeySecrets = %d, %v, want 0", count, err)
+	}
+}
@@ -0,0 +1,122 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"net/url"
+
+	"shipshipship/models"
+)
+
+// confirmationText is the wording of the email asking an imported subscriber to confirm.
+// Subject and Intro take the project name.
+type confirmationText struct {
+	Subject string
+	Intro   string
+	Button  string
+	Ignore  string
+}
+
+var confirmationTexts = map[string]confirmationText{
+	"en": {
+		Subject: "Confirm your subscription to %s",
+		Intro:   "You have been added to the %s newsletter. Please confirm that you want to receive it.",
+		Button:  "Confirm subscription",
+		Ignore:  "If you don't confirm, you won't receive any emails from us.",
+	},
+	"fr": {
+		Subject: "Confirmez votre abonnement à %s",
+		Intro:   "Vous avez été ajouté à la newsletter de %s. Merci de confirmer que vous souhaitez la recevoir.",
+		Button:  "Confirmer l'abonnement",
+		Ignore:  "Sans confirmation de votre part, vous ne recevrez aucun email.",
+	},
+	"de": {
+		Subject: "Bestätigen Sie Ihr Abonnement von %s",
+		Intro:   "Sie wurden zum Newsletter von %s hinzugefügt. Bitte bestätigen Sie, dass Sie ihn erhalten möchten.",
+		Button:  "Abonnement bestätigen",
+		Ignore:  "Ohne Bestätigung erhalten Sie keine E-Mails von uns.",
+	},
+	"es": {
+		Subject: "Confirma tu suscripción a %s",
+		Intro:   "Te hemos añadido al boletín de %s. Confirma que quieres recibirlo.",
+		Button:  "Confirmar suscripción",
+		Ignore:  "Si no confirmas, no recibirás ningún correo nuestro.",
+	},
+	"it": {
+		Subject: "Conferma la tua iscrizione a %s",
+		Intro:   "Sei stato aggiunto alla newsletter di %s. Conferma di volerla ricevere.",
+		Button:  "Conferma iscrizione",
+		Ignore:  "Se non confermi, non riceverai alcuna email da parte nostra.",
+	},
+	"pt": {
+		Subject: "Confirme a sua subscrição de %s",
+		Intro:   "Foi adicionado à newsletter de %s. Confirme que a pretende receber.",
+		Button:  "Confirmar subscrição",
+		Ignore:  "Se não confirmar, não receberá nenhum email nosso.",
+	},
+	"nl": {
+		Subject: "Bevestig je inschrijving op %s",
+		Intro:   "Je bent toegevoegd aan de nieuwsbrief van %s. Bevestig dat je deze wilt ontvangen.",
+		Button:  "Inschrijving bevestigen",
+		Ignore:  "Zonder bevestiging ontvang je geen e-mails van ons.",
+	},
+}
+
+var confirmationTemplate = htmltemplate.Must(htmltemplate.New("confirmation").Parse(`<!DOCTYPE html>
+<html lang="{{.Locale}}">
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"></head>
+<body style="margin: 0; padding: 24px; font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; color: #111827;">
+<div style="max-width: 560px; margin: 0 auto;">
+<h1 style="font-size: 20px;">{{.ProjectName}}</h1>
+<p style="font-size: 16px; line-height: 1.5;">{{.Intro}}</p>
+<p style="margin: 24px 0;"><a href="{{.ConfirmURL}}" style="display: inline-block; padding: 12px 20px; background: #111827; color: #ffffff; border-radius: 6px; text-decoration: none;">{{.Button}}</a></p>
+<p style="font-size: 14px; color: #6b7280;">{{.Ignore}}</p>
+</div>
+</body>
+</html>`))
+
+// ConfirmationURL returns the signed link a pending subscriber follows to confirm their subscription
+func ConfirmationURL(baseURL, subscriberEmail string) (string, error) {
+	token, err := models.ConfirmationToken(subscriberEmail)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/newsletter/confirm?email=%s&token=%s",
+		baseURL, url.QueryEscape(subscriberEmail), token), nil
+}
+
+// NewConfirmationMessage builds the email asking a subscriber to confirm, in their language
+func NewConfirmationMessage(branding *models.BrandingSettings, subscriber *models.NewsletterSubscriber, fromName string) (*Message, error) {
+	locale := subscriber.Locale(branding.Locale)
+	text, ok := confirmationTexts[locale]
+	if !ok {
+		text = confirmationTexts[models.DefaultLocale]
+	}
+	confirmURL, err := ConfirmationURL(branding.BaseURL, subscriber.Email)
+	if err != nil {
+		return nil, err
+	}
+	intro := fmt.Sprintf(text.Intro, branding.ProjectName)
+
+	var body bytes.Buffer
+	err = confirmationTemplate.Execute(&body, map[string]interface{}{
+		"Locale":      locale,
+		"ProjectName": branding.ProjectName,
+		"Intro":       intro,
+		"ConfirmURL":  confirmURL,
+		"Button":      text.Button,
+		"Ignore":      text.Ignore,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		FromName: fromName,
+		To:       subscriber.Email,
+		Subject:  fmt.Sprintf(text.Subject, branding.ProjectName),
+		HTML:     body.String(),
+		Text:     fmt.Sprintf("%s\n\n%s: %s\n\n%s\n", intro, text.Button, confirmURL, text.Ignore),
+	}, nil
+}
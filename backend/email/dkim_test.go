@@ -0,0 +1,129 @@
+package email
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRelaxedHeader(t *testing.T) {
+	// Examples from RFC 6376, section 3.4.5
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"A: X", "a:X"},
+		{"B : Y\t\r\n\tZ  ", "b:Y Z"},
+		{"Subject:   Hello    world", "subject:Hello world"},
+		{"To:a@example.com", "to:a@example.com"},
+		{"X-Empty:", "x-empty:"},
+	}
+	for _, tt := range tests {
+		if got := relaxedHeader(tt.raw); got != tt.want {
+			t.Errorf("relaxedHeader(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestRelaxedBody(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{" C \r\nD \t E\r\n\r\n\r\n", " C\r\nD E\r\n"}, // RFC 6376, section 3.4.5
+		{"Hello", "Hello\r\n"},
+		{"a\r\n\r\nb\r\n", "a\r\n\r\nb\r\n"},
+		{"\r\n\r\n", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := string(relaxedBody([]byte(tt.body))); got != tt.want {
+			t.Errorf("relaxedBody(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+var dkimTagPattern = regexp.MustCompile(`(\w+)=([^;]*)`)
+
+// verifyDKIM checks a message signed by DKIMSigner the way a receiving server would
+func verifyDKIM(signed []byte, key *rsa.PublicKey) error {
+	fields, body := splitMessage(signed)
+	if len(fields) == 0 || !strings.EqualFold(fields[0].name, "DKIM-Signature") {
+		return errors.New("the message doesn't start with a DKIM-Signature header")
+	}
+	signature := fields[0].raw
+
+	tags := map[string]string{}
+	for _, match := range dkimTagPattern.FindAllStringSubmatch(signature[strings.Index(signature, ":")+1:], -1) {
+		tags[match[1]] = strings.NewReplacer("\r\n", "", " ", "", "\t", "").Replace(match[2])
+	}
+
+	bodyHash := sha256.Sum256(relaxedBody(body))
+	if tags["bh"] != base64.StdEncoding.EncodeToString(bodyHash[:]) {
+		return fmt.Errorf("body hash %s doesn't match the body", tags["bh"])
+	}
+
+	var signedHeaders strings.Builder
+	for _, name := range strings.Split(tags["h"], ":") {
+		for i := len(fields) - 1; i > 0; i-- {
+			if strings.EqualFold(fields[i].name, name) {
+				signedHeaders.WriteString(relaxedHeader(fields[i].raw) + "\r\n")
+				break
+			}
+		}
+	}
+	unsigned := regexp.MustCompile(`b=[A-Za-z0-9+/=\s]*$`).ReplaceAllString(signature, "b=")
+	signedHeaders.WriteString(relaxedHeader(unsigned))
+
+	decoded, err := base64.StdEncoding.DecodeString(tags["b"])
+	if err != nil {
+		return fmt.Errorf("malformed signature: %v", err)
+	}
+	digest := sha256.Sum256([]byte(signedHeaders.String()))
+	return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], decoded)
+}
+
+func TestDKIMSign(t *testing.T) {
+	privateKeyPEM, err := GenerateDKIMKey(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParseDKIMPrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := &DKIMSigner{Domain: "example.com", Selector: "sss1", PrivateKey: key}
+
+	message := "From: News <news@example.com>\nTo: reader@example.org\nSubject: A  long\n subject\nMIME-Version: 1.0\n\nHello  there \n\n\n"
+	signed, err := signer.Sign([]byte(message))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if strings.Contains(strings.ReplaceAll(string(signed), "\r\n", ""), "\n") {
+		t.Error("the signed message has bare line feeds")
+	}
+
+	tests := []struct {
+		name    string
+		message string
+		valid   bool
+	}{
+		{"as signed", string(signed), true},
+		{"whitespace collapsed by a relay", strings.Replace(string(signed), "Hello  there", "Hello there", 1), true},
+		{"header refolded by a relay", strings.Replace(string(signed), "A  long\r\n subject", "A long subject", 1), true},
+		{"body changed", strings.Replace(string(signed), "Hello", "Goodbye", 1), false},
+		{"signed header changed", strings.Replace(string(signed), "reader@example.org", "other@example.org", 1), false},
+	}
+	for _, tt := range tests {
+		err := verifyDKIM([]byte(tt.message), &key.PublicKey)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: verification error = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,573 @@
+package email
+
+// Email templates are rendered with Go's html/template (text/template for subjects and text bodies)
+// against a TemplateContext. Templates only see the plain values copied into the
+// context and the helpers in templateFuncs, so they can't reach the database or models.
+//
+// Context available to templates:
+//
+//	.Event          the event the email is about: .ID .Title .Slug .URL .Content .Date .DateISO .Tags
+//	.Tags           the event's tags, each with .Name and .Color
+//	.Status         the event's status: .ID .Name .Slug
+//	.Release        release emails: .Name .Version .Date .URL .Notes .Groups (each with .Status and .Events)
+//	.Digest         digest emails: .Period .Start .End .Items (each with .Event .Tags .Status)
+//	.Branding       .ProjectName .ProjectURL .BaseURL .ChangelogURL .Locale
+//	.Subscriber     the recipient, with .Email, .Frequency and .Language; empty when rendering for several recipients
+//	.UnsubscribeURL and .PreferencesURL
+//	.Labels         .Unsubscribe and .Preferences, the link texts in the email's language
+//
+// Helpers: upper, lower, truncate N TEXT, plaintext HTML (for plain-text bodies), tagBadge TAG.
+//
+// Templates written with the former {{placeholder}} syntax keep working: known placeholders
+// are rewritten to context values before parsing, and {{#items}}...{{/items}} becomes a range
+// over the digest items.
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"net/url"
+	"regexp"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+	"time"
+	"unicode/utf8"
+
+	"shipshipship/constants"
+	"shipshipship/models"
+)
+
+// maxRenderedSize bounds the output of a template so a runaway loop can't exhaust memory
+const maxRenderedSize = 2 << 20
+
+// maxTemplateSteps bounds the loop iterations and template calls of a render, so a loop that
+// writes nothing, such as {{range 1000000000}}{{end}}, can't run for minutes either
+const maxTemplateSteps = 100000
+
+// stepFunc is the function counting steps, called at the start of every loop body and template
+const stepFunc = "_step"
+
+// Personalization placeholders left in emails rendered for several recipients,
+// filled in per subscriber by PersonalizeContent
+const (
+	unsubscribePlaceholder = "{{unsubscribe_url}}"
+	preferencesPlaceholder = "{{preferences_url}}"
+)
+
+// TemplateContext is the data email templates are rendered with
+type TemplateContext struct {
+	Event          *EventContext
+	Tags           []TagContext
+	Status         *StatusContext
+	Release        *ReleaseContext
+	Digest         *DigestContext
+	Branding       BrandingContext
+	Subscriber     *SubscriberContext
+	UnsubscribeURL htmltemplate.URL
+	PreferencesURL htmltemplate.URL
+	Labels         LabelsContext
+	Legacy         map[string]interface{} // Values of the legacy {{placeholders}}, filled in at render time
+}
+
+// EventContext describes an event
+type EventContext struct {
+	ID      uint
+	Title   string
+	Slug    string
+	URL     string
+	Content htmltemplate.HTML // Sanitized when the event is saved
+	Date    string            // Formatted in the project locale, empty when the event has no date
+	DateISO string
+	Tags    []TagContext
+}
+
+// TagContext describes a tag
+type TagContext struct {
+	Name  string
+	Color string
+}
+
+// StatusContext describes an event status
+type StatusContext struct {
+	ID   uint
+	Name string
+	Slug string
+}
+
+// ReleaseContext describes a release
+type ReleaseContext struct {
+	Name    string
+	Version string
+	Date    string
+	URL     string
+	Notes   htmltemplate.HTML
+	Groups  []ReleaseGroupContext
+	events  htmltemplate.HTML // Pre-rendered event list for the legacy placeholder
+	dateTag htmltemplate.HTML
+}
+
+// ReleaseGroupContext lists a release's events sharing a status
+type ReleaseGroupContext struct {
+	Status string
+	Events []EventContext
+}
+
+// DigestContext describes a digest period and its items
+type DigestContext struct {
+	Period string // weekly or monthly
+	Start  string
+	End    string
+	Items  []DigestItemContext
+}
+
+// DigestItemContext is an event that reached a status during the digest period
+type DigestItemContext struct {
+	Event  EventContext
+	Tags   []TagContext
+	Status StatusContext
+	Legacy map[string]interface{} // Values of the legacy item placeholders
+}
+
+// BrandingContext describes the project
+type BrandingContext struct {
+	ProjectName  string
+	ProjectURL   string // External website
+	BaseURL      string // This instance
+	ChangelogURL string
+	Locale       string // Language of the email, used for dates and labels
+}
+
+// SubscriberContext describes the recipient
+type SubscriberContext struct {
+	Email     string
+	Frequency string
+	Language  string
+}
+
+var templateFuncs = map[string]interface{}{
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"truncate": truncateText,
+	"plaintext": func(value interface{}) string {
+		return strings.TrimSpace(HTMLToText(fmt.Sprint(value)))
+	},
+	"tagBadge": func(tag TagContext) htmltemplate.HTML {
+		return htmltemplate.HTML(GenerateTagsHTML([]models.Tag{{Name: htmltemplate.HTMLEscapeString(tag.Name), Color: htmltemplate.HTMLEscapeString(tag.Color)}}))
+	},
+}
+
+// truncateText shortens text to at most n characters, adding an ellipsis when cut
+func truncateText(n int, text string) string {
+	if n <= 0 || utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:n])) + "…"
+}
+
+// legacyPlaceholders are the names of the {{placeholders}} of the former syntax, which the default templates still use
+var legacyPlaceholders = map[string]bool{
+	"project_name": true, "project_url": true, "changelog_url": true,
+	"unsubscribe_url": true, "preferences_url": true, "unsubscribe_text": true, "preferences_text": true,
+	"event_name": true, "event_url": true, "event_content": true, "event_date": true, "event_tags": true,
+	"status": true, "event_status": true,
+	"release_name": true, "release_version": true, "release_date": true, "release_notes": true,
+	"release_events": true, "release_url": true,
+	"digest_period": true, "digest_start": true, "digest_end": true, "item_count": true,
+}
+
+// templateKeywords are the bare words that are valid actions of the current syntax
+var templateKeywords = map[string]bool{
+	"end": true, "else": true, "break": true, "continue": true, "true": true, "false": true,
+}
+
+var legacyPlaceholderPattern = regexp.MustCompile(`\{\{\s*([a-z_]+)\s*\}\}`)
+
+// convertLegacySyntax rewrites legacy placeholders into template actions. Unknown placeholders
+// are kept as literal text, as the former syntax did, rather than failing to parse.
+func convertLegacySyntax(source string) string {
+	source = strings.ReplaceAll(source, constants.DigestItemsBlockStart, "{{range .Digest.Items}}")
+	source = strings.ReplaceAll(source, constants.DigestItemsBlockEnd, "{{end}}")
+	return legacyPlaceholderPattern.ReplaceAllStringFunc(source, func(match string) string {
+		name := legacyPlaceholderPattern.FindStringSubmatch(match)[1]
+		if templateKeywords[name] {
+			return match
+		}
+		if !legacyPlaceholders[name] {
+			return fmt.Sprintf("{{%q}}", match)
+		}
+		return "{{.Legacy." + name + "}}"
+	})
+}
+
+// limitedBuffer fails writes past maxRenderedSize
+type limitedBuffer struct {
+	bytes.Buffer
+}
+
+var errTemplateTooLarge = errors.New("rendered template is too large")
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > maxRenderedSize {
+		return 0, errTemplateTooLarge
+	}
+	return b.Buffer.Write(p)
+}
+
+var errTemplateTooSlow = fmt.Errorf("template takes too long: it runs more than %d loop iterations and template calls", maxTemplateSteps)
+
+// stepBudget returns the function counting the steps of one render, failing past maxTemplateSteps
+func stepBudget() map[string]interface{} {
+	steps := 0
+	return map[string]interface{}{
+		stepFunc: func() (bool, error) {
+			steps++
+			if steps > maxTemplateSteps {
+				return false, errTemplateTooSlow
+			}
+			return false, nil
+		},
+	}
+}
+
+// addStepCalls inserts a call to the step function at the start of a template and of its loop bodies
+func addStepCalls(tree *parse.Tree) {
+	if tree == nil || tree.Root == nil {
+		return
+	}
+	var walk func(list *parse.ListNode, counted bool)
+	walk = func(list *parse.ListNode, counted bool) {
+		if list == nil {
+			return
+		}
+		for _, node := range list.Nodes {
+			switch node := node.(type) {
+			case *parse.IfNode:
+				walk(node.List, false)
+				walk(node.ElseList, false)
+			case *parse.WithNode:
+				walk(node.List, false)
+				walk(node.ElseList, false)
+			case *parse.RangeNode:
+				walk(node.List, true)
+				walk(node.ElseList, false)
+			case *parse.ListNode:
+				walk(node, false)
+			}
+		}
+		if counted {
+			// {{if _step}}{{end}} writes nothing in any HTML context, unlike an action
+			step := &parse.IfNode{BranchNode: parse.BranchNode{
+				NodeType: parse.NodeIf,
+				Pipe: &parse.PipeNode{NodeType: parse.NodePipe, Cmds: []*parse.CommandNode{{
+					NodeType: parse.NodeCommand,
+					Args:     []parse.Node{parse.NewIdentifier(stepFunc)},
+				}}},
+				List: &parse.ListNode{NodeType: parse.NodeList},
+			}}
+			list.Nodes = append([]parse.Node{step}, list.Nodes...)
+		}
+	}
+	walk(tree.Root, true)
+}
+
+// renderHTML renders an email body
+func renderHTML(name, source string, ctx *TemplateContext) (string, error) {
+	tmpl, err := htmltemplate.New(name).Funcs(templateFuncs).Funcs(stepBudget()).Parse(convertLegacySyntax(source))
+	if err != nil {
+		return "", err
+	}
+	for _, t := range tmpl.Templates() {
+		addStepCalls(t.Tree)
+	}
+	var out limitedBuffer
+	if err := tmpl.Execute(&out, ctx); err != nil {
+		return "", err
+	}
+
+	// URL escaping encodes the braces of personalization placeholders placed in attributes
+	rendered := out.String()
+	for _, placeholder := range []string{unsubscribePlaceholder, preferencesPlaceholder} {
+		encoded := strings.NewReplacer("{", "%7b", "}", "%7d").Replace(placeholder)
+		rendered = strings.ReplaceAll(rendered, encoded, placeholder)
+	}
+	return rendered, nil
+}
+
+// renderText renders an email subject or plain-text body
+func renderText(name, source string, ctx *TemplateContext) (string, error) {
+	tmpl, err := texttemplate.New(name).Funcs(templateFuncs).Funcs(stepBudget()).Parse(convertLegacySyntax(source))
+	if err != nil {
+		return "", err
+	}
+	for _, t := range tmpl.Templates() {
+		addStepCalls(t.Tree)
+	}
+	var out limitedBuffer
+	if err := tmpl.Execute(&out, ctx); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// withLegacyValues returns a copy of the context with the legacy placeholder values filled in
+func withLegacyValues(ctx *TemplateContext) *TemplateContext {
+	data := *ctx
+	data.Legacy = legacyValues(&data)
+	if data.Digest != nil {
+		digest := *data.Digest
+		digest.Items = make([]DigestItemContext, len(ctx.Digest.Items))
+		for i, item := range ctx.Digest.Items {
+			item.Legacy = legacyItemValues(data.Legacy, &item)
+			digest.Items[i] = item
+		}
+		data.Digest = &digest
+	}
+	return &data
+}
+
+// RenderEmailTemplate renders a template's subject and content with the given context
+func RenderEmailTemplate(template *models.EmailTemplate, ctx *TemplateContext) (string, string, error) {
+	data := withLegacyValues(ctx)
+
+	subject, err := renderText("subject", template.Subject, data)
+	if err != nil {
+		return "", "", fmt.Errorf("subject: %w", err)
+	}
+	content, err := renderHTML("content", template.Content, data)
+	if err != nil {
+		return "", "", fmt.Errorf("content: %w", err)
+	}
+	return subject, content, nil
+}
+
+// RenderEmailText renders a template's plain-text body, or returns "" when it has none
+// and the text should be derived from the HTML
+func RenderEmailText(template *models.EmailTemplate, ctx *TemplateContext) (string, error) {
+	if strings.TrimSpace(template.TextContent) == "" {
+		return "", nil
+	}
+	text, err := renderText("text", template.TextContent, withLegacyValues(ctx))
+	if err != nil {
+		return "", fmt.Errorf("text content: %w", err)
+	}
+	return text, nil
+}
+
+// dateHTML wraps a formatted date the way the legacy {{event_date}} placeholder did
+func dateHTML(formatted string) htmltemplate.HTML {
+	if formatted == "" {
+		return ""
+	}
+	return htmltemplate.HTML(`<span style="color: #6b7280; font-size: 14px; font-weight: 500;">` + htmltemplate.HTMLEscapeString(formatted) + `</span>`)
+}
+
+func tagsHTML(tags []TagContext) htmltemplate.HTML {
+	modelTags := make([]models.Tag, len(tags))
+	for i, tag := range tags {
+		modelTags[i] = models.Tag{Name: htmltemplate.HTMLEscapeString(tag.Name), Color: htmltemplate.HTMLEscapeString(tag.Color)}
+	}
+	return htmltemplate.HTML(GenerateTagsHTML(modelTags))
+}
+
+// legacyValues returns the values of the legacy placeholders for a context
+func legacyValues(ctx *TemplateContext) map[string]interface{} {
+	values := map[string]interface{}{}
+	for name := range legacyPlaceholders {
+		values[name] = ""
+	}
+
+	values["project_name"] = ctx.Branding.ProjectName
+	values["project_url"] = ctx.Branding.ProjectURL
+	values["changelog_url"] = ctx.Branding.ChangelogURL
+	values["unsubscribe_url"] = ctx.UnsubscribeURL
+	values["preferences_url"] = ctx.PreferencesURL
+	values["unsubscribe_text"] = ctx.Labels.Unsubscribe
+	values["preferences_text"] = ctx.Labels.Preferences
+
+	if ctx.Event != nil {
+		values["event_name"] = ctx.Event.Title
+		values["event_url"] = ctx.Event.URL
+		values["event_content"] = ctx.Event.Content
+		values["event_date"] = dateHTML(ctx.Event.Date)
+		values["event_tags"] = tagsHTML(ctx.Tags)
+	}
+	if ctx.Status != nil {
+		values["status"] = ctx.Status.Name
+		values["event_status"] = ctx.Status.Name
+	}
+	if ctx.Release != nil {
+		values["release_name"] = ctx.Release.Name
+		values["release_version"] = ctx.Release.Version
+		values["release_date"] = ctx.Release.dateTag
+		values["release_notes"] = ctx.Release.Notes
+		values["release_events"] = ctx.Release.events
+		values["release_url"] = ctx.Release.URL
+	}
+	if ctx.Digest != nil {
+		values["digest_period"] = ctx.Digest.Period
+		values["digest_start"] = ctx.Digest.Start
+		values["digest_end"] = ctx.Digest.End
+		values["item_count"] = len(ctx.Digest.Items)
+	}
+	return values
+}
+
+// legacyItemValues returns the legacy placeholder values inside a digest item block
+func legacyItemValues(outer map[string]interface{}, item *DigestItemContext) map[string]interface{} {
+	values := make(map[string]interface{}, len(outer))
+	for name, value := range outer {
+		values[name] = value
+	}
+	values["event_name"] = item.Event.Title
+	values["event_url"] = item.Event.URL
+	values["event_content"] = item.Event.Content
+	values["event_date"] = dateHTML(item.Event.Date)
+	values["event_tags"] = tagsHTML(item.Tags)
+	values["event_status"] = item.Status.Name
+	values["status"] = item.Status.Name
+	return values
+}
+
+// newBrandingContext copies the branding settings into a template context
+func newBrandingContext(branding *models.BrandingSettings) BrandingContext {
+	changelogURL := branding.BaseURL
+	if changelogURL == "" {
+		changelogURL = "/"
+	}
+	return BrandingContext{
+		ProjectName:  branding.ProjectName,
+		ProjectURL:   branding.ProjectURL,
+		BaseURL:      branding.BaseURL,
+		ChangelogURL: changelogURL,
+		Locale:       branding.Locale,
+	}
+}
+
+func newTagContexts(tags []models.Tag) []TagContext {
+	contexts := make([]TagContext, len(tags))
+	for i, tag := range tags {
+		contexts[i] = TagContext{Name: tag.Name, Color: tag.Color}
+	}
+	return contexts
+}
+
+// newEventContext copies an event into a template context
+func newEventContext(event *models.Event, branding *models.BrandingSettings) EventContext {
+	eventURL := fmt.Sprintf("%s/%s", branding.BaseURL, event.Slug)
+	dateISO := ""
+	if !event.Date.IsZero() {
+		dateISO = event.Date.String()
+	}
+	return EventContext{
+		ID:      event.ID,
+		Title:   event.Title,
+		Slug:    event.Slug,
+		URL:     eventURL,
+		Content: htmltemplate.HTML(ConvertRelativeUrlsToAbsolute(event.Content, branding.BaseURL)),
+		Date:    event.Date.Format(branding.Locale),
+		DateISO: dateISO,
+		Tags:    newTagContexts(event.Tags),
+	}
+}
+
+// newTemplateContext returns a context with the branding and placeholder links for several recipients
+func newTemplateContext(branding *models.BrandingSettings) *TemplateContext {
+	return &TemplateContext{
+		Branding:       newBrandingContext(branding),
+		UnsubscribeURL: unsubscribePlaceholder,
+		PreferencesURL: preferencesPlaceholder,
+		Labels:         labelsFor(branding.Locale),
+	}
+}
+
+// NewWelcomeContext returns the context of a welcome email
+func NewWelcomeContext(branding *models.BrandingSettings) *TemplateContext {
+	return newTemplateContext(branding)
+}
+
+// NewEventContext returns the context of a newsletter about an event
+func NewEventContext(event *models.Event, statusDef *models.EventStatusDefinition, branding *models.BrandingSettings) *TemplateContext {
+	ctx := newTemplateContext(branding)
+	eventContext := newEventContext(event, branding)
+	ctx.Event = &eventContext
+	ctx.Tags = eventContext.Tags
+	if statusDef != nil {
+		ctx.Status = &StatusContext{ID: statusDef.ID, Name: statusDef.DisplayName, Slug: statusDef.Slug}
+	}
+	return ctx
+}
+
+// NewReleaseContext returns the context of a release newsletter
+func NewReleaseContext(release *models.Release, groups []models.ReleaseEventGroup, branding *models.BrandingSettings) *TemplateContext {
+	ctx := newTemplateContext(branding)
+
+	releaseName := release.Name
+	if releaseName == "" {
+		releaseName = "Version " + release.Version
+	}
+	formattedDate := FormatDate(release.ReleaseDate, branding.Locale)
+
+	releaseContext := &ReleaseContext{
+		Name:    releaseName,
+		Version: release.Version,
+		Date:    formattedDate,
+		URL:     fmt.Sprintf("%s/releases/%s", branding.BaseURL, release.Version),
+		Notes:   htmltemplate.HTML(ConvertRelativeUrlsToAbsolute(release.Notes, branding.BaseURL)),
+		events:  htmltemplate.HTML(GenerateReleaseEventsHTML(groups, branding.BaseURL)),
+		dateTag: dateHTML(formattedDate),
+	}
+	for _, group := range groups {
+		groupContext := ReleaseGroupContext{Status: group.Status}
+		for i := range group.Events {
+			eventContext := newEventContext(&group.Events[i], branding)
+			if !group.Events[i].HasPublicUrl {
+				eventContext.URL = ""
+			}
+			groupContext.Events = append(groupContext.Events, eventContext)
+		}
+		releaseContext.Groups = append(releaseContext.Groups, groupContext)
+	}
+	ctx.Release = releaseContext
+	return ctx
+}
+
+// NewDigestContext returns the context of a digest covering [from, to)
+func NewDigestContext(items []models.DigestItem, frequency string, from, to time.Time, branding *models.BrandingSettings) *TemplateContext {
+	ctx := newTemplateContext(branding)
+	digest := &DigestContext{
+		Period: frequency,
+		Start:  formatDay(from, branding.Locale),
+		End:    formatDay(to.AddDate(0, 0, -1), branding.Locale), // The period ends the day before the next one starts
+		Items:  make([]DigestItemContext, len(items)),
+	}
+	for i := range items {
+		eventContext := newEventContext(&items[i].Event, branding)
+		if items[i].Event.Slug == "" || !items[i].Event.HasPublicUrl {
+			eventContext.URL = branding.BaseURL
+		}
+		digest.Items[i] = DigestItemContext{
+			Event:  eventContext,
+			Tags:   eventContext.Tags,
+			Status: StatusContext{Name: items[i].Status},
+		}
+	}
+	ctx.Digest = digest
+	return ctx
+}
+
+// ForSubscriber returns a copy of the context addressed to one subscriber, with personal links
+func (ctx *TemplateContext) ForSubscriber(subscriber *models.NewsletterSubscriber) *TemplateContext {
+	personal := *ctx
+	personal.Subscriber = &SubscriberContext{Email: subscriber.Email, Frequency: subscriber.Frequency, Language: subscriber.Language}
+	personal.UnsubscribeURL = htmltemplate.URL(fmt.Sprintf("%s/unsubscribe?email=%s", ctx.Branding.BaseURL, url.QueryEscape(subscriber.Email)))
+	personal.PreferencesURL = htmltemplate.URL(PreferencesURL(ctx.Branding.BaseURL, subscriber.Email))
+	return &personal
+}
+
+// formatDay formats a day in the given locale
+func formatDay(t time.Time, locale string) string {
+	return models.EventDate{At: &t, Precision: models.DatePrecisionDay}.Format(locale)
+}
@@ -0,0 +1,172 @@
+package email
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"shipshipship/models"
+)
+
+func TestConvertLegacySyntax(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"known placeholder", "Hello {{project_name}}", "Hello {{.Legacy.project_name}}"},
+		{"spaces inside braces", "{{ event_name }}", "{{.Legacy.event_name}}"},
+		{"items block", "{{#items}}<li>{{event_name}}</li>{{/items}}", "{{range .Digest.Items}}<li>{{.Legacy.event_name}}</li>{{end}}"},
+		{"unknown placeholder", "Hi {{first_name}}", `Hi {{"{{first_name}}"}}`},
+		{"keyword", "{{if .Event}}yes{{else}}no{{end}}", "{{if .Event}}yes{{else}}no{{end}}"},
+		{"current syntax", "{{.Event.Title | upper}}", "{{.Event.Title | upper}}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := convertLegacySyntax(tt.source); got != tt.want {
+				t.Errorf("convertLegacySyntax(%q) = %q, want %q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderEmailTemplate(t *testing.T) {
+	branding := &models.BrandingSettings{ProjectName: "Acme", BaseURL: "https://changelog.example.com", Locale: "en"}
+	date := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	event := models.Event{ID: 7, Title: "Dark <mode>", Slug: "dark-mode", HasPublicUrl: true,
+		Date: models.EventDate{At: &date, Precision: models.DatePrecisionDay}}
+	status := &models.EventStatusDefinition{ID: 1, DisplayName: "Released", Slug: "released"}
+	eventContext := NewEventContext(&event, status, branding)
+	digestContext := NewDigestContext([]models.DigestItem{
+		{Event: models.Event{ID: 1, Title: "First", Slug: "first"}, Status: "Released", ChangedAt: date},
+		{Event: models.Event{ID: 2, Title: "Second", Slug: "second"}, Status: "Planned", ChangedAt: date},
+	}, models.DigestFrequencyWeekly, date, date.AddDate(0, 0, 7), branding)
+
+	tests := []struct {
+		name        string
+		ctx         *TemplateContext
+		subject     string
+		content     string
+		wantSubject string
+		wantContent []string
+	}{
+		{
+			name:        "legacy placeholders",
+			ctx:         eventContext,
+			subject:     "{{project_name}}: {{event_name}} is {{status}}",
+			content:     "<h1>{{event_name}}</h1>",
+			wantSubject: "Acme: Dark <mode> is Released",
+			wantContent: []string{"<h1>Dark &lt;mode&gt;</h1>"},
+		},
+		{
+			name:        "current syntax",
+			ctx:         eventContext,
+			subject:     "{{.Event.Title | upper}}",
+			content:     `<p>{{.Status.Name}} on {{.Event.Date}}</p>`,
+			wantSubject: "DARK <MODE>",
+			wantContent: []string{"<p>Released on " + formatDay(date, "en") + "</p>"},
+		},
+		{
+			name:        "items block",
+			ctx:         digestContext,
+			subject:     "{{item_count}} updates",
+			content:     "<ul>{{#items}}<li>{{event_name}}: {{event_status}}</li>{{/items}}</ul>",
+			wantSubject: "2 updates",
+			wantContent: []string{"<ul><li>First: Released</li><li>Second: Planned</li></ul>"},
+		},
+		{
+			name:        "unknown placeholder kept as text",
+			ctx:         eventContext,
+			subject:     "Hi {{first_name}}",
+			content:     "<p>Hi {{first_name}}</p>",
+			wantSubject: "Hi {{first_name}}",
+			wantContent: []string{"<p>Hi {{first_name}}</p>"},
+		},
+		{
+			name:        "personalization placeholders in href",
+			ctx:         eventContext,
+			subject:     "News",
+			content:     `<a href="{{unsubscribe_url}}">{{unsubscribe_text}}</a> <a href="{{.PreferencesURL}}">x</a>`,
+			wantSubject: "News",
+			wantContent: []string{`href="{{unsubscribe_url}}"`, `href="{{preferences_url}}"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := &models.EmailTemplate{Subject: tt.subject, Content: tt.content}
+			subject, content, err := RenderEmailTemplate(template, tt.ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if subject != tt.wantSubject {
+				t.Errorf("subject = %q, want %q", subject, tt.wantSubject)
+			}
+			for _, want := range tt.wantContent {
+				if !strings.Contains(content, want) {
+					t.Errorf("content = %q, want it to contain %q", content, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderEmailTemplatePersonalizedLinks(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	branding := &models.BrandingSettings{ProjectName: "Acme", BaseURL: "https://changelog.example.com", Locale: "en"}
+	template := &models.EmailTemplate{Subject: "News", Content: `<a href="{{unsubscribe_url}}">Unsubscribe</a> <a href="{{preferences_url}}">Preferences</a>`}
+
+	_, content, err := RenderEmailTemplate(template, NewWelcomeContext(branding))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content = PersonalizeContent(content, branding.BaseURL, "a+b@example.com")
+
+	if !strings.Contains(content, `href="https://changelog.example.com/unsubscribe?email=a%2Bb%40example.com"`) {
+		t.Errorf("unsubscribe link not filled in: %s", content)
+	}
+	if !strings.Contains(content, `href="https://changelog.example.com/newsletter/preferences?email=a%2Bb%40example.com&token=`) {
+		t.Errorf("preferences link not filled in: %s", content)
+	}
+}
+
+func TestRenderEmailTemplateStepLimit(t *testing.T) {
+	ctx := NewWelcomeContext(&models.BrandingSettings{ProjectName: "Acme", Locale: "en"})
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"range over a large integer", "{{range 300000000}}{{end}}", true},
+		{"nested ranges", "{{range 1000}}{{range 1000}}{{end}}{{end}}", true},
+		{"recursive template", `{{define "a"}}{{if .}}{{template "a" slice . 1}}{{template "a" slice . 1}}{{end}}{{end}}{{template "a" "abcdefghijklmnopqrstuvwxyz0123456789"}}`, true},
+		{"range under the limit", "{{range 1000}}.{{end}}", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			_, _, err := RenderEmailTemplate(&models.EmailTemplate{Subject: "News", Content: tt.content}, ctx)
+			if tt.wantErr && !errors.Is(err, errTemplateTooSlow) {
+				t.Errorf("err = %v, want errTemplateTooSlow", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("err = %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("render took %v", elapsed)
+			}
+		})
+	}
+
+	// The subject and plain-text body are limited too
+	if _, _, err := RenderEmailTemplate(&models.EmailTemplate{Subject: "{{range 300000000}}{{end}}"}, ctx); !errors.Is(err, errTemplateTooSlow) {
+		t.Errorf("subject: err = %v, want errTemplateTooSlow", err)
+	}
+	if _, err := RenderEmailText(&models.EmailTemplate{TextContent: "{{range 300000000}}{{end}}"}, ctx); !errors.Is(err, errTemplateTooSlow) {
+		t.Errorf("text: err = %v, want errTemplateTooSlow", err)
+	}
+}
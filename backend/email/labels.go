@@ -0,0 +1,27 @@
+package email
+
+import "shipshipship/models"
+
+// LabelsContext holds the text of the links every newsletter carries, in the email's language
+type LabelsContext struct {
+	Unsubscribe string
+	Preferences string
+}
+
+var labels = map[string]LabelsContext{
+	"en": {Unsubscribe: "Unsubscribe", Preferences: "Manage preferences"},
+	"fr": {Unsubscribe: "Se désabonner", Preferences: "Gérer mes préférences"},
+	"de": {Unsubscribe: "Abmelden", Preferences: "Einstellungen verwalten"},
+	"es": {Unsubscribe: "Darse de baja", Preferences: "Gestionar preferencias"},
+	"it": {Unsubscribe: "Annulla l'iscrizione", Preferences: "Gestisci preferenze"},
+	"pt": {Unsubscribe: "Cancelar subscrição", Preferences: "Gerir preferências"},
+	"nl": {Unsubscribe: "Afmelden", Preferences: "Voorkeuren beheren"},
+}
+
+// labelsFor returns the link labels in a locale, in English when it isn't supported
+func labelsFor(locale string) LabelsContext {
+	if localized, ok := labels[models.NormalizeLocale(locale)]; ok {
+		return localized
+	}
+	return labels[models.DefaultLocale]
+}
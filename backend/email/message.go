@@ -0,0 +1,110 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Message is an outgoing email
+type Message struct {
+	FromName  string
+	FromEmail string
+	To        string
+	Subject   string
+	HTML      string // Optional; sent as a multipart/alternative message with Text
+	Text      string // Derived from HTML when empty
+}
+
+// newMessageID returns a unique Message-ID in the sender's domain
+func newMessageID(fromEmail string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(fromEmail, "@"); at >= 0 && at < len(fromEmail)-1 {
+		domain = fromEmail[at+1:]
+	}
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return fmt.Sprintf("<%d@%s>", time.Now().UnixNano(), domain)
+	}
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().Unix(), hex.EncodeToString(random), domain)
+}
+
+// writeQuotedPrintable writes a body encoded as quoted-printable, which keeps lines under the SMTP limit
+func writeQuotedPrintable(buffer *bytes.Buffer, body string) error {
+	writer := quotedprintable.NewWriter(buffer)
+	if _, err := writer.Write([]byte(body)); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// Bytes encodes the message in RFC 5322 format. Non-ASCII names and subjects are encoded
+// per RFC 2047; bodies are quoted-printable.
+func (m *Message) Bytes() ([]byte, error) {
+	text := m.Text
+	if text == "" && m.HTML != "" {
+		text = HTMLToText(m.HTML)
+	}
+
+	var buffer bytes.Buffer
+	from := mail.Address{Name: m.FromName, Address: m.FromEmail}
+	to := mail.Address{Address: m.To}
+
+	fmt.Fprintf(&buffer, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buffer, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buffer, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", m.Subject))
+	fmt.Fprintf(&buffer, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buffer, "Message-ID: %s\r\n", newMessageID(m.FromEmail))
+	buffer.WriteString("MIME-Version: 1.0\r\n")
+
+	if m.HTML == "" {
+		buffer.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+		buffer.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&buffer, text); err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	fmt.Fprintf(&buffer, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", writer.Boundary())
+
+	// Clients show the last part they support, so the HTML part comes last
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", text},
+		{"text/html; charset=UTF-8", m.HTML},
+	} {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		partWriter, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		var encoded bytes.Buffer
+		if err := writeQuotedPrintable(&encoded, part.content); err != nil {
+			return nil, err
+		}
+		if _, err := partWriter.Write(encoded.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	buffer.Write(body.Bytes())
+	return buffer.Bytes(), nil
+}
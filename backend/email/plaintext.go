@@ -0,0 +1,230 @@
+package email
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// listState tracks an open <ul> or <ol> while converting HTML to text
+type listState struct {
+	ordered bool
+	count   int
+}
+
+// textConverter accumulates the plain-text rendering of an HTML document
+type textConverter struct {
+	out       strings.Builder
+	lists     []listState
+	linkHref  string
+	linkText  strings.Builder
+	inLink    bool
+	skipDepth int // Inside <style>, <script>, <head> or <title>
+	preDepth  int
+}
+
+var blockElements = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "header": true, "footer": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"table": true, "tr": true, "blockquote": true, "pre": true, "ul": true, "ol": true,
+}
+
+var skippedElements = map[string]bool{"style": true, "script": true, "head": true, "title": true}
+
+var (
+	spaceRun      = regexp.MustCompile(`[ \t\r\n\f]+`)
+	blankLinesRun = regexp.MustCompile(`\n{3,}`)
+)
+
+// write adds text, to the current link if one is open
+func (tc *textConverter) write(text string) {
+	if tc.inLink {
+		tc.linkText.WriteString(text)
+		return
+	}
+	tc.out.WriteString(text)
+}
+
+// newline ends the current line unless it's already ended
+func (tc *textConverter) newline() {
+	if tc.inLink {
+		tc.linkText.WriteString(" ")
+		return
+	}
+	current := tc.out.String()
+	if current != "" && !strings.HasSuffix(current, "\n") {
+		tc.out.WriteString("\n")
+	}
+}
+
+// paragraph separates blocks with a blank line
+func (tc *textConverter) paragraph() {
+	tc.newline()
+	if current := tc.out.String(); current != "" && !strings.HasSuffix(current, "\n\n") {
+		tc.out.WriteString("\n")
+	}
+}
+
+func attr(token html.Token, name string) string {
+	for _, attribute := range token.Attr {
+		if attribute.Key == name {
+			return strings.TrimSpace(attribute.Val)
+		}
+	}
+	return ""
+}
+
+func (tc *textConverter) startTag(token html.Token) {
+	switch token.Data {
+	case "br":
+		tc.newline()
+	case "hr":
+		tc.paragraph()
+		tc.write("----------")
+		tc.paragraph()
+	case "img":
+		if alt := attr(token, "alt"); alt != "" {
+			tc.write(alt)
+		}
+	case "a":
+		tc.inLink = true
+		tc.linkHref = attr(token, "href")
+		tc.linkText.Reset()
+	case "li":
+		tc.newline()
+		indent := ""
+		if len(tc.lists) > 1 {
+			indent = strings.Repeat("  ", len(tc.lists)-1)
+		}
+		marker := "- "
+		if len(tc.lists) > 0 && tc.lists[len(tc.lists)-1].ordered {
+			tc.lists[len(tc.lists)-1].count++
+			marker = fmt.Sprintf("%d. ", tc.lists[len(tc.lists)-1].count)
+		}
+		tc.write(indent + marker)
+	case "td", "th":
+		if current := tc.out.String(); current != "" && !strings.HasSuffix(current, "\n") {
+			tc.write(" ")
+		}
+	}
+
+	if token.Data == "ul" || token.Data == "ol" {
+		if len(tc.lists) == 0 {
+			tc.paragraph()
+		}
+		tc.lists = append(tc.lists, listState{ordered: token.Data == "ol"})
+		return
+	}
+	if token.Data == "pre" {
+		tc.preDepth++
+	}
+	if blockElements[token.Data] {
+		tc.paragraph()
+	}
+}
+
+func (tc *textConverter) endTag(name string) {
+	switch name {
+	case "a":
+		tc.inLink = false
+		text := strings.TrimSpace(spaceRun.ReplaceAllString(tc.linkText.String(), " "))
+		href := tc.linkHref
+		switch {
+		case href == "" || strings.HasPrefix(href, "#") || href == text:
+			tc.write(text)
+		case text == "":
+			tc.write(href)
+		default:
+			tc.write(text + " (" + strings.TrimPrefix(href, "mailto:") + ")")
+		}
+		return
+	case "ul", "ol":
+		if len(tc.lists) > 0 {
+			tc.lists = tc.lists[:len(tc.lists)-1]
+		}
+		if len(tc.lists) == 0 {
+			tc.paragraph()
+		} else {
+			tc.newline()
+		}
+		return
+	case "pre":
+		if tc.preDepth > 0 {
+			tc.preDepth--
+		}
+	}
+	if blockElements[name] {
+		tc.paragraph()
+	}
+}
+
+func (tc *textConverter) text(data string) {
+	if tc.preDepth > 0 {
+		tc.write(data)
+		return
+	}
+	text := spaceRun.ReplaceAllString(data, " ")
+	if text == " " {
+		// Keep a single space between inline elements, but not at line starts
+		current := tc.out.String()
+		if tc.inLink || (current != "" && !strings.HasSuffix(current, "\n") && !strings.HasSuffix(current, " ")) {
+			tc.write(" ")
+		}
+		return
+	}
+	if current := tc.out.String(); !tc.inLink && (current == "" || strings.HasSuffix(current, "\n")) {
+		text = strings.TrimLeft(text, " ")
+	}
+	tc.write(text)
+}
+
+// HTMLToText derives the plain-text version of an HTML email: links are written as
+// "text (url)", lists keep their bullets or numbers and images are replaced by their alt text
+func HTMLToText(htmlContent string) string {
+	tc := &textConverter{}
+	tokenizer := html.NewTokenizer(strings.NewReader(htmlContent))
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+		token := tokenizer.Token()
+
+		switch tokenType {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if skippedElements[token.Data] {
+				if tokenType == html.StartTagToken {
+					tc.skipDepth++
+				}
+				continue
+			}
+			if tc.skipDepth == 0 {
+				tc.startTag(token)
+			}
+		case html.EndTagToken:
+			if skippedElements[token.Data] {
+				if tc.skipDepth > 0 {
+					tc.skipDepth--
+				}
+				continue
+			}
+			if tc.skipDepth == 0 {
+				tc.endTag(token.Data)
+			}
+		case html.TextToken:
+			if tc.skipDepth == 0 {
+				tc.text(token.Data)
+			}
+		}
+	}
+
+	lines := strings.Split(tc.out.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	text := blankLinesRun.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(text) + "\n"
+}
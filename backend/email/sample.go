@@ -0,0 +1,70 @@
+package email
+
+import (
+	"time"
+
+	"shipshipship/constants"
+	"shipshipship/models"
+)
+
+// sampleEvent returns the event used to preview and validate templates
+func sampleEvent(id uint, title, slug string, date time.Time) models.Event {
+	return models.Event{
+		ID:      id,
+		Title:   title,
+		Slug:    slug,
+		Content: "<p>You can now switch the interface to a dark theme from your profile settings. It follows your system preference by default.</p>",
+		Date:    models.EventDate{At: &date, Precision: models.DatePrecisionDay},
+		Tags: []models.Tag{
+			{Name: "Feature", Color: "#3b82f6"},
+			{Name: "UI", Color: "#10b981"},
+		},
+		HasPublicUrl: true,
+	}
+}
+
+// SampleContext returns the context of a sample email of the given template type
+func SampleContext(templateType string, branding *models.BrandingSettings) *TemplateContext {
+	now := time.Now()
+	subscriber := &models.NewsletterSubscriber{Email: "subscriber@example.com", Frequency: models.DigestFrequencyInstant}
+
+	switch templateType {
+	case constants.TemplateTypeEvent:
+		event := sampleEvent(1, "Dark mode support", "dark-mode-support", now)
+		status := &models.EventStatusDefinition{ID: 1, DisplayName: "Released", Slug: "released"}
+		return NewEventContext(&event, status, branding).ForSubscriber(subscriber)
+
+	case constants.TemplateTypeRelease:
+		release := &models.Release{Name: "Spring update", Version: "1.4.0", ReleaseDate: now.Format("2006-01-02"),
+			Notes: "<p>This release focuses on accessibility and performance.</p>"}
+		groups := []models.ReleaseEventGroup{
+			{Status: "Released", Events: []models.Event{
+				sampleEvent(1, "Dark mode support", "dark-mode-support", now),
+				sampleEvent(2, "Faster search", "faster-search", now),
+			}},
+		}
+		return NewReleaseContext(release, groups, branding).ForSubscriber(subscriber)
+
+	case constants.TemplateTypeDigest:
+		subscriber.Frequency = models.DigestFrequencyWeekly
+		periodStart := models.DigestPeriodStart(models.DigestFrequencyWeekly, now)
+		items := []models.DigestItem{
+			{Event: sampleEvent(1, "Dark mode support", "dark-mode-support", now), Status: "Released", ChangedAt: now},
+			{Event: sampleEvent(2, "Faster search", "faster-search", now), Status: "In progress", ChangedAt: now},
+		}
+		return NewDigestContext(items, models.DigestFrequencyWeekly, periodStart,
+			models.NextDigestPeriodStart(models.DigestFrequencyWeekly, periodStart), branding).ForSubscriber(subscriber)
+	}
+
+	return NewWelcomeContext(branding).ForSubscriber(subscriber)
+}
+
+// ValidateEmailTemplate checks that a template parses and renders with a sample context of its type
+func ValidateEmailTemplate(template *models.EmailTemplate, branding *models.BrandingSettings) error {
+	ctx := SampleContext(template.Type, branding)
+	if _, _, err := RenderEmailTemplate(template, ctx); err != nil {
+		return err
+	}
+	_, err := RenderEmailText(template, ctx)
+	return err
+}
@@ -2,6 +2,8 @@ package email
 
 import (
 	"fmt"
+	"html"
+	"net/url"
 	"regexp"
 	"strings"
 	"time"
@@ -11,21 +13,17 @@ import (
 	"gorm.io/gorm"
 )
 
-// FormatDate formats a date string to match the public page format (e.g., "10 Aug. 2025")
-func FormatDate(dateString string) string {
+// FormatDate formats a date string in the given locale (e.g., "10 Aug. 2025" or "Q3 2026" in English)
+func FormatDate(dateString, locale string) string {
 	if dateString == "" {
 		return ""
 	}
 
-	date, err := time.Parse("2006-01-02", dateString)
+	date, err := models.ParseEventDate(dateString)
 	if err != nil {
 		return dateString // Return original if parsing fails
 	}
-
-	// Format as "2 Jan. 2006"
-	formatted := date.Format("2 Jan 2006")
-	// Add period after month abbreviation
-	return strings.Replace(formatted, " "+date.Format("Jan")+" ", " "+date.Format("Jan")+". ", 1)
+	return date.Format(locale)
 }
 
 // GenerateTagsHTML generates HTML for tags
@@ -61,54 +59,76 @@ func ConvertRelativeUrlsToAbsolute(content, baseURL string) string {
 	return re.ReplaceAllString(content, fmt.Sprintf(`src="%s$1"`, baseURL))
 }
 
-// GenerateEmailContent generates email subject and content with variable replacements
+// GenerateEmailContent renders an event newsletter for several recipients, leaving the
+// unsubscribe and preferences links to PersonalizeContent
 func GenerateEmailContent(db *gorm.DB, template *models.EmailTemplate, event *models.Event, statusDef *models.EventStatusDefinition, branding *models.BrandingSettings) (string, string, error) {
-	subject := template.Subject
-	content := template.Content
-
-	// Convert relative image URLs to absolute URLs in event content
-	eventContent := ConvertRelativeUrlsToAbsolute(event.Content, branding.BaseURL)
-
-	// Generate tags HTML
-	tagsHTML := GenerateTagsHTML(event.Tags)
+	return RenderEmailTemplate(template, NewEventContext(event, statusDef, branding))
+}
 
-	// Format date
-	formattedDate := FormatDate(event.Date)
-	formattedDateHTML := ""
-	if formattedDate != "" {
-		formattedDateHTML = `<span style="color: #6b7280; font-size: 14px; font-weight: 500;">` + formattedDate + `</span>`
+// GenerateReleaseEventsHTML generates the list of events included in a release, grouped by status
+func GenerateReleaseEventsHTML(groups []models.ReleaseEventGroup, baseURL string) string {
+	var eventsHTML strings.Builder
+	for _, group := range groups {
+		eventsHTML.WriteString(fmt.Sprintf(
+			`<h3 style="color: #000000; font-size: 18px; font-weight: bold; margin: 20px 0 10px;">%s</h3>`,
+			html.EscapeString(group.Status),
+		))
+		eventsHTML.WriteString(`<ul style="padding-left: 20px; margin: 0 0 15px;">`)
+		for _, event := range group.Events {
+			title := html.EscapeString(event.Title)
+			if event.Slug != "" && event.HasPublicUrl {
+				title = fmt.Sprintf(`<a href="%s/%s" style="color: #2563eb; text-decoration: none; font-weight: 600;">%s</a>`, baseURL, event.Slug, title)
+			}
+			eventsHTML.WriteString(`<li style="margin-bottom: 8px;">` + title)
+			if tagsHTML := GenerateTagsHTML(event.Tags); tagsHTML != "" {
+				eventsHTML.WriteString(" " + tagsHTML)
+			}
+			eventsHTML.WriteString(`</li>`)
+		}
+		eventsHTML.WriteString(`</ul>`)
 	}
+	return eventsHTML.String()
+}
 
-	// Generate URLs (use BaseURL for event links, or relative URLs if empty)
-	eventURL := fmt.Sprintf("%s/%s", branding.BaseURL, event.Slug)
-	if branding.BaseURL == "" {
-		eventURL = fmt.Sprintf("/%s", event.Slug)
-	}
+// GenerateReleaseEmailContent renders a release newsletter for several recipients
+func GenerateReleaseEmailContent(template *models.EmailTemplate, release *models.Release, groups []models.ReleaseEventGroup, branding *models.BrandingSettings) (string, string, error) {
+	return RenderEmailTemplate(template, NewReleaseContext(release, groups, branding))
+}
 
-	// Use BaseURL for unsubscribe (not ProjectURL which is the external website)
-	unsubscribeURL := fmt.Sprintf("%s/unsubscribe", branding.BaseURL)
-	if branding.BaseURL == "" {
-		unsubscribeURL = "/unsubscribe"
+// GenerateDigestEmailContent renders a digest of the items for one subscriber
+func GenerateDigestEmailContent(template *models.EmailTemplate, items []models.DigestItem, frequency string, from, to time.Time, branding *models.BrandingSettings, subscriber *models.NewsletterSubscriber) (string, string, error) {
+	ctx := NewDigestContext(items, frequency, from, to, branding)
+	if subscriber != nil {
+		ctx = ctx.ForSubscriber(subscriber)
 	}
+	return RenderEmailTemplate(template, ctx)
+}
 
-	// Replace common variables
-	replacements := map[string]string{
-		"{{project_name}}":    branding.ProjectName,
-		"{{project_url}}":     branding.ProjectURL,
-		"{{event_name}}":      event.Title,
-		"{{event_url}}":       eventURL,
-		"{{event_content}}":   eventContent,
-		"{{event_date}}":      formattedDateHTML,
-		"{{event_tags}}":      tagsHTML,
-		"{{status}}":          statusDef.DisplayName,
-		"{{unsubscribe_url}}": unsubscribeURL,
+// Localize returns the template and branding to write an email in a language. The template falls
+// back to the project locale, then to its base content; dates and labels follow the language of the
+// content chosen, the base content being in the project locale.
+func Localize(template *models.EmailTemplate, branding *models.BrandingSettings, language string) (*models.EmailTemplate, *models.BrandingSettings) {
+	localized, locale := template.Localized(models.LocaleFallbacks(language, branding.Locale)...)
+	if locale == "" {
+		return localized, branding
 	}
+	return localized, branding.WithLocale(locale)
+}
 
-	// Apply replacements
-	for placeholder, value := range replacements {
-		subject = strings.ReplaceAll(subject, placeholder, value)
-		content = strings.ReplaceAll(content, placeholder, value)
+// PreferencesURL returns the signed link to a subscriber's topic preferences page. Without
+// JWT_SECRET the link carries no token, and the page asks for a newer one.
+func PreferencesURL(baseURL, subscriberEmail string) string {
+	token, err := models.PreferencesToken(subscriberEmail)
+	if err != nil {
+		return fmt.Sprintf("%s/newsletter/preferences?email=%s", baseURL, url.QueryEscape(subscriberEmail))
 	}
+	return fmt.Sprintf("%s/newsletter/preferences?email=%s&token=%s",
+		baseURL, url.QueryEscape(subscriberEmail), token)
+}
 
-	return subject, content, nil
+// PersonalizeContent fills in the subscriber-specific links of a newsletter
+func PersonalizeContent(content, baseURL, subscriberEmail string) string {
+	unsubscribeURL := fmt.Sprintf("%s/unsubscribe?email=%s", baseURL, url.QueryEscape(subscriberEmail))
+	content = strings.ReplaceAll(content, unsubscribePlaceholder, unsubscribeURL)
+	return strings.ReplaceAll(content, preferencesPlaceholder, PreferencesURL(baseURL, subscriberEmail))
 }
@@ -24,12 +24,13 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	if !middleware.CheckAdminCredentials(req.Username, req.Password) {
+	role, ok := middleware.Authenticate(req.Username, req.Password)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
-	token, err := middleware.GenerateToken(req.Username)
+	token, err := middleware.GenerateToken(req.Username, role)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
@@ -48,6 +49,7 @@ func ValidateToken(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"valid":    true,
 		"username": username,
+		"role":     middleware.GetRole(c),
 	})
 }
 
@@ -0,0 +1,376 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"shipshipship/constants"
+	"shipshipship/database"
+	emailtemplates "shipshipship/email"
+	"shipshipship/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// emailTemplateRequest creates or updates a named template; omitted fields are left unchanged
+type emailTemplateRequest struct {
+	Name        *string `json:"name"`
+	Subject     *string `json:"subject"`
+	Content     *string `json:"content"`
+	TextContent *string `json:"text_content"`
+	StatusIDs   *[]uint `json:"status_ids"` // Statuses whose emails use this template
+}
+
+// loadEmailTemplate returns the template with the ID in the path, or writes an error
+func loadEmailTemplate(c *gin.Context, db *gorm.DB) *models.EmailTemplate {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return nil
+	}
+
+	template, err := models.GetEmailTemplateByID(db, uint(id))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Email template not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get email template"})
+		}
+		return nil
+	}
+	return template
+}
+
+// applyEmailTemplateRequest validates the request against the template and copies its fields.
+// Returns the statuses to map the template to, nil to leave them unchanged.
+func applyEmailTemplateRequest(c *gin.Context, db *gorm.DB, template *models.EmailTemplate, req emailTemplateRequest) ([]models.EventStatusDefinition, bool) {
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if template.IsDefault && name != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The default template of a type can't be renamed"})
+			return nil, false
+		}
+		if !template.IsDefault {
+			if name == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Template name is required"})
+				return nil, false
+			}
+			taken, err := models.EmailTemplateNameTaken(db, template.Type, name, template.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check template name"})
+				return nil, false
+			}
+			if taken {
+				c.JSON(http.StatusConflict, gin.H{"error": "A template with this name already exists"})
+				return nil, false
+			}
+		}
+		template.Name = name
+	}
+	if req.Subject != nil {
+		template.Subject = *req.Subject
+	}
+	if req.Content != nil {
+		template.Content = *req.Content
+	}
+	if req.TextContent != nil {
+		template.TextContent = *req.TextContent
+	}
+
+	if strings.TrimSpace(template.Subject) == "" || strings.TrimSpace(template.Content) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Subject and content are required"})
+		return nil, false
+	}
+
+	var statuses []models.EventStatusDefinition
+	if req.StatusIDs != nil {
+		if len(*req.StatusIDs) > 0 && (template.IsDefault || template.Type != constants.TemplateTypeEvent) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only named event templates can be mapped to statuses"})
+			return nil, false
+		}
+		resolved, err := models.ResolveEmailTemplateStatuses(db, template.ID, *req.StatusIDs)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		statuses = resolved
+	}
+
+	branding, err := models.GetBrandingSettingsWithBaseURL(db, getBaseURL(c, db))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get branding settings"})
+		return nil, false
+	}
+	if err := emailtemplates.ValidateEmailTemplate(template, branding); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template: " + err.Error()})
+		return nil, false
+	}
+	return statuses, true
+}
+
+// saveEmailTemplate saves the template and, unless nil, its statuses in one transaction
+func saveEmailTemplate(c *gin.Context, db *gorm.DB, template *models.EmailTemplate, statuses []models.EventStatusDefinition, status int) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(template).Error; err != nil {
+			return err
+		}
+		if statuses != nil {
+			return models.SetEmailTemplateStatuses(tx, template, statuses)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save email template"})
+		return
+	}
+
+	saved, err := models.GetEmailTemplateByID(db, template.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get email template"})
+		return
+	}
+	c.JSON(status, saved)
+}
+
+// ListEmailTemplates returns the default and named templates with the statuses they're mapped to (admin only)
+func ListEmailTemplates(c *gin.Context) {
+	db := database.GetDB()
+
+	templates, err := models.ListEmailTemplates(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get email templates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"templates": templates})
+}
+
+// GetEmailTemplateByID returns one template (admin only)
+func GetEmailTemplateByID(c *gin.Context) {
+	db := database.GetDB()
+	if template := loadEmailTemplate(c, db); template != nil {
+		c.JSON(http.StatusOK, template)
+	}
+}
+
+// CreateEmailTemplate creates a named event template, starting from the default event template (admin only)
+func CreateEmailTemplate(c *gin.Context) {
+	var req emailTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+	if req.Name == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Template name is required"})
+		return
+	}
+
+	db := database.GetDB()
+
+	template := &models.EmailTemplate{Type: constants.TemplateTypeEvent}
+	if base, err := models.GetEmailTemplate(db, constants.TemplateTypeEvent); err == nil {
+		template.Subject, template.Content, template.TextContent = base.Subject, base.Content, base.TextContent
+	} else if defaultTemplate := constants.GetTemplateByType(constants.TemplateTypeEvent); defaultTemplate != nil {
+		template.Subject, template.Content = defaultTemplate.Subject, defaultTemplate.Content
+	}
+
+	statuses, ok := applyEmailTemplateRequest(c, db, template, req)
+	if !ok {
+		return
+	}
+	saveEmailTemplate(c, db, template, statuses, http.StatusCreated)
+}
+
+// UpdateEmailTemplate updates a template's name, content or statuses (admin only)
+func UpdateEmailTemplate(c *gin.Context) {
+	var req emailTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	db := database.GetDB()
+	template := loadEmailTemplate(c, db)
+	if template == nil {
+		return
+	}
+
+	statuses, ok := applyEmailTemplateRequest(c, db, template, req)
+	if !ok {
+		return
+	}
+	saveEmailTemplate(c, db, template, statuses, http.StatusOK)
+}
+
+// CloneEmailTemplate copies an event template and its translations under a new name, without its statuses (admin only)
+func CloneEmailTemplate(c *gin.Context) {
+	var req struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Template name is required"})
+		return
+	}
+
+	db := database.GetDB()
+	source := loadEmailTemplate(c, db)
+	if source == nil {
+		return
+	}
+	if source.Type != constants.TemplateTypeEvent {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only event templates can be cloned"})
+		return
+	}
+
+	clone := &models.EmailTemplate{
+		Type:        source.Type,
+		Subject:     source.Subject,
+		Content:     source.Content,
+		TextContent: source.TextContent,
+	}
+	if _, ok := applyEmailTemplateRequest(c, db, clone, emailTemplateRequest{Name: &req.Name}); !ok {
+		return
+	}
+	for _, translation := range source.Translations {
+		clone.Translations = append(clone.Translations, models.EmailTemplateTranslation{
+			Locale:      translation.Locale,
+			Subject:     translation.Subject,
+			Content:     translation.Content,
+			TextContent: translation.TextContent,
+		})
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Translations").Create(clone).Error; err != nil {
+			return err
+		}
+		for i := range clone.Translations {
+			clone.Translations[i].TemplateID = clone.ID
+		}
+		if len(clone.Translations) == 0 {
+			return nil
+		}
+		return tx.Create(&clone.Translations).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save email template"})
+		return
+	}
+
+	saved, err := models.GetEmailTemplateByID(db, clone.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get email template"})
+		return
+	}
+	c.JSON(http.StatusCreated, saved)
+}
+
+// DeleteEmailTemplate deletes a named template; its statuses fall back to the default template (admin only)
+func DeleteEmailTemplate(c *gin.Context) {
+	db := database.GetDB()
+	template := loadEmailTemplate(c, db)
+	if template == nil {
+		return
+	}
+
+	if template.IsDefault {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The default template of a type can't be deleted"})
+		return
+	}
+
+	if err := models.DeleteEmailTemplate(db, template); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete email template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email template deleted"})
+}
+
+// emailTemplateLocale returns the supported locale in the path, or writes an error
+func emailTemplateLocale(c *gin.Context) (string, bool) {
+	locale := models.NormalizeLocale(c.Param("locale"))
+	if locale == "" || locale != strings.ToLower(c.Param("locale")) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported locale: " + c.Param("locale")})
+		return "", false
+	}
+	return locale, true
+}
+
+// SaveEmailTemplateTranslation creates or replaces a template's subject and content in a language (admin only)
+func SaveEmailTemplateTranslation(c *gin.Context) {
+	var req struct {
+		Subject     string `json:"subject" binding:"required"`
+		Content     string `json:"content" binding:"required"`
+		TextContent string `json:"text_content"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Subject and content are required"})
+		return
+	}
+
+	locale, ok := emailTemplateLocale(c)
+	if !ok {
+		return
+	}
+
+	db := database.GetDB()
+	template := loadEmailTemplate(c, db)
+	if template == nil {
+		return
+	}
+
+	branding, err := models.GetBrandingSettingsWithBaseURL(db, getBaseURL(c, db))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get branding settings"})
+		return
+	}
+	candidate := *template
+	candidate.Subject, candidate.Content, candidate.TextContent = req.Subject, req.Content, req.TextContent
+	if err := emailtemplates.ValidateEmailTemplate(&candidate, branding.WithLocale(locale)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template: " + err.Error()})
+		return
+	}
+
+	translation := &models.EmailTemplateTranslation{
+		TemplateID:  template.ID,
+		Locale:      locale,
+		Subject:     req.Subject,
+		Content:     req.Content,
+		TextContent: req.TextContent,
+	}
+	if err := models.SaveEmailTemplateTranslation(db, translation); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save translation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, translation)
+}
+
+// DeleteEmailTemplateTranslation removes a template's translation; emails in that language fall back to the base content (admin only)
+func DeleteEmailTemplateTranslation(c *gin.Context) {
+	locale, ok := emailTemplateLocale(c)
+	if !ok {
+		return
+	}
+
+	db := database.GetDB()
+	template := loadEmailTemplate(c, db)
+	if template == nil {
+		return
+	}
+
+	deleted, err := models.DeleteEmailTemplateTranslation(db, template.ID, locale)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete translation"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Translation not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Translation deleted"})
+}
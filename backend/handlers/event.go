@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"shipshipship/database"
+	"shipshipship/middleware"
 	"shipshipship/models"
 	"shipshipship/services"
 	"shipshipship/utils"
@@ -16,8 +17,8 @@ import (
 	"gorm.io/gorm"
 )
 
-// Helper function to get reaction summary for an event
-func getReactionSummary(db *gorm.DB, eventID uint, clientIP string) models.ReactionSummary {
+// Helper function to get reaction summary for an event, with the visitor's own reactions
+func getReactionSummary(db *gorm.DB, eventID uint, visitor models.VisitorIdentity) models.ReactionSummary {
 	var reactions []models.ReactionCount
 
 	// Get count for each reaction type
@@ -35,7 +36,7 @@ func getReactionSummary(db *gorm.DB, eventID uint, clientIP string) models.React
 
 	// Get user's reactions
 	var userReactions []models.EventReaction
-	db.Where("event_id = ? AND ip_address = ?", eventID, clientIP).Find(&userReactions)
+	visitor.Match(db.Where("event_id = ?", eventID)).Find(&userReactions)
 
 	userReactionTypes := make([]models.ReactionType, len(userReactions))
 	for i, r := range userReactions {
@@ -55,13 +56,24 @@ func GetEvents(c *gin.Context) {
 
 	db := database.GetDB()
 
-	if err := db.Preload("Tags").Where("is_public = ?", true).Order("created_at ASC").Find(&events).Error; err != nil {
+	query, ok := applyEventDateQuery(c, db.Preload("Tags").Preload("StatusDefinition").Preload("Translations").Where("is_public = ?", true), "created_at ASC")
+	if !ok {
+		return
+	}
+	if query, ok = applyEventTagQuery(c, db, query); !ok {
+		return
+	}
+
+	if err := query.Find(&events).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
 		return
 	}
 
-	// Get client IP for user-specific reaction data
-	clientIP := c.ClientIP()
+	// Serve the title and content in the reader's language when translated
+	localizeEvents(c, db, events)
+
+	// Identify the visitor for user-specific reaction data
+	visitor := readingVisitor(c, db)
 
 	// Build response with reaction summaries
 	type EventWithReactions struct {
@@ -83,7 +95,7 @@ func GetEvents(c *gin.Context) {
 		// Sanitize content URLs (HTML content with image tags)
 		event.Content = SanitizeHTMLContent(event.Content)
 
-		summary := getReactionSummary(db, event.ID, clientIP)
+		summary := getReactionSummary(db, event.ID, visitor)
 		eventsWithReactions[i] = EventWithReactions{
 			Event:           event,
 			ReactionSummary: summary,
@@ -93,18 +105,65 @@ func GetEvents(c *gin.Context) {
 	c.JSON(http.StatusOK, eventsWithReactions)
 }
 
+// applyEventDateQuery applies the ?date_from, ?date_to and ?sort=date|-date query parameters.
+// Bounds accept the same notations as event dates and match events whose date starts within them;
+// undated events are excluded when filtering and listed last when sorting.
+func applyEventDateQuery(c *gin.Context, query *gorm.DB, defaultOrder string) (*gorm.DB, bool) {
+	if from := c.Query("date_from"); from != "" {
+		date, err := models.ParseEventDate(from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date_from: " + err.Error()})
+			return nil, false
+		}
+		if !date.IsZero() {
+			query = query.Where("date_at >= ?", *date.At)
+		}
+	}
+
+	if to := c.Query("date_to"); to != "" {
+		date, err := models.ParseEventDate(to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date_to: " + err.Error()})
+			return nil, false
+		}
+		if !date.IsZero() {
+			query = query.Where("date_at < ?", date.End())
+		}
+	}
+
+	switch c.Query("sort") {
+	case "":
+		return query.Order(defaultOrder), true
+	case "date":
+		return query.Order("date_at IS NULL, date_at ASC, id ASC"), true
+	case "-date":
+		return query.Order("date_at IS NULL, date_at DESC, id DESC"), true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort: use date or -date"})
+		return nil, false
+	}
+}
+
 func GetAllEvents(c *gin.Context) {
 	var events []models.Event
 
 	db := database.GetDB()
 
-	if err := db.Preload("Tags").Order("created_at DESC").Find(&events).Error; err != nil {
+	query, ok := applyEventDateQuery(c, db.Preload("Tags").Preload("StatusDefinition"), "created_at DESC")
+	if !ok {
+		return
+	}
+	if query, ok = applyEventTagQuery(c, db, query); !ok {
+		return
+	}
+
+	if err := query.Find(&events).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
 		return
 	}
 
-	// Get client IP for user-specific reaction data
-	clientIP := c.ClientIP()
+	// Identify the visitor for user-specific reaction data
+	visitor := readingVisitor(c, db)
 
 	// Build response with reaction summaries
 	type EventWithReactions struct {
@@ -126,7 +185,7 @@ func GetAllEvents(c *gin.Context) {
 		// Sanitize content URLs (HTML content with image tags)
 		event.Content = SanitizeHTMLContent(event.Content)
 
-		summary := getReactionSummary(db, event.ID, clientIP)
+		summary := getReactionSummary(db, event.ID, visitor)
 		eventsWithReactions[i] = EventWithReactions{
 			Event:           event,
 			ReactionSummary: summary,
@@ -148,7 +207,7 @@ func GetEvent(c *gin.Context) {
 
 	db := database.GetDB()
 
-	if err := db.Preload("Tags").First(&event, eventID).Error; err != nil {
+	if err := db.Preload("Tags").Preload("StatusDefinition").First(&event, eventID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 		return
 	}
@@ -165,8 +224,8 @@ func GetEvent(c *gin.Context) {
 	// Sanitize content URLs (HTML content with image tags)
 	event.Content = SanitizeHTMLContent(event.Content)
 
-	// Get client IP for user-specific reaction data
-	clientIP := c.ClientIP()
+	// Identify the visitor for user-specific reaction data
+	visitor := readingVisitor(c, db)
 
 	// Build response with reaction summary
 	type EventWithReactions struct {
@@ -174,7 +233,7 @@ func GetEvent(c *gin.Context) {
 		ReactionSummary models.ReactionSummary `json:"reaction_summary"`
 	}
 
-	summary := getReactionSummary(db, event.ID, clientIP)
+	summary := getReactionSummary(db, event.ID, visitor)
 	response := EventWithReactions{
 		Event:           event,
 		ReactionSummary: summary,
@@ -195,7 +254,7 @@ func GetEventBySlug(c *gin.Context) {
 	db := database.GetDB()
 
 	// Find event by slug
-	if err := db.Preload("Tags").Where("slug = ?", slug).First(&event).Error; err != nil {
+	if err := db.Preload("Tags").Preload("StatusDefinition").Preload("Translations").Where("slug = ?", slug).First(&event).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 		} else {
@@ -204,6 +263,10 @@ func GetEventBySlug(c *gin.Context) {
 		return
 	}
 
+	// Serve the title and content in the reader's language when translated
+	event.Localize(requestedLocales(c), models.ContentLocale(db))
+	c.Header("Content-Language", event.Locale)
+
 	// Sanitize media URLs to convert localhost to relative URLs
 	var mediaURLs []string
 	if event.Media != "" {
@@ -216,19 +279,27 @@ func GetEventBySlug(c *gin.Context) {
 	// Sanitize content URLs (HTML content with image tags)
 	event.Content = SanitizeHTMLContent(event.Content)
 
-	// Get client IP for user-specific reaction data
-	clientIP := c.ClientIP()
+	// Identify the visitor for user-specific reaction data
+	visitor := readingVisitor(c, db)
 
-	// Build response with reaction summary
+	// Build response with reaction summary and status timeline
 	type EventWithReactions struct {
 		models.Event
-		ReactionSummary models.ReactionSummary `json:"reaction_summary"`
+		ReactionSummary models.ReactionSummary      `json:"reaction_summary"`
+		StatusTimeline  []models.PublicStatusChange `json:"status_timeline"`
 	}
 
-	summary := getReactionSummary(db, event.ID, clientIP)
+	timeline, err := models.GetPublicEventStatusTimeline(db, event.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch status timeline"})
+		return
+	}
+
+	summary := getReactionSummary(db, event.ID, visitor)
 	response := EventWithReactions{
 		Event:           event,
 		ReactionSummary: summary,
+		StatusTimeline:  timeline,
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -247,9 +318,21 @@ func CreateEvent(c *gin.Context) {
 	// Database connection
 	db := database.GetDB()
 
-	// Ensure status definition exists (auto-creates if needed)
-	if _, err := models.GetOrCreateStatusDefinition(db, string(req.Status)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ensure status definition"})
+	statusRef := models.StatusRefFromRequest(&req.Status, req.StatusID)
+	if statusRef == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status is required"})
+		return
+	}
+
+	// Resolve status definition (auto-creates names if needed)
+	statusDef, ok := resolveEventStatus(c, db, *statusRef)
+	if !ok {
+		return
+	}
+
+	// Single-select tag groups allow only one of their tags per event
+	if err := models.ValidateTagSelection(db, req.TagIDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -263,16 +346,18 @@ func CreateEvent(c *gin.Context) {
 		Title:   req.Title,
 		Slug:    slug,
 		Media:   string(mediaJSON),
-		Status:  req.Status,
 		Date:    req.Date,
 		Content: req.Content,
 	}
+	event.SetStatus(statusDef)
 
 	if err := db.Create(&event).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create event"})
 		return
 	}
 
+	recordStatusChange(c, db, event.ID, nil, statusDef)
+
 	// Associate tags with the event
 	if len(req.TagIDs) > 0 {
 		var tags []models.Tag
@@ -287,7 +372,7 @@ func CreateEvent(c *gin.Context) {
 	}
 
 	// Reload event with tags for response
-	if err := db.Preload("Tags").First(&event, event.ID).Error; err != nil {
+	if err := db.Preload("Tags").Preload("StatusDefinition").First(&event, event.ID).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reload event"})
 		return
 	}
@@ -311,13 +396,41 @@ func UpdateEvent(c *gin.Context) {
 
 	db := database.GetDB()
 	var event models.Event
-	if err := db.Preload("Tags").First(&event, eventID).Error; err != nil {
+	if err := db.Preload("Tags").Preload("StatusDefinition").First(&event, eventID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 		return
 	}
 
 	// Store the original status to detect changes
-	originalStatus := event.Status
+	originalStatusID := event.StatusID
+	originalStatusDef := event.StatusDefinition
+
+	// Check the status transition before changing anything
+	var statusDef *models.EventStatusDefinition
+	if statusRef := models.StatusRefFromRequest(req.Status, req.StatusID); statusRef != nil {
+		var ok bool
+		if statusDef, ok = resolveEventStatus(c, db, *statusRef); !ok {
+			return
+		}
+		if !enforceStatusWorkflow(c, db, []models.Event{event}, statusDef.ID, req.OverrideWorkflow) {
+			return
+		}
+	}
+
+	var tags []models.Tag
+	if req.TagIDs != nil && len(*req.TagIDs) > 0 {
+		if err := models.ValidateTagSelection(db, *req.TagIDs); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Find(&tags, *req.TagIDs).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag IDs"})
+			return
+		}
+	}
+
+	// Files no longer referenced are only deleted once the event is saved
+	var removedFiles []string
 
 	// Update fields if provided
 	if req.Title != nil {
@@ -329,109 +442,60 @@ func UpdateEvent(c *gin.Context) {
 		}
 		event.Slug = newSlug
 	}
-	if req.TagIDs != nil {
-		var tags []models.Tag
-		// Only query for tags if we have IDs to find
-		if len(*req.TagIDs) > 0 {
-			if err := db.Find(&tags, *req.TagIDs).Error; err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag IDs"})
-				return
-			}
-		}
-		// Replace with the tags (empty array if no tag IDs provided)
-		if err := db.Model(&event).Association("Tags").Replace(tags); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tags"})
-			return
-		}
-	}
 	if req.Media != nil {
-		// Clean up old media files that are no longer referenced
+		// Find old media files that are no longer referenced
 		if event.Media != "" {
-			// Parse old media URLs
 			var oldMediaURLs []string
 			if err := json.Unmarshal([]byte(event.Media), &oldMediaURLs); err == nil {
-				// Find URLs that are in old media but not in new media
-				newMediaURLs := req.Media
-				removedURLs := []string{}
-
-				for _, oldURL := range oldMediaURLs {
-					found := false
-					for _, newURL := range newMediaURLs {
-						if oldURL == newURL {
-							found = true
-							break
-						}
-					}
-					if !found {
-						removedURLs = append(removedURLs, oldURL)
-					}
-				}
-
-				// Clean up only the removed URLs
-				for _, removedURL := range removedURLs {
-					if err := deleteImageFromURL(removedURL); err != nil {
-						fmt.Printf("Warning: Failed to cleanup removed media file %s for event %d: %v\n", removedURL, eventID, err)
-					}
-				}
+				removedFiles = append(removedFiles, removedURLs(oldMediaURLs, req.Media)...)
 			}
 		}
 		mediaJSON, _ := json.Marshal(req.Media)
 		event.Media = string(mediaJSON)
 	}
-	if req.Status != nil {
-		event.Status = *req.Status
-		// Ensure status definition exists for new status value
-		if _, err := models.GetOrCreateStatusDefinition(db, string(event.Status)); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ensure status definition"})
-			return
-		}
+	if statusDef != nil {
+		event.SetStatus(statusDef)
 	}
 	if req.Date != nil {
 		event.Date = *req.Date
+		event.LegacyDate = ""
 	}
 	if req.Content != nil {
-		// Clean up images that were removed from content
+		// Find images that were removed from content
 		if event.Content != "" && event.Content != *req.Content {
-			oldImages := extractImagesFromContent(event.Content)
-			newImages := extractImagesFromContent(*req.Content)
-
-			// Find images that are in old content but not in new content
-			for _, oldURL := range oldImages {
-				found := false
-				for _, newURL := range newImages {
-					if oldURL == newURL {
-						found = true
-						break
-					}
-				}
-				if !found {
-					if err := deleteImageFromURL(oldURL); err != nil {
-						fmt.Printf("Warning: Failed to cleanup removed content image %s for event %d: %v\n", oldURL, eventID, err)
-					}
-				}
-			}
+			removedFiles = append(removedFiles, removedURLs(extractImagesFromContent(event.Content), extractImagesFromContent(*req.Content))...)
 		}
 		event.Content = *req.Content
 	}
 	// Order field removed
 
-	if err := db.Save(&event).Error; err != nil {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if req.TagIDs != nil {
+			// Replace with the tags (empty array if no tag IDs provided)
+			if err := tx.Model(&event).Association("Tags").Replace(tags); err != nil {
+				return err
+			}
+		}
+		return tx.Omit("Tags").Save(&event).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event"})
 		return
 	}
 
-	// Trigger newsletter automation if status changed
-	if req.Status != nil && originalStatus != event.Status {
-		go func() {
-			automationService := services.NewNewsletterAutomationService()
-			if err := automationService.ProcessStatusChange(event.ID, originalStatus, event.Status); err != nil {
-				fmt.Printf("Newsletter automation error for event %d: %v\n", event.ID, err)
-			}
-		}()
+	for _, removedURL := range removedFiles {
+		if err := deleteImageFromURL(removedURL); err != nil {
+			fmt.Printf("Warning: Failed to cleanup removed file %s for event %d: %v\n", removedURL, eventID, err)
+		}
+	}
+
+	// Record the change and trigger newsletter automation if status changed
+	if originalStatusID != event.StatusID {
+		recordStatusChange(c, db, event.ID, originalStatusDef, event.StatusDefinition)
 	}
 
 	// Reload event with tags for response
-	if err := db.Preload("Tags").First(&event, event.ID).Error; err != nil {
+	if err := db.Preload("Tags").Preload("StatusDefinition").First(&event, event.ID).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reload event"})
 		return
 	}
@@ -439,6 +503,86 @@ func UpdateEvent(c *gin.Context) {
 	c.JSON(http.StatusOK, event)
 }
 
+// removedURLs returns the URLs in old that are not in new
+func removedURLs(old, new []string) []string {
+	var removed []string
+	for _, oldURL := range old {
+		found := false
+		for _, newURL := range new {
+			if oldURL == newURL {
+				found = true
+				break
+			}
+		}
+		if !found {
+			removed = append(removed, oldURL)
+		}
+	}
+	return removed
+}
+
+// BulkUpdateEventStatus moves several events to the same status.
+// Nothing is changed if the workflow rejects any of the transitions.
+func BulkUpdateEventStatus(c *gin.Context) {
+	var req models.BulkUpdateEventStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	statusRef := models.StatusRefFromRequest(req.Status, req.StatusID)
+	if statusRef == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status is required"})
+		return
+	}
+	if len(req.EventIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "event_ids cannot be empty"})
+		return
+	}
+
+	db := database.GetDB()
+
+	statusDef, ok := resolveEventStatus(c, db, *statusRef)
+	if !ok {
+		return
+	}
+
+	var events []models.Event
+	if err := db.Preload("StatusDefinition").Where("id IN ?", req.EventIDs).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+		return
+	}
+	if len(events) != len(models.UniqueIDs(req.EventIDs)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "One or more events not found"})
+		return
+	}
+
+	if !enforceStatusWorkflow(c, db, events, statusDef.ID, req.OverrideWorkflow) {
+		return
+	}
+
+	if err := db.Model(&models.Event{}).Where("id IN ?", req.EventIDs).Update("status_id", statusDef.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update events"})
+		return
+	}
+
+	// Record the change and trigger newsletter automation for events whose status changed
+	updated := 0
+	for _, event := range events {
+		if event.StatusID == statusDef.ID {
+			continue
+		}
+		updated++
+		recordStatusChange(c, db, event.ID, event.StatusDefinition, statusDef)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Events updated successfully",
+		"status":  statusDef,
+		"updated": updated,
+	})
+}
+
 func DeleteEvent(c *gin.Context) {
 	id := c.Param("id")
 	eventID, err := strconv.ParseUint(id, 10, 32)
@@ -464,16 +608,29 @@ func DeleteEvent(c *gin.Context) {
 		}
 	}
 
-	// Clean up images in content (from TipTap editor)
-	if event.Content != "" {
-		if err := cleanupContentImages(event.Content); err != nil {
+	// Clean up images in content (from TipTap editor), including translated content
+	translations, _ := models.GetEventTranslations(db, event.ID)
+	contents := []string{event.Content}
+	for _, translation := range translations {
+		contents = append(contents, translation.Content)
+	}
+	for _, content := range contents {
+		if content == "" {
+			continue
+		}
+		if err := cleanupContentImages(content); err != nil {
 			// Log the error but don't fail the deletion
 			fmt.Printf("Warning: Failed to cleanup content images for event %d: %v\n", eventID, err)
 		}
 	}
 
-	// Delete the event from database
-	if err := db.Delete(&models.Event{}, eventID).Error; err != nil {
+	// Delete the event and its translations from database
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("event_id = ?", eventID).Delete(&models.EventTranslation{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Event{}, eventID).Error
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete event"})
 		return
 	}
@@ -489,9 +646,6 @@ func VoteEvent(c *gin.Context) {
 		return
 	}
 
-	// Get client IP address
-	clientIP := c.ClientIP()
-
 	db := database.GetDB()
 	var event models.Event
 	if err := db.First(&event, eventID).Error; err != nil {
@@ -499,15 +653,21 @@ func VoteEvent(c *gin.Context) {
 		return
 	}
 
+	visitor, err := reactingVisitor(c, db)
+	if err != nil {
+		respondVisitorError(c, err)
+		return
+	}
+
 	// Voting now allowed for all statuses (restriction removed)
 
-	// Check if this IP has already voted for this event using count to avoid error logging
+	// Check if this visitor has already voted for this event using count to avoid error logging
 	var voteCount int64
-	db.Model(&models.Vote{}).Where("event_id = ? AND ip_address = ?", eventID, clientIP).Count(&voteCount)
+	visitor.Match(db.Model(&models.Vote{}).Where("event_id = ?", eventID)).Count(&voteCount)
 	if voteCount > 0 {
 		// User has already voted, get the existing vote for deletion
 		var existingVote models.Vote
-		db.Where("event_id = ? AND ip_address = ?", eventID, clientIP).First(&existingVote)
+		visitor.Match(db.Where("event_id = ?", eventID)).First(&existingVote)
 		// User has already voted, so remove the vote (toggle functionality)
 		if err := db.Delete(&existingVote).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove vote"})
@@ -531,10 +691,36 @@ func VoteEvent(c *gin.Context) {
 		return
 	}
 
+	// Take over the vote made from this address before visitor IDs, which is already counted
+	claimed, err := visitor.ClaimIPRecord(db, &models.Vote{}, "event_id = ?", eventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record vote"})
+		return
+	}
+	if claimed {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Vote recorded successfully",
+			"votes":   event.Votes,
+			"voted":   true,
+		})
+		return
+	}
+
+	limited, err := visitor.IPLimitReached(db.Model(&models.Vote{}).Where("event_id = ?", eventID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record vote"})
+		return
+	}
+	if limited {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many votes from your network on this event"})
+		return
+	}
+
 	// Create vote record
 	vote := models.Vote{
-		EventID:   uint(eventID),
-		IPAddress: clientIP,
+		EventID:     uint(eventID),
+		VisitorHash: visitor.Hash,
+		IPHash:      visitor.IPHash,
 	}
 
 	if err := db.Create(&vote).Error; err != nil {
@@ -564,19 +750,19 @@ func CheckVoteStatus(c *gin.Context) {
 		return
 	}
 
-	// Get client IP address
-	clientIP := c.ClientIP()
-
 	db := database.GetDB()
+
+	// Identify the visitor
+	visitor := readingVisitor(c, db)
 	var event models.Event
 	if err := db.First(&event, eventID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 		return
 	}
 
-	// Check if this IP has voted for this event using count to avoid error logging
+	// Check if this visitor has voted for this event using count to avoid error logging
 	var voteCount int64
-	db.Model(&models.Vote{}).Where("event_id = ? AND ip_address = ?", eventID, clientIP).Count(&voteCount)
+	visitor.Match(db.Model(&models.Vote{}).Where("event_id = ?", eventID)).Count(&voteCount)
 	hasVoted := voteCount > 0
 
 	c.JSON(http.StatusOK, gin.H{
@@ -586,23 +772,101 @@ func CheckVoteStatus(c *gin.Context) {
 }
 
 // getStatusForCategory finds the first status mapped to a given category
-func getStatusForCategory(db *gorm.DB, categoryID string, themeID string) (string, error) {
+func getStatusForCategory(db *gorm.DB, categoryID string, themeID string) (*models.EventStatusDefinition, error) {
 	var mapping models.StatusCategoryMapping
 	err := db.Where("category_id = ? AND theme_id = ?", categoryID, themeID).
 		First(&mapping).Error
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// Get the status definition to get the display name
 	var statusDef models.EventStatusDefinition
 	err = db.First(&statusDef, mapping.StatusDefinitionID).Error
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	return &statusDef, nil
+}
+
+// enforceStatusWorkflow checks that every event may move to the target status, writing a 409 response
+// listing the rejected transitions otherwise. Only admins may override the workflow.
+func enforceStatusWorkflow(c *gin.Context, db *gorm.DB, events []models.Event, toStatusID uint, override bool) bool {
+	role := middleware.GetRole(c)
+	if override {
+		if role != middleware.RoleAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can override the status workflow"})
+			return false
+		}
+		return true
+	}
+
+	var rejected []gin.H
+	for _, event := range events {
+		err := models.CheckStatusTransition(db, event.StatusID, toStatusID, role)
+		if transitionErr, ok := err.(*models.TransitionError); ok {
+			rejected = append(rejected, gin.H{
+				"event_id":   event.ID,
+				"event":      event.Title,
+				"transition": transitionErr,
+			})
+			continue
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check status workflow"})
+			return false
+		}
+	}
+
+	if len(rejected) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    "Status change not allowed by the workflow",
+			"rejected": rejected,
+		})
+		return false
+	}
+	return true
+}
+
+// recordStatusChange adds the change to the event's status timeline, then runs newsletter
+// automation in the background for changes of an existing event (from is nil on creation)
+func recordStatusChange(c *gin.Context, db *gorm.DB, eventID uint, from, to *models.EventStatusDefinition) {
+	change, err := models.RecordStatusChange(db, eventID, from, to, c.GetString("username"))
+	if err != nil {
+		fmt.Printf("Warning: Failed to record status change for event %d: %v\n", eventID, err)
 	}
 
-	return statusDef.DisplayName, nil
+	if from == nil {
+		return
+	}
+
+	go func() {
+		automationService := services.NewNewsletterAutomationService()
+		fired, err := automationService.ProcessStatusChange(eventID, from.ID, to.ID)
+		if err != nil {
+			fmt.Printf("Newsletter automation error for event %d: %v\n", eventID, err)
+		}
+		if fired && change != nil {
+			if err := models.MarkAutomationFired(db, change.ID); err != nil {
+				fmt.Printf("Warning: Failed to flag automation on status change %d: %v\n", change.ID, err)
+			}
+		}
+	}()
+}
+
+// resolveEventStatus resolves the status of an event request, writing the error response on failure
+func resolveEventStatus(c *gin.Context, db *gorm.DB, ref models.StatusRef) (*models.EventStatusDefinition, bool) {
+	statusDef, err := models.ResolveStatusRef(db, ref, true)
+	if err == models.ErrStatusNotFound {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Status not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ensure status definition"})
+		return nil, false
+	}
+	return statusDef, true
 }
 
 // SubmitFeedback allows public users to submit feedback
@@ -658,10 +922,18 @@ func SubmitFeedback(c *gin.Context) {
 	}
 
 	// Determine which status to use for feedback
-	feedbackStatus := models.EventStatus("Feedback") // default status for feedback submissions
+	var feedbackStatus *models.EventStatusDefinition
 	if settings.CurrentThemeID != "" {
 		if status, err := getStatusForCategory(db, "feedback", settings.CurrentThemeID); err == nil {
-			feedbackStatus = models.EventStatus(status)
+			feedbackStatus = status
+		}
+	}
+	if feedbackStatus == nil {
+		// Default status for feedback submissions
+		feedbackStatus, err = models.GetOrCreateStatusDefinition(db, "Feedback")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ensure status definition"})
+			return
 		}
 	}
 
@@ -669,16 +941,18 @@ func SubmitFeedback(c *gin.Context) {
 		Title:   req.Title,
 		Slug:    slug,
 		Media:   string(mediaJSON),
-		Status:  feedbackStatus,
-		Date:    "",
+		Date:    models.EventDate{},
 		Content: req.Content,
 	}
+	event.SetStatus(feedbackStatus)
 
 	if err := db.Create(&event).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit feedback"})
 		return
 	}
 
+	recordStatusChange(c, db, event.ID, nil, feedbackStatus)
+
 	// Associate "Feedback" tag with the event
 	var feedbackTag models.Tag
 	if err := db.Where("name = ?", "Feedback").First(&feedbackTag).Error; err != nil {
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"shipshipship/database"
+	"shipshipship/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// requestedLocales returns the languages asked for with ?lang=, or else with the Accept-Language header
+func requestedLocales(c *gin.Context) []string {
+	c.Header("Vary", "Accept-Language")
+	if lang := c.Query("lang"); lang != "" {
+		if locale := models.NormalizeLocale(lang); locale != "" {
+			return []string{locale}
+		}
+		return []string{}
+	}
+	return models.AcceptedLocales(c.GetHeader("Accept-Language"))
+}
+
+// localizeEvents switches events with loaded translations to the language of the request
+func localizeEvents(c *gin.Context, db *gorm.DB, events []models.Event) {
+	requested := requestedLocales(c)
+	defaultLocale := models.ContentLocale(db)
+	for i := range events {
+		events[i].Localize(requested, defaultLocale)
+	}
+}
+
+// loadTranslatedEvent returns the event with the ID in the path, or writes an error
+func loadTranslatedEvent(c *gin.Context, db *gorm.DB) *models.Event {
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return nil
+	}
+
+	var event models.Event
+	if err := db.Preload("StatusDefinition").First(&event, eventID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch event"})
+		}
+		return nil
+	}
+	return &event
+}
+
+// translationLocale returns the supported locale in the path, or writes an error
+func translationLocale(c *gin.Context, db *gorm.DB) (string, bool) {
+	locale := models.NormalizeLocale(c.Param("locale"))
+	if locale == "" || locale != strings.ToLower(c.Param("locale")) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported locale: " + c.Param("locale")})
+		return "", false
+	}
+	if locale == models.ContentLocale(db) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Events are written in " + locale + " by default; edit the event instead"})
+		return "", false
+	}
+	return locale, true
+}
+
+// GetEventTranslations returns an event's translations (admin only)
+func GetEventTranslations(c *gin.Context) {
+	db := database.GetDB()
+	event := loadTranslatedEvent(c, db)
+	if event == nil {
+		return
+	}
+
+	translations, err := models.GetEventTranslations(db, event.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch translations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"default_locale":    models.ContentLocale(db),
+		"supported_locales": models.SupportedDateLocales(),
+		"translations":      translations,
+	})
+}
+
+// SaveEventTranslation creates or updates an event's title and content in a language (admin only)
+func SaveEventTranslation(c *gin.Context) {
+	var req models.SaveEventTranslationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required"})
+		return
+	}
+	if strings.TrimSpace(req.Title) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required"})
+		return
+	}
+
+	db := database.GetDB()
+	locale, ok := translationLocale(c, db)
+	if !ok {
+		return
+	}
+	event := loadTranslatedEvent(c, db)
+	if event == nil {
+		return
+	}
+
+	translation := &models.EventTranslation{
+		EventID: event.ID,
+		Locale:  locale,
+		Title:   req.Title,
+		Content: req.Content,
+	}
+	if err := models.SaveEventTranslation(db, translation); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save translation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, translation)
+}
+
+// DeleteEventTranslation removes an event's translation; readers of that language get the default one (admin only)
+func DeleteEventTranslation(c *gin.Context) {
+	db := database.GetDB()
+	locale, ok := translationLocale(c, db)
+	if !ok {
+		return
+	}
+	event := loadTranslatedEvent(c, db)
+	if event == nil {
+		return
+	}
+
+	deleted, err := models.DeleteEventTranslation(db, event.ID, locale)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete translation"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Translation not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Translation deleted"})
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"shipshipship/database"
+	"shipshipship/email"
+	"shipshipship/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+var dkimSelectorPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9.-]*[A-Za-z0-9])?$`)
+
+func isValidDKIMSelector(selector string) bool {
+	return len(selector) <= 63 && dkimSelectorPattern.MatchString(selector)
+}
+
+// dkimRecord is a DNS TXT record to publish for a DKIM key
+type dkimRecord struct {
+	Status   string `json:"status"` // active or pending
+	Selector string `json:"selector"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Value    string `json:"value"`
+	Zone     string `json:"zone"` // Zone file line, with the value split into 255-character strings
+}
+
+// dkimZoneEntry formats a record for a zone file, where a single TXT string can't exceed 255 characters
+func dkimZoneEntry(name, value string) string {
+	var chunks []string
+	for len(value) > 255 {
+		chunks = append(chunks, `"`+value[:255]+`"`)
+		value = value[255:]
+	}
+	chunks = append(chunks, `"`+value+`"`)
+	return name + ". IN TXT ( " + strings.Join(chunks, " ") + " )"
+}
+
+func dkimRecords(settings *models.MailSettings) ([]dkimRecord, error) {
+	domain := settings.DKIMSigningDomain()
+	records := []dkimRecord{}
+	for _, key := range []struct {
+		status, selector, privateKey string
+	}{
+		{"active", settings.DKIMSelector, settings.DKIMPrivateKey},
+		{"pending", settings.DKIMPendingSelector, settings.DKIMPendingPrivateKey},
+	} {
+		if key.privateKey == "" || key.selector == "" {
+			continue
+		}
+		value, err := email.DKIMRecordValue(key.privateKey)
+		if err != nil {
+			return nil, fmt.Errorf("%s key: %v", key.status, err)
+		}
+		name := email.DKIMRecordName(key.selector, domain)
+		records = append(records, dkimRecord{
+			Status:   key.status,
+			Selector: key.selector,
+			Name:     name,
+			Type:     "TXT",
+			Value:    value,
+			Zone:     dkimZoneEntry(name, value),
+		})
+	}
+	return records, nil
+}
+
+func dkimResponse(c *gin.Context, settings *models.MailSettings) {
+	records, err := dkimRecords(settings)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":          settings.DKIMEnabled,
+		"domain":           settings.DKIMSigningDomain(),
+		"selector":         settings.DKIMSelector,
+		"pending_selector": settings.DKIMPendingSelector,
+		"records":          records,
+	})
+}
+
+// GetDKIMRecords returns the DNS TXT records to publish for the active key and any key being rotated in
+func GetDKIMRecords(c *gin.Context) {
+	db := database.GetDB()
+	settings, err := models.GetOrCreateMailSettings(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch mail settings"})
+		return
+	}
+	dkimResponse(c, settings)
+}
+
+// GenerateDKIMKey creates a key pair on the server. Without an active key it becomes the active
+// key; otherwise it's pending until its DNS record is published and it's activated.
+func GenerateDKIMKey(c *gin.Context) {
+	var req struct {
+		Selector string `json:"selector"`
+		Bits     int    `json:"bits"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	if req.Bits == 0 {
+		req.Bits = 2048
+	}
+	if req.Bits != 1024 && req.Bits != 2048 && req.Bits != 4096 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bits must be 1024, 2048 or 4096"})
+		return
+	}
+
+	req.Selector = strings.TrimSpace(req.Selector)
+	if req.Selector == "" {
+		// Date-based selectors keep every rotation distinct
+		req.Selector = "ship" + time.Now().Format("20060102")
+	}
+	if !isValidDKIMSelector(req.Selector) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "selector may only contain letters, digits, dots and hyphens"})
+		return
+	}
+
+	db := database.GetDB()
+	settings, err := models.GetOrCreateMailSettings(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch mail settings"})
+		return
+	}
+
+	if settings.DKIMSigningDomain() == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set a DKIM domain or a from email first"})
+		return
+	}
+
+	rotating := settings.DKIMPrivateKey != ""
+	if rotating && req.Selector == settings.DKIMSelector {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A rotated key needs a new selector, so both DNS records can be published at once"})
+		return
+	}
+
+	privateKey, err := email.GenerateDKIMKey(req.Bits)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate DKIM key"})
+		return
+	}
+
+	if rotating {
+		settings.DKIMPendingSelector = req.Selector
+		settings.DKIMPendingPrivateKey = privateKey
+	} else {
+		settings.DKIMSelector = req.Selector
+		settings.DKIMPrivateKey = privateKey
+	}
+	if err := db.Save(settings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save DKIM key"})
+		return
+	}
+
+	dkimResponse(c, settings)
+}
+
+// ActivateDKIMKey makes the pending key the signing key. The previous key's DNS record should
+// stay published for a few days, until mail signed with it has been delivered.
+func ActivateDKIMKey(c *gin.Context) {
+	db := database.GetDB()
+	settings, err := models.GetOrCreateMailSettings(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch mail settings"})
+		return
+	}
+
+	if settings.DKIMPendingPrivateKey == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "There is no pending DKIM key to activate"})
+		return
+	}
+
+	previousSelector := settings.DKIMSelector
+	settings.DKIMSelector = settings.DKIMPendingSelector
+	settings.DKIMPrivateKey = settings.DKIMPendingPrivateKey
+	settings.DKIMPendingSelector = ""
+	settings.DKIMPendingPrivateKey = ""
+	if err := db.Save(settings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to activate DKIM key"})
+		return
+	}
+
+	records, err := dkimRecords(settings)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":           settings.DKIMEnabled,
+		"domain":            settings.DKIMSigningDomain(),
+		"selector":          settings.DKIMSelector,
+		"previous_selector": previousSelector,
+		"records":           records,
+	})
+}
+
+// CancelDKIMRotation discards the pending key
+func CancelDKIMRotation(c *gin.Context) {
+	db := database.GetDB()
+	settings, err := models.GetOrCreateMailSettings(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch mail settings"})
+		return
+	}
+
+	settings.DKIMPendingSelector = ""
+	settings.DKIMPendingPrivateKey = ""
+	if err := db.Save(settings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to discard pending DKIM key"})
+		return
+	}
+
+	dkimResponse(c, settings)
+}
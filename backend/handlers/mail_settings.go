@@ -3,12 +3,12 @@ package handlers
 import (
 	"fmt"
 	"net/http"
-	"net/smtp"
 	"strings"
 
 	"shipshipship/database"
+	"shipshipship/email"
 	"shipshipship/models"
-	"shipshipship/utils"
+	"shipshipship/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,10 +21,8 @@ func GetMailSettings(c *gin.Context) {
 		return
 	}
 
-	// Don't return the password in the response for security
-	settings.SMTPPassword = ""
-
-	c.JSON(http.StatusOK, settings)
+	// Secrets are write-only: the response only says whether they're set
+	c.JSON(http.StatusOK, settings.Masked())
 }
 
 func UpdateMailSettings(c *gin.Context) {
@@ -51,28 +49,87 @@ func UpdateMailSettings(c *gin.Context) {
 	if req.SMTPUsername != nil {
 		settings.SMTPUsername = *req.SMTPUsername
 	}
-	if req.SMTPPassword != nil {
+	if models.IsSecretUpdate(req.SMTPPassword) {
 		settings.SMTPPassword = *req.SMTPPassword
 	}
 	if req.SMTPEncryption != nil {
 		settings.SMTPEncryption = *req.SMTPEncryption
 	}
+	if req.SMTPMaxPerConnection != nil {
+		if *req.SMTPMaxPerConnection < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "smtp_max_per_connection must be 0 or more"})
+			return
+		}
+		settings.SMTPMaxPerConnection = *req.SMTPMaxPerConnection
+	}
+	if req.SMTPMessageDelayMs != nil {
+		if *req.SMTPMessageDelayMs < 0 || *req.SMTPMessageDelayMs > 60000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "smtp_message_delay_ms must be between 0 and 60000"})
+			return
+		}
+		settings.SMTPMessageDelayMs = *req.SMTPMessageDelayMs
+	}
 	if req.FromEmail != nil {
 		settings.FromEmail = *req.FromEmail
 	}
 	if req.FromName != nil {
 		settings.FromName = *req.FromName
 	}
+	if req.Transport != nil {
+		transport := strings.ToLower(strings.TrimSpace(*req.Transport))
+		if !models.IsValidMailTransport(transport) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "transport must be smtp, sendmail, file, maildir or api"})
+			return
+		}
+		settings.Transport = transport
+	}
+	if req.SendmailPath != nil {
+		settings.SendmailPath = strings.TrimSpace(*req.SendmailPath)
+	}
+	if req.FileDirectory != nil {
+		settings.FileDirectory = strings.TrimSpace(*req.FileDirectory)
+	}
+	if req.APIURL != nil {
+		settings.APIURL = strings.TrimSpace(*req.APIURL)
+	}
+	if models.IsSecretUpdate(req.APIKey) {
+		settings.APIKey = *req.APIKey
+	}
+	if req.DKIMDomain != nil {
+		settings.DKIMDomain = strings.ToLower(strings.TrimSpace(*req.DKIMDomain))
+	}
+	if req.DKIMSelector != nil {
+		selector := strings.TrimSpace(*req.DKIMSelector)
+		if selector != "" && !isValidDKIMSelector(selector) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "dkim_selector may only contain letters, digits, dots and hyphens"})
+			return
+		}
+		settings.DKIMSelector = selector
+	}
+	if models.IsSecretUpdate(req.DKIMPrivateKey) && strings.TrimSpace(*req.DKIMPrivateKey) != "" {
+		if _, err := email.ParseDKIMPrivateKey(*req.DKIMPrivateKey); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		settings.DKIMPrivateKey = strings.TrimSpace(*req.DKIMPrivateKey) + "\n"
+	}
+	if req.DKIMEnabled != nil {
+		settings.DKIMEnabled = *req.DKIMEnabled
+	}
+	if settings.DKIMEnabled {
+		if _, err := services.NewDKIMSigner(settings); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("DKIM can't be enabled: %v", err)})
+			return
+		}
+	}
 
 	if err := db.Save(&settings).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update mail settings"})
 		return
 	}
 
-	// Don't return the password in the response for security
-	settings.SMTPPassword = ""
-
-	c.JSON(http.StatusOK, settings)
+	// Secrets are write-only: the response only says whether they're set
+	c.JSON(http.StatusOK, settings.Masked())
 }
 
 func TestMailSettings(c *gin.Context) {
@@ -92,9 +149,9 @@ func TestMailSettings(c *gin.Context) {
 		return
 	}
 
-	// Validate that required settings are configured
-	if settings.SMTPHost == "" || settings.FromEmail == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "SMTP host and from email must be configured"})
+	// Validate that the selected transport is configured
+	if err := settings.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -109,47 +166,19 @@ func TestMailSettings(c *gin.Context) {
 }
 
 func sendTestEmail(settings *models.MailSettings, toEmail string) error {
-	// Prepare email content
-	fromName := settings.FromName
-	if fromName == "" {
-		fromName = "ShipShipShip"
-	}
-
-	from := fmt.Sprintf("%s <%s>", fromName, settings.FromEmail)
-	to := toEmail
 	subject := "ShipShipShip Test Email"
-	body := `This is a test email from ShipShipShip to verify your SMTP configuration.
+	body := `This is a test email from ShipShipShip to verify your mail configuration.
 
 If you received this email, your mail settings are working correctly!
 
 Best regards,
 ShipShipShip Team`
 
-	// Prepare message
-	message := fmt.Sprintf("From: %s\r\n", from)
-	message += fmt.Sprintf("To: %s\r\n", to)
-	message += fmt.Sprintf("Subject: %s\r\n", subject)
-	message += "MIME-Version: 1.0\r\n"
-	message += "Content-Type: text/plain; charset=UTF-8\r\n"
-	message += "\r\n"
-	message += body
-
-	// Determine authentication
-	var auth smtp.Auth
-	if settings.SMTPUsername != "" {
-		auth = smtp.PlainAuth("", settings.SMTPUsername, settings.SMTPPassword, settings.SMTPHost)
-	}
-
-	// Send email based on encryption type
-	addr := fmt.Sprintf("%s:%d", settings.SMTPHost, settings.SMTPPort)
-
-	switch strings.ToLower(settings.SMTPEncryption) {
-	case "ssl":
-		return utils.SendMailWithSSL(addr, auth, settings.FromEmail, []string{toEmail}, []byte(message))
-	case "tls":
-		return utils.SendMailWithTLS(addr, auth, settings.FromEmail, []string{toEmail}, []byte(message))
-	default:
-		// No encryption
-		return smtp.SendMail(addr, auth, settings.FromEmail, []string{toEmail}, []byte(message))
-	}
+	emailService := services.NewEmailServiceWithSettings(settings)
+	defer emailService.Close()
+	return emailService.SendMessage(&email.Message{
+		To:      toEmail,
+		Subject: subject,
+		Text:    body,
+	})
 }
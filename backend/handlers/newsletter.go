@@ -1,17 +1,17 @@
 package handlers
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
-	"net/smtp"
 	"os"
 	"strconv"
-	"strings"
+	"time"
 
 	"shipshipship/constants"
 	"shipshipship/database"
+	emailtemplates "shipshipship/email"
 	"shipshipship/models"
+	"shipshipship/services"
 	"shipshipship/utils"
 
 	"github.com/gin-gonic/gin"
@@ -50,11 +50,26 @@ func SubscribeToNewsletter(c *gin.Context) {
 		return
 	}
 
+	if req.Frequency != "" && !models.IsValidDigestFrequency(req.Frequency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "frequency must be instant, weekly or monthly"})
+		return
+	}
+
+	// Write emails in the language asked for, or the browser's
+	language := models.PreferredLocale(c.GetHeader("Accept-Language"))
+	if req.Language != "" {
+		language = models.NormalizeLocale(req.Language)
+		if language == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported language: " + req.Language})
+			return
+		}
+	}
+
 	db := database.GetDB()
 
 	// Check if user is already subscribed
 	existingSubscriber, err := models.FindSubscriberByEmail(db, req.Email)
-	if err == nil {
+	if err == nil && existingSubscriber.IsActive {
 		c.JSON(http.StatusOK, gin.H{
 			"message":            "You are already subscribed to our newsletter",
 			"email":              existingSubscriber.Email,
@@ -63,7 +78,61 @@ func SubscribeToNewsletter(c *gin.Context) {
 		return
 	}
 
-	subscriber, err := models.Subscribe(db, req.Email)
+	// Anyone can post an address, so a pending subscriber is sent the confirmation link again
+	// rather than activated
+	if err == nil {
+		mailSettings, err := models.GetOrCreateMailSettings(db)
+		if err == nil {
+			err = mailSettings.Validate()
+		}
+		if err == nil && !utils.HasSigningSecret() {
+			err = utils.ErrDefaultSigningSecret
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send the confirmation email"})
+			return
+		}
+		go sendConfirmationEmails(db, mailSettings, getBaseURL(c, db), []models.NewsletterSubscriber{*existingSubscriber})
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":               "Please confirm your subscription with the link we sent to your email",
+			"email":                 existingSubscriber.Email,
+			"already_subscribed":    false,
+			"confirmation_required": true,
+		})
+		return
+	}
+
+	var subscriber *models.NewsletterSubscriber
+	var topicsErr error
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if subscriber, err = models.Subscribe(tx, req.Email); err != nil {
+			return err
+		}
+		if len(req.TagIDs) > 0 || len(req.TagGroupIDs) > 0 {
+			topicsErr = models.SetSubscriberTopics(tx, subscriber, models.SubscriberTopicsRequest{
+				TagIDs:      req.TagIDs,
+				TagGroupIDs: req.TagGroupIDs,
+			})
+			if topicsErr != nil {
+				return topicsErr
+			}
+		}
+		if language != "" {
+			if err := models.SetSubscriberLanguage(tx, subscriber, language); err != nil {
+				return err
+			}
+		}
+		if req.Frequency != "" {
+			return models.SetSubscriberFrequency(tx, subscriber, req.Frequency)
+		}
+		return nil
+	})
+	if topicsErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": topicsErr.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to subscribe to newsletter"})
 		return
@@ -142,7 +211,7 @@ func CheckSubscriptionStatus(c *gin.Context) {
 	}
 
 	db := database.GetDB()
-	_, err := models.FindSubscriberByEmail(db, email)
+	subscriber, err := models.FindSubscriberByEmail(db, email)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusOK, gin.H{
@@ -157,7 +226,7 @@ func CheckSubscriptionStatus(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"subscribed": true,
-		"active":     true,
+		"active":     subscriber.IsActive,
 	})
 }
 
@@ -263,53 +332,50 @@ func GetNewsletterSubscribersPaginated(c *gin.Context) {
 func sendWelcomeEmail(db *gorm.DB, email string) error {
 	// Get mail settings
 	mailSettings, err := models.GetOrCreateMailSettings(db)
-	if err != nil || mailSettings.SMTPHost == "" || mailSettings.FromEmail == "" {
+	if err != nil {
 		return fmt.Errorf("mail settings not configured")
 	}
+	if err := mailSettings.Validate(); err != nil {
+		return fmt.Errorf("mail settings not configured: %v", err)
+	}
 
-	// Get project settings for project name
-	projectSettings, err := models.GetOrCreateSettings(db)
+	// Get branding, with BASE_URL for the unsubscribe link (not the project URL, which is the external website)
+	branding, err := models.GetBrandingSettingsWithBaseURL(db, os.Getenv("BASE_URL"))
 	if err != nil {
 		return fmt.Errorf("failed to get project settings: %v", err)
 	}
-
-	// Replace variables in template
-	projectName := projectSettings.Title
-	if projectName == "" {
-		projectName = "ShipShipShip"
+	if branding.ProjectName == "" {
+		branding.ProjectName = "ShipShipShip"
 	}
+	projectName := branding.ProjectName
 
-	// Get project URL (external website) from settings
-	projectURL := projectSettings.WebsiteURL
-
-	// Get base URL from BASE_URL env (for unsubscribe link)
-	baseURL := os.Getenv("BASE_URL")
-	if baseURL == "" {
-		// Fallback to relative URL
-		baseURL = ""
-	}
-
-	// Use baseURL for unsubscribe (not projectURL which is the external website)
-	unsubscribeURL := fmt.Sprintf("%s/unsubscribe?email=%s", baseURL, email)
-	if baseURL == "" {
-		unsubscribeURL = fmt.Sprintf("/unsubscribe?email=%s", email)
+	// Get welcome email template (check for custom template first)
+	welcomeTemplate := &models.EmailTemplate{
+		Type:    constants.TemplateTypeWelcome,
+		Subject: constants.SubjectWelcome,
+		Content: getWelcomeEmailTemplate(),
 	}
-
-	// Get welcome email template and subject (check for custom template first)
-	welcomeTemplate := getWelcomeEmailTemplate()
-	welcomeSubject := fmt.Sprintf("Welcome to %s!", projectName)
-
-	if customTemplate, err := models.GetEmailTemplate(db, "welcome"); err == nil {
-		welcomeTemplate = customTemplate.Content
-		welcomeSubject = strings.ReplaceAll(customTemplate.Subject, "{{project_name}}", projectName)
+	if customTemplate, err := models.GetEmailTemplate(db, constants.TemplateTypeWelcome); err == nil {
+		welcomeTemplate = customTemplate
 	} else if err != gorm.ErrRecordNotFound {
 		// Log only unexpected errors, not "record not found"
 		fmt.Printf("Warning: Failed to load custom welcome template: %v\n", err)
 	}
 
-	content := strings.ReplaceAll(welcomeTemplate, "{{project_name}}", projectName)
-	content = strings.ReplaceAll(content, "{{project_url}}", projectURL)
-	content = strings.ReplaceAll(content, "{{unsubscribe_url}}", unsubscribeURL)
+	subscriber := &models.NewsletterSubscriber{Email: email, Frequency: models.DigestFrequencyInstant}
+	if existing, err := models.FindSubscriberByEmail(db, email); err == nil {
+		subscriber = existing
+	}
+	welcomeTemplate, branding = emailtemplates.Localize(welcomeTemplate, branding, subscriber.Language)
+	welcomeContext := emailtemplates.NewWelcomeContext(branding).ForSubscriber(subscriber)
+	welcomeSubject, content, err := emailtemplates.RenderEmailTemplate(welcomeTemplate, welcomeContext)
+	if err != nil {
+		return fmt.Errorf("failed to render welcome template: %v", err)
+	}
+	textContent, err := emailtemplates.RenderEmailText(welcomeTemplate, welcomeContext)
+	if err != nil {
+		return fmt.Errorf("failed to render welcome template: %v", err)
+	}
 
 	// Prepare email
 	fromName := mailSettings.FromName
@@ -317,35 +383,15 @@ func sendWelcomeEmail(db *gorm.DB, email string) error {
 		fromName = projectName
 	}
 
-	from := fmt.Sprintf("%s <%s>", fromName, mailSettings.FromEmail)
-
-	// Prepare message
-	message := fmt.Sprintf("From: %s\r\n", from)
-	message += fmt.Sprintf("To: %s\r\n", email)
-	message += fmt.Sprintf("Subject: %s\r\n", welcomeSubject)
-	message += "MIME-Version: 1.0\r\n"
-	message += "Content-Type: text/html; charset=UTF-8\r\n"
-	message += "\r\n"
-	message += content
-
-	// Determine authentication
-	var auth smtp.Auth
-	if mailSettings.SMTPUsername != "" {
-		auth = smtp.PlainAuth("", mailSettings.SMTPUsername, mailSettings.SMTPPassword, mailSettings.SMTPHost)
-	}
-
-	// Send email based on encryption type
-	addr := fmt.Sprintf("%s:%d", mailSettings.SMTPHost, mailSettings.SMTPPort)
-
-	switch strings.ToLower(mailSettings.SMTPEncryption) {
-	case "ssl":
-		return utils.SendMailWithSSL(addr, auth, mailSettings.FromEmail, []string{email}, []byte(message))
-	case "tls":
-		return utils.SendMailWithTLS(addr, auth, mailSettings.FromEmail, []string{email}, []byte(message))
-	default:
-		// No encryption
-		return smtp.SendMail(addr, auth, mailSettings.FromEmail, []string{email}, []byte(message))
-	}
+	emailService := services.NewEmailServiceWithSettings(mailSettings)
+	defer emailService.Close()
+	return emailService.SendMessage(&emailtemplates.Message{
+		FromName: fromName,
+		To:       email,
+		Subject:  welcomeSubject,
+		HTML:     content,
+		Text:     textContent,
+	})
 }
 
 // getWelcomeEmailTemplate returns the default welcome email template
@@ -369,8 +415,9 @@ func GetEmailTemplates(c *gin.Context) {
 		result := make(map[string]interface{})
 		for _, template := range defaultTemplates {
 			result[template.Type] = map[string]string{
-				"subject": template.Subject,
-				"content": template.Content,
+				"subject":      template.Subject,
+				"content":      template.Content,
+				"text_content": "",
 			}
 		}
 		c.JSON(http.StatusOK, gin.H{"templates": result})
@@ -380,8 +427,9 @@ func GetEmailTemplates(c *gin.Context) {
 	result := make(map[string]interface{})
 	for templateType, template := range templates {
 		result[templateType] = map[string]string{
-			"subject": template.Subject,
-			"content": template.Content,
+			"subject":      template.Subject,
+			"content":      template.Content,
+			"text_content": template.TextContent,
 		}
 	}
 
@@ -392,8 +440,9 @@ func GetEmailTemplates(c *gin.Context) {
 func UpdateEmailTemplates(c *gin.Context) {
 	var req struct {
 		Templates map[string]struct {
-			Subject string `json:"subject" binding:"required"`
-			Content string `json:"content" binding:"required"`
+			Subject     string  `json:"subject" binding:"required"`
+			Content     string  `json:"content" binding:"required"`
+			TextContent *string `json:"text_content"` // Plain-text body; unchanged when omitted, derived from content when empty
 		} `json:"templates" binding:"required"`
 	}
 
@@ -404,15 +453,38 @@ func UpdateEmailTemplates(c *gin.Context) {
 
 	db := database.GetDB()
 
-	// Save each template
+	branding, err := models.GetBrandingSettingsWithBaseURL(db, getBaseURL(c, db))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get branding settings"})
+		return
+	}
+
+	// Validate every template before saving any
 	for templateType, template := range req.Templates {
 		if templateType != constants.TemplateTypeEvent &&
-			templateType != constants.TemplateTypeWelcome {
+			templateType != constants.TemplateTypeWelcome &&
+			templateType != constants.TemplateTypeRelease &&
+			templateType != constants.TemplateTypeDigest {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template type: " + templateType})
 			return
 		}
 
+		candidate := &models.EmailTemplate{Type: templateType, Subject: template.Subject, Content: template.Content}
+		if template.TextContent != nil {
+			candidate.TextContent = *template.TextContent
+		}
+		if err := emailtemplates.ValidateEmailTemplate(candidate, branding); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + templateType + " template: " + err.Error()})
+			return
+		}
+	}
+
+	// Save each template
+	for templateType, template := range req.Templates {
 		err := models.SaveEmailTemplate(db, templateType, template.Subject, template.Content)
+		if err == nil && template.TextContent != nil {
+			err = models.SaveEmailTemplateText(db, templateType, *template.TextContent)
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save " + templateType + " template"})
 			return
@@ -422,6 +494,90 @@ func UpdateEmailTemplates(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Email templates updated successfully"})
 }
 
+// PreviewEmailTemplate renders a template with a sample event and subscriber (admin only).
+// Subject and content default to the saved template with template_id, or the default template of the type.
+func PreviewEmailTemplate(c *gin.Context) {
+	var req struct {
+		Type        string `json:"type"`
+		TemplateID  *uint  `json:"template_id"`
+		Subject     string `json:"subject"`
+		Content     string `json:"content"`
+		TextContent string `json:"text_content"`
+		Locale      string `json:"locale"` // Renders the template's translation and formatting in this language
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	if req.Locale != "" && models.NormalizeLocale(req.Locale) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported locale: " + req.Locale})
+		return
+	}
+
+	db := database.GetDB()
+
+	var template *models.EmailTemplate
+	if req.TemplateID != nil {
+		saved, err := models.GetEmailTemplateByID(db, *req.TemplateID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Email template not found"})
+			return
+		}
+		template = saved
+		req.Type = saved.Type
+	} else {
+		defaultTemplate := constants.GetTemplateByType(req.Type)
+		if defaultTemplate == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template type: " + req.Type})
+			return
+		}
+		template = &models.EmailTemplate{Type: req.Type, Subject: defaultTemplate.Subject, Content: defaultTemplate.Content}
+		if saved, err := models.GetEmailTemplate(db, req.Type); err == nil {
+			template = saved
+		}
+	}
+
+	branding, err := models.GetBrandingSettingsWithBaseURL(db, getBaseURL(c, db))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get branding settings"})
+		return
+	}
+	if req.Locale != "" {
+		template, branding = emailtemplates.Localize(template, branding, req.Locale)
+	}
+
+	if req.Subject != "" {
+		template.Subject = req.Subject
+	}
+	if req.Content != "" {
+		template.Content = req.Content
+	}
+	if req.TextContent != "" {
+		template.TextContent = req.TextContent
+	}
+
+	sample := emailtemplates.SampleContext(req.Type, branding)
+	subject, content, err := emailtemplates.RenderEmailTemplate(template, sample)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template: " + err.Error()})
+		return
+	}
+	text, err := emailtemplates.RenderEmailText(template, sample)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template: " + err.Error()})
+		return
+	}
+	if text == "" {
+		text = emailtemplates.HTMLToText(content)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"subject": subject,
+		"content": content,
+		"text":    text,
+	})
+}
+
 // getDefaultEventTemplate returns the default event template
 func getDefaultEventTemplate() string {
 	return constants.TemplateEvent
@@ -459,26 +615,16 @@ func GetNewsletterAutomationSettings(c *gin.Context) {
 		return
 	}
 
-	// Parse the JSON trigger statuses
-	var triggerStatuses []string
-	if err := json.Unmarshal([]byte(settings.TriggerStatuses), &triggerStatuses); err != nil {
-		triggerStatuses = []string{} // Default to empty array if parsing fails
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"id":               settings.ID,
-		"enabled":          settings.Enabled,
-		"trigger_statuses": triggerStatuses,
-		"created_at":       settings.CreatedAt,
-		"updated_at":       settings.UpdatedAt,
-	})
+	c.JSON(http.StatusOK, automationSettingsResponse(db, settings))
 }
 
-// UpdateNewsletterAutomationSettings updates the automation settings (admin only)
+// UpdateNewsletterAutomationSettings updates the automation settings (admin only).
+// Trigger statuses may be given as status IDs or display names.
 func UpdateNewsletterAutomationSettings(c *gin.Context) {
 	var req struct {
-		Enabled         *bool    `json:"enabled"`
-		TriggerStatuses []string `json:"trigger_statuses"`
+		Enabled          *bool              `json:"enabled"`
+		TriggerStatuses  []models.StatusRef `json:"trigger_statuses"`
+		TriggerStatusIDs []uint             `json:"trigger_status_ids"` // Takes precedence over trigger_statuses
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -501,40 +647,97 @@ func UpdateNewsletterAutomationSettings(c *gin.Context) {
 		enabled = *req.Enabled
 	}
 
-	// Update trigger statuses if provided
-	triggerStatuses := "[]" // Default to empty array
-	if req.TriggerStatuses != nil {
-		statusesJSON, err := json.Marshal(req.TriggerStatuses)
+	refs := req.TriggerStatuses
+	if req.TriggerStatusIDs != nil {
+		refs = make([]models.StatusRef, len(req.TriggerStatusIDs))
+		for i, id := range req.TriggerStatusIDs {
+			refs[i] = models.StatusRef{ID: id}
+		}
+	}
+
+	// Resolve trigger statuses to status definition IDs
+	triggerStatusIDs := []uint{}
+	for _, ref := range refs {
+		statusDef, err := models.ResolveStatusRef(db, ref, false)
+		if err == models.ErrStatusNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown trigger status", "status": ref})
+			return
+		}
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trigger statuses format"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve trigger statuses"})
 			return
 		}
-		triggerStatuses = string(statusesJSON)
+		triggerStatusIDs = append(triggerStatusIDs, statusDef.ID)
 	}
 
 	// If automation is disabled, clear trigger statuses
 	if !enabled {
-		triggerStatuses = "[]"
+		triggerStatusIDs = []uint{}
 	}
 
 	// Update settings
-	updatedSettings, err := models.UpdateAutomationSettings(db, enabled, triggerStatuses)
+	updatedSettings, err := models.UpdateAutomationSettings(db, enabled, models.EncodeTriggerStatusIDs(triggerStatusIDs))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update automation settings"})
 		return
 	}
 
-	// Parse the JSON trigger statuses for response
-	var parsedTriggerStatuses []string
-	if err := json.Unmarshal([]byte(updatedSettings.TriggerStatuses), &parsedTriggerStatuses); err != nil {
-		parsedTriggerStatuses = []string{}
+	c.JSON(http.StatusOK, automationSettingsResponse(db, updatedSettings))
+}
+
+// automationSettingsResponse returns trigger statuses both as IDs and, for backward compatibility, as names
+func automationSettingsResponse(db *gorm.DB, settings *models.NewsletterAutomationSettings) gin.H {
+	triggerStatusIDs := settings.GetTriggerStatusIDs()
+
+	triggerStatuses := []string{}
+	if len(triggerStatusIDs) > 0 {
+		var statusDefs []models.EventStatusDefinition
+		db.Where("id IN ?", triggerStatusIDs).Find(&statusDefs)
+		namesByID := make(map[uint]string)
+		for _, statusDef := range statusDefs {
+			namesByID[statusDef.ID] = statusDef.DisplayName
+		}
+		for _, id := range triggerStatusIDs {
+			if name, ok := namesByID[id]; ok {
+				triggerStatuses = append(triggerStatuses, name)
+			}
+		}
+	}
+
+	return gin.H{
+		"id":                 settings.ID,
+		"enabled":            settings.Enabled,
+		"trigger_statuses":   triggerStatuses,
+		"trigger_status_ids": triggerStatusIDs,
+		"created_at":         settings.CreatedAt,
+		"updated_at":         settings.UpdatedAt,
+	}
+}
+
+// GetDigestPreview renders the weekly or monthly digest of the period in progress (admin only)
+func GetDigestPreview(c *gin.Context) {
+	frequency := c.DefaultQuery("frequency", models.DigestFrequencyWeekly)
+	if frequency != models.DigestFrequencyWeekly && frequency != models.DigestFrequencyMonthly {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "frequency must be weekly or monthly"})
+		return
+	}
+
+	db := database.GetDB()
+
+	subject, content, items, err := services.NewDigestService(db).PreviewDigest(frequency, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate digest preview"})
+		return
 	}
 
+	var subscriberCount int64
+	db.Model(&models.NewsletterSubscriber{}).Where("is_active = ? AND frequency = ?", true, frequency).Count(&subscriberCount)
+
 	c.JSON(http.StatusOK, gin.H{
-		"id":               updatedSettings.ID,
-		"enabled":          updatedSettings.Enabled,
-		"trigger_statuses": parsedTriggerStatuses,
-		"created_at":       updatedSettings.CreatedAt,
-		"updated_at":       updatedSettings.UpdatedAt,
+		"frequency":        frequency,
+		"subject":          subject,
+		"content":          content,
+		"item_count":       len(items),
+		"subscriber_count": subscriberCount,
 	})
 }
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"shipshipship/database"
+	emailtemplates "shipshipship/email"
+	"shipshipship/models"
+	"shipshipship/services"
+	"shipshipship/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxSubscriberImportSize bounds the size of an uploaded subscriber file
+const maxSubscriberImportSize = 5 << 20
+
+// readSubscriberImportFile returns the CSV sent as the "file" field of a form, or as the request body
+func readSubscriberImportFile(c *gin.Context) ([]byte, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSubscriberImportSize)
+
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, _, err := c.Request.FormFile("file")
+		if err != nil {
+			return nil, fmt.Errorf("no CSV file provided")
+		}
+		defer file.Close()
+		return io.ReadAll(file)
+	}
+	return io.ReadAll(c.Request.Body)
+}
+
+// ImportNewsletterSubscribers adds subscribers from a CSV file with an email column and optional
+// language, frequency, subscribed_at, consent_at and source columns (admin only).
+// Query parameters:
+//   - dry_run=true: only report what would be imported
+//   - require_confirmation=true: add subscribers as pending and email them a confirmation link
+func ImportNewsletterSubscribers(c *gin.Context) {
+	dryRun := c.Query("dry_run") == "true"
+	requireConfirmation := c.Query("require_confirmation") == "true"
+
+	data, err := readSubscriberImportFile(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read subscriber file", "details": err.Error()})
+		return
+	}
+
+	db := database.GetDB()
+
+	// Pending subscribers can only confirm through the email, so it must be deliverable
+	var mailSettings *models.MailSettings
+	if requireConfirmation {
+		if !utils.HasSigningSecret() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "JWT_SECRET must be set to send confirmation links"})
+			return
+		}
+		mailSettings, err = models.GetOrCreateMailSettings(db)
+		if err == nil {
+			err = mailSettings.Validate()
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Mail settings must be configured to send confirmation emails", "details": err.Error()})
+			return
+		}
+	}
+
+	plan, err := models.PlanSubscriberImport(db, bytes.NewReader(data), requireConfirmation)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscriber file", "details": err.Error()})
+		return
+	}
+
+	if dryRun {
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"dry_run": true,
+			"plan":    plan,
+		})
+		return
+	}
+
+	subscribers, err := models.ApplySubscriberImport(db, plan)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import subscribers", "details": err.Error()})
+		return
+	}
+
+	if requireConfirmation && len(subscribers) > 0 {
+		baseURL := getBaseURL(c, db)
+		go sendConfirmationEmails(db, mailSettings, baseURL, subscribers)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":              true,
+		"created":              len(subscribers),
+		"skipped":              plan.Skipped,
+		"invalid":              plan.Invalid,
+		"require_confirmation": requireConfirmation,
+		"rows":                 plan.Rows,
+	})
+}
+
+// sendConfirmationEmails asks imported subscribers to confirm, over one session
+func sendConfirmationEmails(db *gorm.DB, mailSettings *models.MailSettings, baseURL string, subscribers []models.NewsletterSubscriber) {
+	branding, err := models.GetBrandingSettingsWithBaseURL(db, baseURL)
+	if err != nil {
+		log.Printf("Failed to send confirmation emails: %v", err)
+		return
+	}
+	if branding.ProjectName == "" {
+		branding.ProjectName = "ShipShipShip"
+	}
+	fromName := mailSettings.FromName
+	if fromName == "" {
+		fromName = branding.ProjectName
+	}
+
+	emailService := services.NewEmailServiceWithSettings(mailSettings)
+	defer emailService.Close()
+
+	sent := 0
+	for i := range subscribers {
+		message, err := emailtemplates.NewConfirmationMessage(branding, &subscribers[i], fromName)
+		if err == nil {
+			err = emailService.SendMessage(message)
+		}
+		if err != nil {
+			log.Printf("Failed to send confirmation email to %s: %v", subscribers[i].Email, err)
+			continue
+		}
+		sent++
+	}
+	log.Printf("Sent %d of %d subscription confirmation emails", sent, len(subscribers))
+}
+
+// ExportNewsletterSubscribers downloads the subscribers as CSV, or JSON with format=json (admin only)
+func ExportNewsletterSubscribers(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+		return
+	}
+
+	db := database.GetDB()
+
+	rows, err := models.ExportSubscribers(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export subscribers"})
+		return
+	}
+
+	filename := fmt.Sprintf("subscribers-%s.%s", time.Now().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if format == "json" {
+		c.JSON(http.StatusOK, gin.H{
+			"subscribers": rows,
+			"total":       len(rows),
+		})
+		return
+	}
+
+	var buffer bytes.Buffer
+	if err := models.WriteSubscribersCSV(&buffer, rows); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export subscribers"})
+		return
+	}
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buffer.Bytes())
+}
+
+var confirmationPageTemplate = template.Must(template.New("confirmation").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Confirm your subscription - {{.ProjectName}}</title>
+<style>
+body { font-family: Arial, sans-serif; color: #333; max-width: 560px; margin: 40px auto; padding: 0 20px; line-height: 1.6; }
+h1 { font-size: 24px; }
+.notice { padding: 10px 14px; border-radius: 6px; margin: 16px 0; }
+.success { background: #dcfce7; color: #166534; }
+.error { background: #fee2e2; color: #991b1b; }
+button { background: #3b82f6; color: white; border: 0; padding: 12px 24px; border-radius: 6px; font-weight: bold; font-size: 15px; cursor: pointer; }
+</style>
+</head>
+<body>
+<h1>Confirm your subscription</h1>
+{{if .Error}}<p class="notice error">{{.Error}}</p>
+{{else if .Confirmed}}<p class="notice success">Thanks! <strong>{{.Email}}</strong> will now receive the {{.ProjectName}} newsletter.</p>
+{{else}}
+<p>Confirm that <strong>{{.Email}}</strong> wants to receive the {{.ProjectName}} newsletter.</p>
+<form method="post">
+<input type="hidden" name="email" value="{{.Email}}">
+<input type="hidden" name="token" value="{{.Token}}">
+<p><button type="submit">Confirm subscription</button></p>
+</form>
+{{end}}
+</body>
+</html>`))
+
+type confirmationPageData struct {
+	ProjectName string
+	Email       string
+	Token       string
+	Confirmed   bool
+	Error       string
+}
+
+func renderConfirmationPage(c *gin.Context, db *gorm.DB, status int, data confirmationPageData) {
+	data.ProjectName = "our newsletter"
+	if settings, err := models.GetOrCreateSettings(db); err == nil && settings.Title != "" {
+		data.ProjectName = settings.Title
+	}
+
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := confirmationPageTemplate.Execute(c.Writer, data); err != nil {
+		c.String(http.StatusInternalServerError, "Failed to render confirmation page")
+	}
+}
+
+// loadSubscriberForConfirmation checks the signed link and loads the subscriber
+func loadSubscriberForConfirmation(db *gorm.DB, email, token string) (*models.NewsletterSubscriber, int, string) {
+	if email == "" || token == "" || !models.VerifyConfirmationToken(email, token) {
+		return nil, http.StatusForbidden, "Invalid or expired confirmation link"
+	}
+
+	subscriber, err := models.FindSubscriberByEmail(db, email)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, http.StatusNotFound, "This email is not subscribed to the newsletter"
+		}
+		return nil, http.StatusInternalServerError, "Failed to load subscriber"
+	}
+	return subscriber, http.StatusOK, ""
+}
+
+// ServeConfirmationPage renders the page imported subscribers reach through the link in the confirmation email.
+// Confirming takes a form submission, so link scanners opening the page don't confirm on the subscriber's behalf.
+func ServeConfirmationPage(c *gin.Context) {
+	db := database.GetDB()
+
+	token := c.Query("token")
+	subscriber, status, message := loadSubscriberForConfirmation(db, c.Query("email"), token)
+	if subscriber == nil {
+		renderConfirmationPage(c, db, status, confirmationPageData{Error: message})
+		return
+	}
+
+	renderConfirmationPage(c, db, http.StatusOK, confirmationPageData{
+		Email:     subscriber.Email,
+		Token:     token,
+		Confirmed: subscriber.IsActive,
+	})
+}
+
+// ConfirmSubscriptionPage handles the confirmation page form
+func ConfirmSubscriptionPage(c *gin.Context) {
+	db := database.GetDB()
+
+	token := c.PostForm("token")
+	subscriber, status, message := loadSubscriberForConfirmation(db, c.PostForm("email"), token)
+	if subscriber == nil {
+		renderConfirmationPage(c, db, status, confirmationPageData{Error: message})
+		return
+	}
+
+	if !subscriber.IsActive {
+		if err := models.ConfirmSubscriber(db, subscriber); err != nil {
+			renderConfirmationPage(c, db, http.StatusInternalServerError, confirmationPageData{Error: "Failed to confirm your subscription"})
+			return
+		}
+	}
+
+	renderConfirmationPage(c, db, http.StatusOK, confirmationPageData{
+		Email:     subscriber.Email,
+		Confirmed: true,
+	})
+}
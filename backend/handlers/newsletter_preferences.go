@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+	"strconv"
+
+	"shipshipship/database"
+	"shipshipship/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// loadSubscriberForPreferences checks the signed link and loads the subscriber with their topics
+func loadSubscriberForPreferences(db *gorm.DB, email, token string) (*models.NewsletterSubscriber, int, string) {
+	if email == "" || token == "" || !models.VerifyPreferencesToken(email, token) {
+		return nil, http.StatusForbidden, "Invalid or expired preferences link"
+	}
+
+	var subscriber models.NewsletterSubscriber
+	if err := db.Preload("TopicTags").Preload("TopicGroups").Where("email = ?", email).First(&subscriber).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, http.StatusNotFound, "This email is not subscribed to the newsletter"
+		}
+		return nil, http.StatusInternalServerError, "Failed to load subscriber"
+	}
+	return &subscriber, http.StatusOK, ""
+}
+
+// availableTopics returns the tag groups with their tags, and the ungrouped tags
+func availableTopics(db *gorm.DB) ([]models.TagGroup, []models.Tag, error) {
+	var groups []models.TagGroup
+	if err := db.Preload("Tags", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("name ASC")
+	}).Order("`order` ASC, name ASC").Find(&groups).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var ungrouped []models.Tag
+	if err := db.Where("group_id IS NULL").Order("name ASC").Find(&ungrouped).Error; err != nil {
+		return nil, nil, err
+	}
+	return groups, ungrouped, nil
+}
+
+func subscriberTopicIDs(subscriber *models.NewsletterSubscriber) ([]uint, []uint) {
+	tagIDs := []uint{}
+	for _, tag := range subscriber.TopicTags {
+		tagIDs = append(tagIDs, tag.ID)
+	}
+	groupIDs := []uint{}
+	for _, group := range subscriber.TopicGroups {
+		groupIDs = append(groupIDs, group.ID)
+	}
+	return tagIDs, groupIDs
+}
+
+// GetNewsletterPreferences returns a subscriber's topics and the topics they can choose from
+func GetNewsletterPreferences(c *gin.Context) {
+	db := database.GetDB()
+
+	subscriber, status, message := loadSubscriberForPreferences(db, c.Query("email"), c.Query("token"))
+	if subscriber == nil {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	groups, ungrouped, err := availableTopics(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load topics"})
+		return
+	}
+
+	tagIDs, groupIDs := subscriberTopicIDs(subscriber)
+	c.JSON(http.StatusOK, gin.H{
+		"email":         subscriber.Email,
+		"all_topics":    subscriber.AllTopics,
+		"frequency":     subscriber.Frequency,
+		"language":      subscriber.Language,
+		"languages":     models.SupportedDateLocales(),
+		"tag_ids":       tagIDs,
+		"tag_group_ids": groupIDs,
+		"tag_groups":    groups,
+		"tags":          ungrouped,
+	})
+}
+
+// UpdateNewsletterPreferences saves a subscriber's topics and frequency
+func UpdateNewsletterPreferences(c *gin.Context) {
+	var req models.UpdatePreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	if req.Frequency != "" && !models.IsValidDigestFrequency(req.Frequency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "frequency must be instant, weekly or monthly"})
+		return
+	}
+
+	language := ""
+	if req.Language != nil && *req.Language != "" {
+		if language = models.NormalizeLocale(*req.Language); language == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported language: " + *req.Language})
+			return
+		}
+	}
+
+	db := database.GetDB()
+
+	subscriber, status, message := loadSubscriberForPreferences(db, req.Email, req.Token)
+	if subscriber == nil {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	if err := models.SetSubscriberTopics(db, subscriber, req.SubscriberTopicsRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Frequency != "" {
+		if err := models.SetSubscriberFrequency(db, subscriber, req.Frequency); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update frequency"})
+			return
+		}
+	}
+
+	if req.Language != nil {
+		if err := models.SetSubscriberLanguage(db, subscriber, language); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update language"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Preferences updated successfully",
+		"all_topics": subscriber.AllTopics,
+		"frequency":  subscriber.Frequency,
+		"language":   subscriber.Language,
+	})
+}
+
+var preferencesPageTemplate = template.Must(template.New("preferences").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Newsletter preferences - {{.ProjectName}}</title>
+<style>
+body { font-family: Arial, sans-serif; color: #333; max-width: 560px; margin: 40px auto; padding: 0 20px; line-height: 1.6; }
+h1 { font-size: 24px; }
+fieldset { border: 1px solid #e5e7eb; border-radius: 8px; margin: 16px 0; padding: 12px 16px; }
+legend { font-weight: bold; padding: 0 6px; }
+label { display: block; margin: 4px 0; }
+.notice { padding: 10px 14px; border-radius: 6px; margin: 16px 0; }
+.success { background: #dcfce7; color: #166534; }
+.error { background: #fee2e2; color: #991b1b; }
+button { background: #3b82f6; color: white; border: 0; padding: 12px 24px; border-radius: 6px; font-weight: bold; font-size: 15px; cursor: pointer; }
+</style>
+</head>
+<body>
+<h1>Newsletter preferences</h1>
+{{if .Error}}<p class="notice error">{{.Error}}</p>{{end}}
+{{if .Saved}}<p class="notice success">Your preferences have been saved.</p>{{end}}
+{{if .Email}}
+<p>Choose what {{.ProjectName}} emails <strong>{{.Email}}</strong> about.</p>
+<form method="post">
+<input type="hidden" name="email" value="{{.Email}}">
+<input type="hidden" name="token" value="{{.Token}}">
+<fieldset>
+<legend>How often</legend>
+<label><input type="radio" name="frequency" value="instant"{{if eq .Frequency "instant"}} checked{{end}}> As soon as something ships</label>
+<label><input type="radio" name="frequency" value="weekly"{{if eq .Frequency "weekly"}} checked{{end}}> Weekly digest</label>
+<label><input type="radio" name="frequency" value="monthly"{{if eq .Frequency "monthly"}} checked{{end}}> Monthly digest</label>
+</fieldset>
+<fieldset>
+<legend>Language</legend>
+<select name="language">
+<option value=""{{if eq .Language ""}} selected{{end}}>Default</option>
+{{range .Languages}}<option value="{{.}}"{{if eq $.Language .}} selected{{end}}>{{.}}</option>
+{{end}}</select>
+</fieldset>
+<label><input type="radio" name="all_topics" value="true"{{if .AllTopics}} checked{{end}}> All topics</label>
+<label><input type="radio" name="all_topics" value="false"{{if not .AllTopics}} checked{{end}}> Only the topics selected below</label>
+{{range .Groups}}
+<fieldset>
+<legend>{{.Name}}</legend>
+<label><input type="checkbox" name="tag_group_ids" value="{{.ID}}"{{if index $.SelectedGroups .ID}} checked{{end}}> Everything in {{.Name}}</label>
+{{range .Tags}}<label><input type="checkbox" name="tag_ids" value="{{.ID}}"{{if index $.SelectedTags .ID}} checked{{end}}> {{.Name}}</label>
+{{end}}
+</fieldset>
+{{end}}
+{{if .Ungrouped}}
+<fieldset>
+<legend>Other topics</legend>
+{{range .Ungrouped}}<label><input type="checkbox" name="tag_ids" value="{{.ID}}"{{if index $.SelectedTags .ID}} checked{{end}}> {{.Name}}</label>
+{{end}}
+</fieldset>
+{{end}}
+<p><button type="submit">Save preferences</button></p>
+</form>
+<p><a href="{{.UnsubscribeURL}}">Unsubscribe from all emails</a></p>
+{{end}}
+</body>
+</html>`))
+
+type preferencesPageData struct {
+	ProjectName    string
+	Email          string
+	Token          string
+	AllTopics      bool
+	Frequency      string
+	Language       string
+	Languages      []string
+	Groups         []models.TagGroup
+	Ungrouped      []models.Tag
+	SelectedTags   map[uint]bool
+	SelectedGroups map[uint]bool
+	UnsubscribeURL string
+	Saved          bool
+	Error          string
+}
+
+func renderPreferencesPage(c *gin.Context, db *gorm.DB, status int, data preferencesPageData) {
+	data.ProjectName = "our newsletter"
+	if settings, err := models.GetOrCreateSettings(db); err == nil && settings.Title != "" {
+		data.ProjectName = settings.Title
+	}
+
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := preferencesPageTemplate.Execute(c.Writer, data); err != nil {
+		c.String(http.StatusInternalServerError, "Failed to render preferences page")
+	}
+}
+
+func preferencesPageDataFor(db *gorm.DB, subscriber *models.NewsletterSubscriber, token string) (preferencesPageData, error) {
+	groups, ungrouped, err := availableTopics(db)
+	if err != nil {
+		return preferencesPageData{}, err
+	}
+
+	data := preferencesPageData{
+		Email:          subscriber.Email,
+		Token:          token,
+		AllTopics:      subscriber.AllTopics,
+		Frequency:      subscriber.Frequency,
+		Language:       subscriber.Language,
+		Languages:      models.SupportedDateLocales(),
+		Groups:         groups,
+		Ungrouped:      ungrouped,
+		SelectedTags:   make(map[uint]bool),
+		SelectedGroups: make(map[uint]bool),
+		UnsubscribeURL: "/unsubscribe?email=" + template.URLQueryEscaper(subscriber.Email),
+	}
+	for _, tag := range subscriber.TopicTags {
+		data.SelectedTags[tag.ID] = true
+	}
+	for _, group := range subscriber.TopicGroups {
+		data.SelectedGroups[group.ID] = true
+	}
+	return data, nil
+}
+
+// ServePreferencesPage renders the page subscribers reach through the signed link in newsletters
+func ServePreferencesPage(c *gin.Context) {
+	db := database.GetDB()
+
+	token := c.Query("token")
+	subscriber, status, message := loadSubscriberForPreferences(db, c.Query("email"), token)
+	if subscriber == nil {
+		renderPreferencesPage(c, db, status, preferencesPageData{Error: message})
+		return
+	}
+
+	data, err := preferencesPageDataFor(db, subscriber, token)
+	if err != nil {
+		renderPreferencesPage(c, db, http.StatusInternalServerError, preferencesPageData{Error: "Failed to load topics"})
+		return
+	}
+	renderPreferencesPage(c, db, http.StatusOK, data)
+}
+
+// SavePreferencesPage handles the preferences page form
+func SavePreferencesPage(c *gin.Context) {
+	db := database.GetDB()
+
+	token := c.PostForm("token")
+	subscriber, status, message := loadSubscriberForPreferences(db, c.PostForm("email"), token)
+	if subscriber == nil {
+		renderPreferencesPage(c, db, status, preferencesPageData{Error: message})
+		return
+	}
+
+	allTopics := c.PostForm("all_topics") != "false"
+	req := models.SubscriberTopicsRequest{AllTopics: &allTopics}
+	if !allTopics {
+		req.TagIDs = parseFormIDs(c.PostFormArray("tag_ids"))
+		req.TagGroupIDs = parseFormIDs(c.PostFormArray("tag_group_ids"))
+	}
+
+	saveErr := models.SetSubscriberTopics(db, subscriber, req)
+	if frequency := c.PostForm("frequency"); saveErr == nil && models.IsValidDigestFrequency(frequency) {
+		saveErr = models.SetSubscriberFrequency(db, subscriber, frequency)
+	}
+	if language, ok := c.GetPostForm("language"); ok && saveErr == nil && (language == "" || models.NormalizeLocale(language) == language) {
+		saveErr = models.SetSubscriberLanguage(db, subscriber, language)
+	}
+
+	// Reload to show what was actually saved
+	subscriber, _, _ = loadSubscriberForPreferences(db, subscriber.Email, token)
+	data, err := preferencesPageDataFor(db, subscriber, token)
+	if err != nil {
+		renderPreferencesPage(c, db, http.StatusInternalServerError, preferencesPageData{Error: "Failed to load topics"})
+		return
+	}
+	if saveErr != nil {
+		data.Error = saveErr.Error()
+		renderPreferencesPage(c, db, http.StatusBadRequest, data)
+		return
+	}
+	data.Saved = true
+	renderPreferencesPage(c, db, http.StatusOK, data)
+}
+
+func parseFormIDs(values []string) []uint {
+	ids := []uint{}
+	for _, value := range values {
+		if id, err := strconv.ParseUint(value, 10, 32); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"shipshipship/database"
+	"shipshipship/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LookupPersonalData returns everything stored about ?email=, ?ip= and/or ?visitor_id= (admin only)
+func LookupPersonalData(c *gin.Context) {
+	query := models.PersonalDataQuery{
+		Email:     c.Query("email"),
+		IPAddress: c.Query("ip"),
+		VisitorID: c.Query("visitor_id"),
+	}
+	if query.IsEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email, ip or visitor_id parameter is required"})
+		return
+	}
+
+	data, err := models.FindPersonalData(database.GetDB(), query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up personal data"})
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// ErasePersonalData permanently deletes everything stored about an email, IP address and/or visitor (admin only)
+func ErasePersonalData(c *gin.Context) {
+	var req models.PersonalDataQuery
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.IsEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email, ip_address or visitor_id is required"})
+		return
+	}
+
+	result, err := models.ErasePersonalData(database.GetDB(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to erase personal data", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"erased":  result,
+	})
+}
+
+// AnonymizeIPAddresses applies the retention period to IP addresses and email delivery records now
+// rather than at the next cleanup run (admin only)
+func AnonymizeIPAddresses(c *gin.Context) {
+	db := database.GetDB()
+
+	settings, err := models.GetOrCreateSettings(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get settings"})
+		return
+	}
+	if settings.IPRetentionDays <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set ip_retention_days in the settings to anonymize IP addresses"})
+		return
+	}
+
+	result, err := models.ApplyRetention(db, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to anonymize IP addresses"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":            true,
+		"anonymized":         result.AnonymizedIPs,
+		"deleted_deliveries": result.DeletedDeliveries,
+		"ip_retention_days":  settings.IPRetentionDays,
+	})
+}
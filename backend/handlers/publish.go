@@ -2,10 +2,8 @@ package handlers
 
 import (
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
 	"shipshipship/constants"
@@ -15,6 +13,7 @@ import (
 	"shipshipship/services"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // GetEventPublishStatus gets the publication status of an event
@@ -109,47 +108,27 @@ func GetEventNewsletterPreview(c *gin.Context) {
 		return
 	}
 
-	// Use generic event template for all statuses
-	templateType := "event"
-
 	db := database.GetDB()
 
 	// Get the event with tags and status definition preloaded
 	var event models.Event
-	if err := db.Preload("Tags").First(&event, eventID).Error; err != nil {
+	if err := db.Preload("Tags").Preload("StatusDefinition").First(&event, eventID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 		return
 	}
 
-	// Get status definition for color
-	var statusDef models.EventStatusDefinition
-	if err := db.Where("display_name = ?", event.Status).First(&statusDef).Error; err != nil {
+	// Status definition is used for color
+	if event.StatusDefinition == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Status definition not found"})
 		return
 	}
+	statusDef := event.StatusDefinition
 
-	// Get the email template or use defaults if not found
-	template, err := models.GetEmailTemplate(db, templateType)
-	if err != nil {
-		// If template doesn't exist, use default content directly
-		log.Printf("Template not found in DB, using defaults for type: %s", templateType)
-		defaultTemplate := constants.GetTemplateByType(templateType)
-		if defaultTemplate == nil {
-			log.Printf("ERROR: No default template found for type: %s", templateType)
-			// List available templates
-			allTemplates := constants.GetDefaultTemplates()
-			log.Printf("Available templates: %v", allTemplates)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template type"})
-			return
-		}
-
-		log.Printf("Using default template: %s", defaultTemplate.Type)
-		// Create a temporary template object with defaults
-		template = &models.EmailTemplate{
-			Type:    defaultTemplate.Type,
-			Subject: defaultTemplate.Subject,
-			Content: defaultTemplate.Content,
-		}
+	// Use the template picked by the admin, the one mapped to the event's status, or the defaults
+	template, status, message := eventNewsletterTemplate(db, c.Query("template_id"), statusDef.ID)
+	if template == nil {
+		c.JSON(status, gin.H{"error": message})
+		return
 	}
 
 	// Get branding settings for project info
@@ -162,18 +141,101 @@ func GetEventNewsletterPreview(c *gin.Context) {
 	}
 
 	// Generate the preview with variable replacements
-	subject, content, err := email.GenerateEmailContent(db, template, &event, &statusDef, branding)
+	subject, content, err := email.GenerateEmailContent(db, template, &event, statusDef, branding)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate email content"})
 		return
 	}
 
+	// Count who would receive it, given subscriber topics
+	recipients, err := models.GetNewsletterSubscribersForEvent(db, &event)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get newsletter subscribers"})
+		return
+	}
+
+	// Render it in the languages of the recipients too
+	localized, err := localizedPreviews(template, branding, recipients, func(template *models.EmailTemplate, branding *models.BrandingSettings) (string, string, error) {
+		return email.GenerateEmailContent(db, template, &event, statusDef, branding)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate email content"})
+		return
+	}
+	totalSubscribers, err := models.GetActiveSubscriberCount(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get newsletter subscribers"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"subject": subject,
-		"content": content,
+		"subject":           subject,
+		"content":           content,
+		"template_id":       template.ID,
+		"template":          template.DisplayName(),
+		"localized":         localized,
+		"recipient_count":   len(recipients),
+		"total_subscribers": totalSubscribers,
 	})
 }
 
+// localizedPreviews renders a newsletter in each language of its recipients other than the project's
+func localizedPreviews(template *models.EmailTemplate, branding *models.BrandingSettings, recipients []models.NewsletterSubscriber,
+	render func(*models.EmailTemplate, *models.BrandingSettings) (string, string, error)) (map[string]models.NewsletterContent, error) {
+	projectLocale := models.NormalizeLocale(branding.Locale)
+	if projectLocale == "" {
+		projectLocale = models.DefaultLocale
+	}
+
+	localized := map[string]models.NewsletterContent{}
+	for _, locale := range models.SubscriberLocales(recipients, branding.Locale) {
+		if locale == projectLocale {
+			continue
+		}
+		subject, content, err := render(email.Localize(template, branding, locale))
+		if err != nil {
+			return nil, err
+		}
+		localized[locale] = models.NewsletterContent{Subject: subject, Content: content}
+	}
+	return localized, nil
+}
+
+// eventNewsletterTemplate returns the event template with the given ID, or the one mapped to the status.
+// Without stored templates it falls back to the built-in default.
+func eventNewsletterTemplate(db *gorm.DB, templateID string, statusID uint) (*models.EmailTemplate, int, string) {
+	if templateID != "" {
+		id, err := strconv.ParseUint(templateID, 10, 32)
+		if err != nil {
+			return nil, http.StatusBadRequest, "Invalid template ID"
+		}
+		template, err := models.GetEmailTemplateByID(db, uint(id))
+		if err != nil || template.Type != constants.TemplateTypeEvent {
+			return nil, http.StatusNotFound, "Event email template not found"
+		}
+		return template, http.StatusOK, ""
+	}
+
+	template, err := models.GetEmailTemplateForStatus(db, statusID)
+	if err == nil {
+		return template, http.StatusOK, ""
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, http.StatusInternalServerError, "Failed to get email template"
+	}
+
+	defaultTemplate := constants.GetTemplateByType(constants.TemplateTypeEvent)
+	if defaultTemplate == nil {
+		return nil, http.StatusInternalServerError, "No event email template available"
+	}
+	return &models.EmailTemplate{
+		Type:      defaultTemplate.Type,
+		IsDefault: true,
+		Subject:   defaultTemplate.Subject,
+		Content:   defaultTemplate.Content,
+	}, http.StatusOK, ""
+}
+
 // SendEventNewsletter sends a newsletter for an event
 func SendEventNewsletter(c *gin.Context) {
 	eventIDStr := c.Param("id")
@@ -188,23 +250,36 @@ func SendEventNewsletter(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
 		return
 	}
-
-	// Use generic event template
-	req.Template = "event"
+	if err := models.ValidateLocalizedContent(req.Localized); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	db := database.GetDB()
 
 	// Get the event with tags and publication preloaded
 	var event models.Event
-	if err := db.Preload("Publication").Preload("Tags").First(&event, eventID).Error; err != nil {
+	if err := db.Preload("Publication").Preload("Tags").Preload("StatusDefinition").First(&event, eventID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 		return
 	}
 
+	// Record which template the content came from
+	templateID := ""
+	if req.TemplateID != nil {
+		templateID = strconv.FormatUint(uint64(*req.TemplateID), 10)
+	}
+	template, status, message := eventNewsletterTemplate(db, templateID, event.StatusID)
+	if template == nil {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+	req.Template = template.DisplayName()
+
 	// Note: We allow resending emails, but track the history
 
-	// Get newsletter subscribers
-	subscribers, err := models.GetActiveNewsletterSubscribers(db)
+	// Get the subscribers whose topics match the event's tags
+	subscribers, err := models.GetNewsletterSubscribersForEvent(db, &event)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get newsletter subscribers"})
 		return
@@ -226,17 +301,15 @@ func SendEventNewsletter(c *gin.Context) {
 
 	// Send emails to all subscribers
 	emailService := services.NewEmailService()
+	defer emailService.Close()
 	sentCount := 0
 
 	for _, subscriber := range subscribers {
-		// Replace unsubscribe URL in content (use BaseURL, not ProjectURL)
-		unsubscribeURL := fmt.Sprintf("%s/unsubscribe?email=%s", branding.BaseURL, subscriber.Email)
-		if branding.BaseURL == "" {
-			unsubscribeURL = fmt.Sprintf("/unsubscribe?email=%s", subscriber.Email)
-		}
-		personalizedContent := strings.ReplaceAll(req.Content, "{{unsubscribe_url}}", unsubscribeURL)
+		// Use the version in the subscriber's language, and replace unsubscribe and preferences URLs in content (use BaseURL, not ProjectURL)
+		subject, content := models.LocalizedNewsletterContent(req.Subject, req.Content, req.Localized, subscriber.Locale(branding.Locale))
+		personalizedContent := email.PersonalizeContent(content, branding.BaseURL, subscriber.Email)
 
-		err := emailService.SendEmail(subscriber.Email, req.Subject, personalizedContent)
+		err := emailService.SendEmail(subscriber.Email, subject, personalizedContent)
 		if err != nil {
 			// Log the error but continue sending to other subscribers
 			fmt.Printf("Failed to send email to %s: %v\n", subscriber.Email, err)
@@ -1,15 +1,60 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 
 	"shipshipship/database"
+	"shipshipship/middleware"
 	"shipshipship/models"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// readingVisitor identifies the visitor whose own reactions are shown by their visitor ID. Visitors
+// without one have no reactions of their own: those made by IP before visitor IDs belong to nobody
+// until a visitor reacts again from that address.
+func readingVisitor(c *gin.Context, db *gorm.DB) models.VisitorIdentity {
+	if id, ok := middleware.VisitorID(c); ok {
+		hash, err := models.VisitorHash(db, id)
+		if err != nil {
+			log.Printf("Failed to hash visitor ID: %v", err)
+			return models.VisitorIdentity{}
+		}
+		return models.VisitorIdentity{Hash: hash}
+	}
+	return models.VisitorIdentity{}
+}
+
+// reactingVisitor identifies a visitor reacting or voting, issuing their visitor ID on first use
+func reactingVisitor(c *gin.Context, db *gorm.DB) (models.VisitorIdentity, error) {
+	id, _, err := middleware.EnsureVisitorID(c)
+	if err != nil {
+		return models.VisitorIdentity{}, err
+	}
+	hash, err := models.VisitorHash(db, id)
+	if err != nil {
+		return models.VisitorIdentity{}, err
+	}
+	visitor := models.VisitorIdentity{Hash: hash, IP: c.ClientIP()}
+	if visitor.IPHash, err = models.HashIPAddress(db, c.ClientIP()); err != nil {
+		log.Printf("Failed to hash IP address, the per-IP vote limit is skipped: %v", err)
+	}
+	return visitor, nil
+}
+
+// respondVisitorError responds to a request whose visitor could not be identified
+func respondVisitorError(c *gin.Context, err error) {
+	if errors.Is(err, middleware.ErrVisitorIDLimit) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many new visitors from your network. Please try again later."})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to identify visitor"})
+}
+
 // AddOrRemoveReaction handles adding or removing a reaction (toggle behavior)
 func AddOrRemoveReaction(c *gin.Context) {
 	id := c.Param("id")
@@ -34,9 +79,6 @@ func AddOrRemoveReaction(c *gin.Context) {
 		return
 	}
 
-	// Get client IP address
-	clientIP := c.ClientIP()
-
 	db := database.GetDB()
 
 	// Check if event exists
@@ -46,9 +88,15 @@ func AddOrRemoveReaction(c *gin.Context) {
 		return
 	}
 
-	// Check if this IP has already reacted with this type
+	visitor, err := reactingVisitor(c, db)
+	if err != nil {
+		respondVisitorError(c, err)
+		return
+	}
+
+	// Check if this visitor has already reacted with this type
 	var existingReaction models.EventReaction
-	err = db.Where("event_id = ? AND ip_address = ? AND reaction_type = ?", eventID, clientIP, req.ReactionType).
+	err = visitor.Match(db.Where("event_id = ? AND reaction_type = ?", eventID, req.ReactionType)).
 		First(&existingReaction).Error
 
 	if err == nil {
@@ -59,7 +107,7 @@ func AddOrRemoveReaction(c *gin.Context) {
 		}
 
 		// Get updated reaction summary
-		summary := getReactionSummary(db, uint(eventID), clientIP)
+		summary := getReactionSummary(db, uint(eventID), visitor)
 
 		c.JSON(http.StatusOK, gin.H{
 			"message":  "Reaction removed successfully",
@@ -70,20 +118,40 @@ func AddOrRemoveReaction(c *gin.Context) {
 		return
 	}
 
-	// Reaction doesn't exist, create it
-	reaction := models.EventReaction{
-		EventID:      uint(eventID),
-		ReactionType: req.ReactionType,
-		IPAddress:    clientIP,
-	}
-
-	if err := db.Create(&reaction).Error; err != nil {
+	// Reaction doesn't exist: take over the one made from this address before visitor IDs, if any
+	claimed, err := visitor.ClaimIPRecord(db, &models.EventReaction{}, "event_id = ? AND reaction_type = ?", eventID, req.ReactionType)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add reaction"})
 		return
 	}
 
+	if !claimed {
+		// Otherwise create it unless the visitor's network has reached its limit
+		limited, err := visitor.IPLimitReached(db.Model(&models.EventReaction{}).Where("event_id = ? AND reaction_type = ?", eventID, req.ReactionType))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add reaction"})
+			return
+		}
+		if limited {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many reactions from your network on this event"})
+			return
+		}
+
+		reaction := models.EventReaction{
+			EventID:      uint(eventID),
+			ReactionType: req.ReactionType,
+			VisitorHash:  visitor.Hash,
+			IPHash:       visitor.IPHash,
+		}
+
+		if err := db.Create(&reaction).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add reaction"})
+			return
+		}
+	}
+
 	// Get updated reaction summary
-	summary := getReactionSummary(db, uint(eventID), clientIP)
+	summary := getReactionSummary(db, uint(eventID), visitor)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":  "Reaction added successfully",
@@ -102,11 +170,11 @@ func GetEventReactions(c *gin.Context) {
 		return
 	}
 
-	// Get client IP address
-	clientIP := c.ClientIP()
-
 	db := database.GetDB()
 
+	// Identify the visitor
+	visitor := readingVisitor(c, db)
+
 	// Check if event exists
 	var event models.Event
 	if err := db.First(&event, eventID).Error; err != nil {
@@ -114,12 +182,12 @@ func GetEventReactions(c *gin.Context) {
 		return
 	}
 
-	summary := getReactionSummary(db, uint(eventID), clientIP)
+	summary := getReactionSummary(db, uint(eventID), visitor)
 
 	c.JSON(http.StatusOK, summary)
 }
 
-// GetMyReactions returns the current user/IP's reactions for an event
+// GetMyReactions returns the current visitor's reactions for an event
 func GetMyReactions(c *gin.Context) {
 	id := c.Param("id")
 	eventID, err := strconv.ParseUint(id, 10, 32)
@@ -128,14 +196,14 @@ func GetMyReactions(c *gin.Context) {
 		return
 	}
 
-	// Get client IP address
-	clientIP := c.ClientIP()
-
 	db := database.GetDB()
 
+	// Identify the visitor
+	visitor := readingVisitor(c, db)
+
 	// Get user's reactions
 	var reactions []models.EventReaction
-	db.Where("event_id = ? AND ip_address = ?", eventID, clientIP).Find(&reactions)
+	visitor.Match(db.Where("event_id = ?", eventID)).Find(&reactions)
 
 	reactionTypes := make([]models.ReactionType, len(reactions))
 	for i, r := range reactions {
@@ -191,8 +259,8 @@ func MigrateVotesToReactions(c *gin.Context) {
 	for _, vote := range votes {
 		// Check if reaction already exists
 		var existingReaction models.EventReaction
-		err := db.Where("event_id = ? AND ip_address = ? AND reaction_type = ?",
-			vote.EventID, vote.IPAddress, models.ReactionThumbsUp).
+		voter := models.VisitorIdentity{Hash: vote.VisitorHash, IP: vote.IPAddress}
+		err := voter.Match(db.Where("event_id = ? AND reaction_type = ?", vote.EventID, models.ReactionThumbsUp)).
 			First(&existingReaction).Error
 
 		if err == nil {
@@ -204,7 +272,9 @@ func MigrateVotesToReactions(c *gin.Context) {
 		reaction := models.EventReaction{
 			EventID:      vote.EventID,
 			ReactionType: models.ReactionThumbsUp,
+			VisitorHash:  vote.VisitorHash,
 			IPAddress:    vote.IPAddress,
+			IPHash:       vote.IPHash,
 			CreatedAt:    vote.CreatedAt,
 			UpdatedAt:    vote.UpdatedAt,
 		}
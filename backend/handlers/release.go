@@ -0,0 +1,436 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"shipshipship/constants"
+	"shipshipship/database"
+	"shipshipship/email"
+	"shipshipship/models"
+	"shipshipship/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PublicRelease is a release as shown on the public site, with its public events grouped by status
+type PublicRelease struct {
+	ID          uint                       `json:"id"`
+	Version     string                     `json:"version"`
+	Name        string                     `json:"name"`
+	ReleaseDate string                     `json:"release_date"`
+	Notes       string                     `json:"notes"`
+	EventCount  int                        `json:"event_count"`
+	EventGroups []models.ReleaseEventGroup `json:"event_groups"`
+}
+
+// releasesQuery preloads the events of releases with their tags and statuses
+func releasesQuery(db *gorm.DB, publicOnly bool) *gorm.DB {
+	return db.Preload("Events", func(tx *gorm.DB) *gorm.DB {
+		if publicOnly {
+			tx = tx.Where("is_public = ?", true)
+		}
+		return tx.Order("date_at IS NULL, date_at DESC, id DESC")
+	}).Preload("Events.Tags").Preload("Events.StatusDefinition")
+}
+
+func toPublicRelease(release models.Release) PublicRelease {
+	for i := range release.Events {
+		release.Events[i].Content = SanitizeHTMLContent(release.Events[i].Content)
+	}
+	return PublicRelease{
+		ID:          release.ID,
+		Version:     release.Version,
+		Name:        release.Name,
+		ReleaseDate: release.ReleaseDate,
+		Notes:       SanitizeHTMLContent(release.Notes),
+		EventCount:  len(release.Events),
+		EventGroups: models.GroupReleaseEvents(release.Events),
+	}
+}
+
+// GetPublicReleases returns all releases, newest version first
+func GetPublicReleases(c *gin.Context) {
+	db := database.GetDB()
+
+	var releases []models.Release
+	if err := releasesQuery(db, true).Find(&releases).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch releases"})
+		return
+	}
+	models.SortReleasesByVersion(releases)
+
+	result := make([]PublicRelease, len(releases))
+	for i, release := range releases {
+		result[i] = toPublicRelease(release)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetPublicRelease returns a single release by version
+func GetPublicRelease(c *gin.Context) {
+	version, err := models.NormalizeReleaseVersion(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := database.GetDB()
+
+	var release models.Release
+	if err := releasesQuery(db, true).Where("version = ?", version).First(&release).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Release not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch release"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, toPublicRelease(release))
+}
+
+// GetReleases returns all releases with every linked event (admin only)
+func GetReleases(c *gin.Context) {
+	db := database.GetDB()
+
+	var releases []models.Release
+	if err := releasesQuery(db, false).Find(&releases).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch releases"})
+		return
+	}
+	models.SortReleasesByVersion(releases)
+
+	c.JSON(http.StatusOK, releases)
+}
+
+// GetRelease returns a single release by ID (admin only)
+func GetRelease(c *gin.Context) {
+	db := database.GetDB()
+
+	release, ok := findRelease(c, db, false)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, release)
+}
+
+// CreateRelease creates a release and links its events (admin only)
+func CreateRelease(c *gin.Context) {
+	var req models.CreateReleaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	version, err := models.NormalizeReleaseVersion(req.Version)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := models.ValidateReleaseDate(req.ReleaseDate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := database.GetDB()
+
+	if releaseVersionTaken(db, version, 0) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A release with this version already exists"})
+		return
+	}
+
+	release := models.Release{
+		Version:     version,
+		Name:        strings.TrimSpace(req.Name),
+		ReleaseDate: req.ReleaseDate,
+		Notes:       req.Notes,
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&release).Error; err != nil {
+			return err
+		}
+		return models.SetReleaseEvents(tx, &release, req.EventIDs)
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create release: " + err.Error()})
+		return
+	}
+
+	releasesQuery(db, false).First(&release, release.ID)
+	c.JSON(http.StatusCreated, release)
+}
+
+// UpdateRelease updates a release and optionally replaces its events (admin only)
+func UpdateRelease(c *gin.Context) {
+	var req models.UpdateReleaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := database.GetDB()
+
+	release, ok := findRelease(c, db, false)
+	if !ok {
+		return
+	}
+
+	if req.Version != nil {
+		version, err := models.NormalizeReleaseVersion(*req.Version)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if releaseVersionTaken(db, version, release.ID) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A release with this version already exists"})
+			return
+		}
+		release.Version = version
+	}
+	if req.Name != nil {
+		release.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.ReleaseDate != nil {
+		if err := models.ValidateReleaseDate(*req.ReleaseDate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		release.ReleaseDate = *req.ReleaseDate
+	}
+	if req.Notes != nil {
+		release.Notes = *req.Notes
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Events").Save(release).Error; err != nil {
+			return err
+		}
+		if req.EventIDs != nil {
+			return models.SetReleaseEvents(tx, release, *req.EventIDs)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update release: " + err.Error()})
+		return
+	}
+
+	var updated models.Release
+	releasesQuery(db, false).First(&updated, release.ID)
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteRelease deletes a release and unlinks its events (admin only)
+func DeleteRelease(c *gin.Context) {
+	db := database.GetDB()
+
+	release, ok := findRelease(c, db, false)
+	if !ok {
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(release).Association("Events").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(release).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete release"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Release deleted successfully"})
+}
+
+// GetReleaseNewsletterPreview generates a preview of the newsletter for a release (admin only)
+func GetReleaseNewsletterPreview(c *gin.Context) {
+	db := database.GetDB()
+
+	release, ok := findRelease(c, db, true)
+	if !ok {
+		return
+	}
+
+	branding, err := models.GetBrandingSettingsWithBaseURL(db, getBaseURL(c, db))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get branding settings"})
+		return
+	}
+
+	template := getReleaseEmailTemplate(db)
+	subject, content, err := email.GenerateReleaseEmailContent(template, release, models.GroupReleaseEvents(release.Events), branding)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render release template: " + err.Error()})
+		return
+	}
+
+	recipients, err := models.GetNewsletterSubscribersForTags(db, releaseTagIDs(release))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get newsletter subscribers"})
+		return
+	}
+
+	localized, err := localizedPreviews(template, branding, recipients, func(template *models.EmailTemplate, branding *models.BrandingSettings) (string, string, error) {
+		return email.GenerateReleaseEmailContent(template, release, models.GroupReleaseEvents(release.Events), branding)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render release template: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"subject":         subject,
+		"content":         content,
+		"localized":       localized,
+		"event_count":     len(release.Events),
+		"recipient_count": len(recipients),
+	})
+}
+
+// SendReleaseNewsletter sends one newsletter covering every public event of a release (admin only)
+func SendReleaseNewsletter(c *gin.Context) {
+	var req models.ReleaseNewsletterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+	if err := models.ValidateLocalizedContent(req.Localized); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := database.GetDB()
+
+	release, ok := findRelease(c, db, true)
+	if !ok {
+		return
+	}
+
+	if len(release.Events) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Release has no public events"})
+		return
+	}
+
+	subscribers, err := models.GetNewsletterSubscribersForTags(db, releaseTagIDs(release))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get newsletter subscribers"})
+		return
+	}
+
+	if len(subscribers) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No newsletter subscribers follow the topics of this release"})
+		return
+	}
+
+	branding, err := models.GetBrandingSettingsWithBaseURL(db, getBaseURL(c, db))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get branding settings"})
+		return
+	}
+
+	emailService := services.NewEmailService()
+	defer emailService.Close()
+	sentCount := 0
+
+	for _, subscriber := range subscribers {
+		subject, content := models.LocalizedNewsletterContent(req.Subject, req.Content, req.Localized, subscriber.Locale(branding.Locale))
+		personalizedContent := email.PersonalizeContent(content, branding.BaseURL, subscriber.Email)
+
+		if err := emailService.SendEmail(subscriber.Email, subject, personalizedContent); err != nil {
+			// Log the error but continue sending to other subscribers
+			fmt.Printf("Failed to send release email to %s: %v\n", subscriber.Email, err)
+			continue
+		}
+		sentCount++
+	}
+
+	// Record the send on the release and in the newsletter history
+	now := time.Now()
+	if err := db.Model(release).Updates(map[string]interface{}{
+		"newsletter_sent_at":    &now,
+		"newsletter_recipients": sentCount,
+	}).Error; err != nil {
+		log.Printf("Failed to record newsletter send for release %s: %v", release.Version, err)
+	}
+
+	history := &models.NewsletterHistory{
+		Subject:        req.Subject,
+		Content:        req.Content,
+		Status:         "sent",
+		RecipientCount: sentCount,
+		SentAt:         &now,
+	}
+	if err := db.Create(history).Error; err != nil {
+		log.Printf("Failed to save newsletter history for release %s: %v", release.Version, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":           "Release newsletter sent successfully",
+		"subscribers_sent":  sentCount,
+		"total_subscribers": len(subscribers),
+	})
+}
+
+// findRelease loads the release from the :id parameter, writing an error response when it can't
+func findRelease(c *gin.Context, db *gorm.DB, publicOnly bool) (*models.Release, bool) {
+	releaseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid release ID"})
+		return nil, false
+	}
+
+	var release models.Release
+	if err := releasesQuery(db, publicOnly).First(&release, releaseID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Release not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch release"})
+		}
+		return nil, false
+	}
+
+	return &release, true
+}
+
+// releaseTagIDs collects the tags of all events in a release
+func releaseTagIDs(release *models.Release) []uint {
+	tagIDs := []uint{}
+	for _, event := range release.Events {
+		for _, tag := range event.Tags {
+			tagIDs = append(tagIDs, tag.ID)
+		}
+	}
+	return tagIDs
+}
+
+func releaseVersionTaken(db *gorm.DB, version string, excludeID uint) bool {
+	var count int64
+	db.Model(&models.Release{}).Where("version = ? AND id != ?", version, excludeID).Count(&count)
+	return count > 0
+}
+
+// getReleaseEmailTemplate returns the stored release template or the default one
+func getReleaseEmailTemplate(db *gorm.DB) *models.EmailTemplate {
+	template, err := models.GetEmailTemplate(db, constants.TemplateTypeRelease)
+	if err == nil {
+		return template
+	}
+
+	defaultTemplate := constants.GetTemplateByType(constants.TemplateTypeRelease)
+	return &models.EmailTemplate{
+		Type:    defaultTemplate.Type,
+		Subject: defaultTemplate.Subject,
+		Content: defaultTemplate.Content,
+	}
+}
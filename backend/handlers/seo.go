@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"shipshipship/database"
+	"shipshipship/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Maximum length of the generated meta description
+const seoDescriptionMaxLength = 160
+
+var (
+	seoTitleRegex        = regexp.MustCompile(`(?is)<title[^>]*>.*?</title>`)
+	seoExistingMetaRegex = regexp.MustCompile(`(?is)<meta\s+[^>]*(name|property)=["'](description|og:[^"']*|twitter:[^"']*)["'][^>]*>\s*`)
+	seoCanonicalRegex    = regexp.MustCompile(`(?is)<link\s+[^>]*rel=["']canonical["'][^>]*>\s*`)
+	seoParagraphRegex    = regexp.MustCompile(`(?is)<p[^>]*>(.*?)</p>`)
+	seoTagRegex          = regexp.MustCompile(`(?s)<[^>]*>`)
+	seoWhitespaceRegex   = regexp.MustCompile(`\s+`)
+)
+
+// EventPageMetadata holds the SEO values rendered into an event page
+type EventPageMetadata struct {
+	Title        string
+	Description  string
+	ImageURL     string
+	CanonicalURL string
+	SiteName     string
+	JSONLD       string
+}
+
+// ServeEventPage serves the theme's index.html for /:slug with per-event metadata injected.
+// It returns false when the path doesn't belong to a public event, so the caller can fall back.
+func ServeEventPage(c *gin.Context, indexPath string) bool {
+	slug := strings.Trim(c.Request.URL.Path, "/")
+	if slug == "" || strings.Contains(slug, "/") || strings.Contains(slug, ".") {
+		return false
+	}
+
+	db := database.GetDB()
+
+	var event models.Event
+	if err := db.Preload("StatusDefinition").Where("slug = ? AND is_public = ? AND has_public_url = ?", slug, true, true).
+		First(&event).Error; err != nil {
+		return false
+	}
+
+	settings, err := models.GetOrCreateSettings(db)
+	if err != nil {
+		return false
+	}
+
+	page, err := os.ReadFile(indexPath)
+	if err != nil {
+		return false
+	}
+
+	metadata := BuildEventPageMetadata(&event, settings, getBaseURL(c, db))
+
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(InjectPageMetadata(string(page), metadata)))
+	return true
+}
+
+// BuildEventPageMetadata computes SEO values from the event and project settings
+func BuildEventPageMetadata(event *models.Event, settings *models.ProjectSettings, baseURL string) *EventPageMetadata {
+	siteName := settings.Title
+	if siteName == "" {
+		siteName = "Changelog"
+	}
+
+	metadata := &EventPageMetadata{
+		Title:        fmt.Sprintf("%s - %s", event.Title, siteName),
+		Description:  firstParagraphText(event.Content, seoDescriptionMaxLength),
+		CanonicalURL: fmt.Sprintf("%s/%s", baseURL, event.Slug),
+		SiteName:     siteName,
+	}
+
+	// Use the first media image as the preview image
+	var mediaURLs []string
+	if event.Media != "" {
+		json.Unmarshal([]byte(event.Media), &mediaURLs)
+	}
+	for _, mediaURL := range SanitizeImageURLs(mediaURLs) {
+		if mediaURL == "" {
+			continue
+		}
+		if strings.HasPrefix(mediaURL, "/") {
+			mediaURL = baseURL + mediaURL
+		}
+		metadata.ImageURL = mediaURL
+		break
+	}
+
+	jsonLD := map[string]interface{}{
+		"@context":      "https://schema.org",
+		"@type":         "Article",
+		"headline":      event.Title,
+		"url":           metadata.CanonicalURL,
+		"datePublished": event.CreatedAt,
+		"dateModified":  event.UpdatedAt,
+		"publisher": map[string]interface{}{
+			"@type": "Organization",
+			"name":  siteName,
+		},
+	}
+	if metadata.Description != "" {
+		jsonLD["description"] = metadata.Description
+	}
+	if metadata.ImageURL != "" {
+		jsonLD["image"] = metadata.ImageURL
+	}
+	if settings.WebsiteURL != "" {
+		jsonLD["publisher"].(map[string]interface{})["url"] = settings.WebsiteURL
+	}
+
+	// json.Marshal escapes <, > and & so the payload can't break out of the script tag
+	if data, err := json.Marshal(jsonLD); err == nil {
+		metadata.JSONLD = string(data)
+	}
+
+	return metadata
+}
+
+// InjectPageMetadata replaces the page title and inserts SEO tags before </head>
+func InjectPageMetadata(page string, metadata *EventPageMetadata) string {
+	// Drop tags the theme ships with so crawlers don't see conflicting values
+	page = seoTitleRegex.ReplaceAllString(page, "")
+	page = seoExistingMetaRegex.ReplaceAllString(page, "")
+	page = seoCanonicalRegex.ReplaceAllString(page, "")
+
+	var tags strings.Builder
+	writeTag := func(format string, args ...interface{}) {
+		tags.WriteString(fmt.Sprintf(format, args...))
+		tags.WriteString("\n")
+	}
+
+	writeTag(`<title>%s</title>`, html.EscapeString(metadata.Title))
+	if metadata.Description != "" {
+		writeTag(`<meta name="description" content="%s">`, html.EscapeString(metadata.Description))
+	}
+	writeTag(`<link rel="canonical" href="%s">`, html.EscapeString(metadata.CanonicalURL))
+
+	// OpenGraph
+	writeTag(`<meta property="og:type" content="article">`)
+	writeTag(`<meta property="og:site_name" content="%s">`, html.EscapeString(metadata.SiteName))
+	writeTag(`<meta property="og:title" content="%s">`, html.EscapeString(metadata.Title))
+	writeTag(`<meta property="og:url" content="%s">`, html.EscapeString(metadata.CanonicalURL))
+	if metadata.Description != "" {
+		writeTag(`<meta property="og:description" content="%s">`, html.EscapeString(metadata.Description))
+	}
+	if metadata.ImageURL != "" {
+		writeTag(`<meta property="og:image" content="%s">`, html.EscapeString(metadata.ImageURL))
+	}
+
+	// Twitter card
+	if metadata.ImageURL != "" {
+		writeTag(`<meta name="twitter:card" content="summary_large_image">`)
+		writeTag(`<meta name="twitter:image" content="%s">`, html.EscapeString(metadata.ImageURL))
+	} else {
+		writeTag(`<meta name="twitter:card" content="summary">`)
+	}
+	writeTag(`<meta name="twitter:title" content="%s">`, html.EscapeString(metadata.Title))
+	if metadata.Description != "" {
+		writeTag(`<meta name="twitter:description" content="%s">`, html.EscapeString(metadata.Description))
+	}
+
+	if metadata.JSONLD != "" {
+		writeTag(`<script type="application/ld+json">%s</script>`, metadata.JSONLD)
+	}
+
+	// Insert before </head>, or at the top of the document if the theme has no head
+	if idx := strings.Index(strings.ToLower(page), "</head>"); idx != -1 {
+		return page[:idx] + tags.String() + page[idx:]
+	}
+	return tags.String() + page
+}
+
+// firstParagraphText returns the plain text of the first non-empty paragraph, truncated on a word boundary
+func firstParagraphText(content string, maxLength int) string {
+	text := ""
+	for _, match := range seoParagraphRegex.FindAllStringSubmatch(content, -1) {
+		text = htmlToPlainText(match[1])
+		if text != "" {
+			break
+		}
+	}
+
+	// Content without paragraphs (plain text or markdown): use the first line
+	if text == "" {
+		for _, line := range strings.Split(htmlToPlainText(strings.ReplaceAll(content, "<br", "\n<br")), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				text = line
+				break
+			}
+		}
+	}
+
+	runes := []rune(text)
+	if len(runes) <= maxLength {
+		return text
+	}
+
+	truncated := string(runes[:maxLength])
+	if idx := strings.LastIndex(truncated, " "); idx > maxLength/2 {
+		truncated = truncated[:idx]
+	}
+	return strings.TrimRight(truncated, " ,.;:") + "…"
+}
+
+// htmlToPlainText strips tags and entities and collapses whitespace (newlines are preserved)
+func htmlToPlainText(fragment string) string {
+	text := html.UnescapeString(seoTagRegex.ReplaceAllString(fragment, " "))
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(seoWhitespaceRegex.ReplaceAllString(line, " "))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
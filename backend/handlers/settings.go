@@ -37,6 +37,11 @@ func GetSettings(c *gin.Context) {
 		"website_url":           websiteURL,
 		"current_theme_id":      settings.CurrentThemeID,
 		"current_theme_version": settings.CurrentThemeVersion,
+		"robots_disallow_admin": settings.RobotsDisallowAdmin,
+		"robots_extra_rules":    settings.RobotsExtraRules,
+		"locale":                settings.Locale,
+		"supported_locales":     models.SupportedDateLocales(),
+		"ip_retention_days":     settings.IPRetentionDays,
 		"created_at":            settings.CreatedAt,
 		"updated_at":            settings.UpdatedAt,
 		"environment":           environment,
@@ -78,6 +83,31 @@ func UpdateSettings(c *gin.Context) {
 		settings.WebsiteURL = *req.WebsiteURL
 	}
 
+	if req.RobotsDisallowAdmin != nil {
+		settings.RobotsDisallowAdmin = *req.RobotsDisallowAdmin
+	}
+
+	if req.RobotsExtraRules != nil {
+		settings.RobotsExtraRules = *req.RobotsExtraRules
+	}
+
+	if req.Locale != nil {
+		locale := models.NormalizeLocale(*req.Locale)
+		if locale == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported locale: " + *req.Locale})
+			return
+		}
+		settings.Locale = locale
+	}
+
+	if req.IPRetentionDays != nil {
+		if *req.IPRetentionDays < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ip_retention_days must be 0 or more"})
+			return
+		}
+		settings.IPRetentionDays = *req.IPRetentionDays
+	}
+
 	if err := db.Save(&settings).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settings"})
 		return
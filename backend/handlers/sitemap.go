@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"shipshipship/database"
+	"shipshipship/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Maximum number of URLs allowed in a single sitemap by the sitemaps.org protocol
+const sitemapMaxURLs = 50000
+
+// AdminRoutePrefixes are the first path segments served by the admin interface
+var AdminRoutePrefixes = []string{"admin", "login"}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name       `xml:"sitemapindex"`
+	Xmlns    string         `xml:"xmlns,attr"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+// GetSitemap serves /sitemap.xml with every public event page.
+// Above the protocol limit it serves a sitemap index, and ?page=N serves each part.
+func GetSitemap(c *gin.Context) {
+	db := database.GetDB()
+	baseURL := getBaseURL(c, db)
+
+	var total int64
+	if err := publicEventPagesQuery(db).Count(&total).Error; err != nil {
+		c.String(http.StatusInternalServerError, "Failed to generate sitemap")
+		return
+	}
+
+	pageCount := int((total + sitemapMaxURLs - 1) / sitemapMaxURLs)
+	page := 1
+	if pageParam := c.Query("page"); pageParam != "" {
+		parsed, err := strconv.Atoi(pageParam)
+		if err != nil || parsed < 1 || parsed > pageCount {
+			c.String(http.StatusNotFound, "Sitemap not found")
+			return
+		}
+		page = parsed
+	} else if pageCount > 1 {
+		serveSitemapIndex(c, baseURL, pageCount)
+		return
+	}
+
+	var events []models.Event
+	if err := publicEventPagesQuery(db).Select("slug", "updated_at").
+		Order("id ASC").
+		Offset((page - 1) * sitemapMaxURLs).
+		Limit(sitemapMaxURLs).
+		Find(&events).Error; err != nil {
+		c.String(http.StatusInternalServerError, "Failed to generate sitemap")
+		return
+	}
+
+	urlSet := sitemapURLSet{
+		Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9",
+		URLs:  make([]sitemapURL, 0, len(events)),
+	}
+	for _, event := range events {
+		urlSet.URLs = append(urlSet.URLs, sitemapURL{
+			Loc:     fmt.Sprintf("%s/%s", baseURL, event.Slug),
+			LastMod: event.UpdatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+
+	writeSitemapXML(c, urlSet)
+}
+
+// serveSitemapIndex lists each sitemap part with the latest modification time of its events
+func serveSitemapIndex(c *gin.Context, baseURL string, pageCount int) {
+	db := database.GetDB()
+
+	var updatedAts []time.Time
+	if err := publicEventPagesQuery(db).
+		Order("id ASC").
+		Pluck("updated_at", &updatedAts).Error; err != nil {
+		c.String(http.StatusInternalServerError, "Failed to generate sitemap")
+		return
+	}
+
+	index := sitemapIndex{
+		Xmlns:    "http://www.sitemaps.org/schemas/sitemap/0.9",
+		Sitemaps: make([]sitemapEntry, 0, pageCount),
+	}
+
+	for page := 1; page <= pageCount; page++ {
+		entry := sitemapEntry{Loc: fmt.Sprintf("%s/sitemap.xml?page=%d", baseURL, page)}
+
+		// Parts are built in the same order, so each chunk matches one sitemap
+		start := (page - 1) * sitemapMaxURLs
+		end := min(start+sitemapMaxURLs, len(updatedAts))
+		var lastMod time.Time
+		for i := start; i < end; i++ {
+			if updatedAts[i].After(lastMod) {
+				lastMod = updatedAts[i]
+			}
+		}
+		if !lastMod.IsZero() {
+			entry.LastMod = lastMod.UTC().Format(time.RFC3339)
+		}
+
+		index.Sitemaps = append(index.Sitemaps, entry)
+	}
+
+	writeSitemapXML(c, index)
+}
+
+// publicEventPagesQuery selects the events that have their own public page
+func publicEventPagesQuery(db *gorm.DB) *gorm.DB {
+	return db.Model(&models.Event{}).
+		Where("is_public = ? AND has_public_url = ? AND slug != ''", true, true)
+}
+
+func writeSitemapXML(c *gin.Context, v interface{}) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to generate sitemap")
+		return
+	}
+
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", append([]byte(xml.Header), data...))
+}
+
+// GetRobotsTxt serves /robots.txt based on the project settings
+func GetRobotsTxt(c *gin.Context) {
+	db := database.GetDB()
+
+	settings, err := models.GetOrCreateSettings(db)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to generate robots.txt")
+		return
+	}
+
+	var robots strings.Builder
+	robots.WriteString("User-agent: *\n")
+	if settings.RobotsDisallowAdmin {
+		for _, prefix := range AdminRoutePrefixes {
+			robots.WriteString(fmt.Sprintf("Disallow: /%s\n", prefix))
+		}
+	} else {
+		robots.WriteString("Disallow:\n")
+	}
+
+	if extra := strings.TrimSpace(settings.RobotsExtraRules); extra != "" {
+		robots.WriteString("\n")
+		robots.WriteString(extra)
+		robots.WriteString("\n")
+	}
+
+	robots.WriteString(fmt.Sprintf("\nSitemap: %s/sitemap.xml\n", getBaseURL(c, db)))
+
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(robots.String()))
+}
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 	"strings"
 
@@ -145,8 +144,6 @@ func UpdateStatus(c *gin.Context) {
 		return
 	}
 
-	originalName := status.DisplayName
-
 	// Apply changes
 	if req.DisplayName != nil {
 		newName := strings.TrimSpace(*req.DisplayName)
@@ -179,35 +176,6 @@ func UpdateStatus(c *gin.Context) {
 		return
 	}
 
-	// If display name changed, update events referencing old name
-	if req.DisplayName != nil && originalName != status.DisplayName {
-		db.Model(&models.Event{}).Where("status = ?", originalName).Update("status", status.DisplayName)
-
-		// Also update newsletter automation trigger statuses
-		automationSettings, err := models.GetOrCreateAutomationSettings(db)
-		if err == nil && automationSettings.TriggerStatuses != "" {
-			var triggerStatuses []string
-			if err := json.Unmarshal([]byte(automationSettings.TriggerStatuses), &triggerStatuses); err == nil {
-				// Replace old status name with new status name
-				updated := false
-				for i, ts := range triggerStatuses {
-					if ts == originalName {
-						triggerStatuses[i] = status.DisplayName
-						updated = true
-					}
-				}
-
-				// Save updated trigger statuses if changed
-				if updated {
-					statusesJSON, err := json.Marshal(triggerStatuses)
-					if err == nil {
-						db.Model(&automationSettings).Update("trigger_statuses", string(statusesJSON))
-					}
-				}
-			}
-		}
-	}
-
 	c.JSON(http.StatusOK, status)
 }
 
@@ -232,7 +200,7 @@ func DeleteStatus(c *gin.Context) {
 
 	// Check usage
 	var eventCount int64
-	db.Model(&models.Event{}).Where("status = ?", status.DisplayName).Count(&eventCount)
+	db.Model(&models.Event{}).Where("status_id = ?", status.ID).Count(&eventCount)
 	if eventCount > 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete status while it is used by events"})
 		return
@@ -244,27 +212,28 @@ func DeleteStatus(c *gin.Context) {
 		return
 	}
 
+	// Remove workflow transitions involving this status
+	if err := db.Where("from_status_id = ? OR to_status_id = ?", status.ID, status.ID).Delete(&models.StatusTransition{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete status transitions"})
+		return
+	}
+
+	// Statuses using a named email template fall back to the default one
+	if err := db.Exec("DELETE FROM email_template_statuses WHERE event_status_definition_id = ?", status.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete email template mappings"})
+		return
+	}
+
 	// Remove this status from newsletter automation trigger statuses
 	automationSettings, err := models.GetOrCreateAutomationSettings(db)
-	if err == nil && automationSettings.TriggerStatuses != "" {
-		var triggerStatuses []string
-		if err := json.Unmarshal([]byte(automationSettings.TriggerStatuses), &triggerStatuses); err == nil {
-			// Filter out the deleted status
-			updatedStatuses := []string{}
-			for _, ts := range triggerStatuses {
-				if ts != status.DisplayName {
-					updatedStatuses = append(updatedStatuses, ts)
-				}
-			}
-
-			// Save updated trigger statuses if changed
-			if len(updatedStatuses) != len(triggerStatuses) {
-				statusesJSON, err := json.Marshal(updatedStatuses)
-				if err == nil {
-					db.Model(&automationSettings).Update("trigger_statuses", string(statusesJSON))
-				}
+	if err == nil && automationSettings.HasTriggerStatus(status.ID) {
+		updatedIDs := []uint{}
+		for _, triggerID := range automationSettings.GetTriggerStatusIDs() {
+			if triggerID != status.ID {
+				updatedIDs = append(updatedIDs, triggerID)
 			}
 		}
+		db.Model(&automationSettings).Update("trigger_statuses", models.EncodeTriggerStatusIDs(updatedIDs))
 	}
 
 	if err := db.Delete(&status).Error; err != nil {
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"shipshipship/database"
+	"shipshipship/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetEventStatusHistory returns the full status timeline of an event, including actors (admin only)
+func GetEventStatusHistory(c *gin.Context) {
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	db := database.GetDB()
+
+	var count int64
+	db.Unscoped().Model(&models.Event{}).Where("id = ?", eventID).Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+
+	timeline, err := models.GetEventStatusTimeline(db, uint(eventID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch status history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"event_id": eventID,
+		"timeline": timeline,
+	})
+}
+
+// GetStatusTimeAnalytics returns lead and cycle times per status pair.
+// Query parameters (dates as YYYY-MM-DD or RFC 3339):
+//   - from: start of the range (default: 90 days ago)
+//   - to: end of the range (default: now)
+//   - from_status_id, to_status_id: only return matching pairs
+func GetStatusTimeAnalytics(c *gin.Context) {
+	to := time.Now()
+	from := to.AddDate(0, 0, -90)
+
+	if value := c.Query("from"); value != "" {
+		parsed, err := parseAnalyticsDate(value, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date"})
+			return
+		}
+		from = parsed
+	}
+	if value := c.Query("to"); value != "" {
+		parsed, err := parseAnalyticsDate(value, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date"})
+			return
+		}
+		to = parsed
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+
+	var fromStatusID, toStatusID uint64
+	var err error
+	if value := c.Query("from_status_id"); value != "" {
+		if fromStatusID, err = strconv.ParseUint(value, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from_status_id"})
+			return
+		}
+	}
+	if value := c.Query("to_status_id"); value != "" {
+		if toStatusID, err = strconv.ParseUint(value, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to_status_id"})
+			return
+		}
+	}
+
+	db := database.GetDB()
+
+	pairs, err := models.ComputeStatusPairTimes(db, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute status analytics"})
+		return
+	}
+
+	filtered := make([]models.StatusPairTimes, 0, len(pairs))
+	for _, pair := range pairs {
+		if fromStatusID != 0 && pair.FromStatusID != uint(fromStatusID) {
+			continue
+		}
+		if toStatusID != 0 && pair.ToStatusID != uint(toStatusID) {
+			continue
+		}
+		filtered = append(filtered, pair)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":  from,
+		"to":    to,
+		"pairs": filtered,
+	})
+}
+
+// parseAnalyticsDate accepts a date or RFC 3339 timestamp; plain end dates include the whole day
+func parseAnalyticsDate(value string, endOfDay bool) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+
+	parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		parsed = parsed.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return parsed, nil
+}
@@ -347,33 +347,33 @@ func GetPublicEventsByCategory(c *gin.Context) {
 	}
 
 	// Get all public events
+	query, ok := applyEventDateQuery(c, db.Preload("Tags").Preload("StatusDefinition").Preload("Translations").Where("is_public = ?", true), "created_at DESC")
+	if !ok {
+		return
+	}
+	if query, ok = applyEventTagQuery(c, db, query); !ok {
+		return
+	}
+
 	var events []models.Event
-	if err := db.Preload("Tags").
-		Where("is_public = ?", true).
-		Order("created_at DESC").
-		Find(&events).Error; err != nil {
+	if err := query.Find(&events).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
 		return
 	}
 
-	// Get all status definitions
-	var statusDefs []models.EventStatusDefinition
-	if err := db.Find(&statusDefs).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch status definitions"})
+	// Serve the title and content in the reader's language when translated
+	localizeEvents(c, db, events)
+
+	// Create status -> category lookup (unmapped statuses won't appear in any category)
+	var mappings []models.StatusCategoryMapping
+	if err := db.Where("theme_id = ?", settings.CurrentThemeID).Find(&mappings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch status mappings"})
 		return
 	}
 
-	// Create status -> category lookup
-	statusCategoryMap := make(map[string]string)
-	for _, statusDef := range statusDefs {
-		var mapping models.StatusCategoryMapping
-		err := db.Where("status_definition_id = ? AND theme_id = ?", statusDef.ID, settings.CurrentThemeID).
-			First(&mapping).Error
-
-		if err == nil {
-			statusCategoryMap[statusDef.DisplayName] = mapping.CategoryID
-		}
-		// Skip unmapped statuses - they won't appear in any category
+	statusCategoryMap := make(map[uint]string)
+	for _, mapping := range mappings {
+		statusCategoryMap[mapping.StatusDefinitionID] = mapping.CategoryID
 	}
 
 	// Group events by category
@@ -398,7 +398,7 @@ func GetPublicEventsByCategory(c *gin.Context) {
 		// Sanitize content URLs (HTML content with image tags)
 		event.Content = SanitizeHTMLContent(event.Content)
 
-		categoryID, exists := statusCategoryMap[string(event.Status)]
+		categoryID, exists := statusCategoryMap[event.StatusID]
 		if exists {
 			categorizedEvents[categoryID] = append(categorizedEvents[categoryID], event)
 		}
@@ -609,31 +609,15 @@ func UpdateThemeSettings(c *gin.Context) {
 		}
 
 		// Convert value to string for storage
-		var valueStr string
-		switch v := value.(type) {
-		case bool:
-			valueStr = fmt.Sprintf("%t", v)
-		case float64:
-			valueStr = fmt.Sprintf("%v", v)
-		case string:
-			valueStr = v
-		default:
-			// For arrays and objects, serialize as JSON
-			if setting.Type == "array" || setting.Type == "object" {
-				jsonBytes, err := json.Marshal(v)
-				if err != nil {
-					c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid value for %s", settingID)})
-					return
-				}
-				valueStr = string(jsonBytes)
-			} else {
-				valueStr = fmt.Sprintf("%v", v)
-			}
+		valueStr, err := models.FormatThemeSettingValue(setting, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid value for %s", settingID)})
+			return
 		}
 
 		// Update or create setting value
 		var settingValue models.ThemeSettingValue
-		err := db.Where("theme_id = ? AND setting_id = ?", settings.CurrentThemeID, settingID).
+		err = db.Where("theme_id = ? AND setting_id = ?", settings.CurrentThemeID, settingID).
 			First(&settingValue).Error
 
 		if err == nil {
@@ -714,25 +698,7 @@ func GetPublicThemeSettings(c *gin.Context) {
 
 			// If user has set a value, use that instead
 			if val, exists := valueMap[setting.ID]; exists {
-				// Parse the stored JSON value based on type
-				if setting.Type == "boolean" {
-					value = val == "true"
-				} else if setting.Type == "number" {
-					// Parse as number
-					var num float64
-					fmt.Sscanf(val, "%f", &num)
-					value = num
-				} else if setting.Type == "array" || setting.Type == "object" {
-					// Parse JSON for arrays and objects
-					var parsed interface{}
-					if err := json.Unmarshal([]byte(val), &parsed); err == nil {
-						value = parsed
-					} else {
-						value = setting.Default
-					}
-				} else {
-					value = val
-				}
+				value = models.ParseThemeSettingValue(setting, val)
 			}
 
 			settingsResponse[setting.ID] = value
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"shipshipship/database"
+	"shipshipship/middleware"
+	"shipshipship/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetStatusWorkflow returns whether the workflow is enforced and its allowed transitions
+func GetStatusWorkflow(c *gin.Context) {
+	db := database.GetDB()
+
+	settings, err := models.GetOrCreateWorkflowSettings(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get workflow settings"})
+		return
+	}
+
+	var transitions []models.StatusTransition
+	if err := db.Order("from_status_id ASC, to_status_id ASC").Find(&transitions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch status transitions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":     settings.Enabled,
+		"transitions": transitions,
+	})
+}
+
+// UpdateStatusWorkflow toggles enforcement and/or replaces the allowed transitions
+func UpdateStatusWorkflow(c *gin.Context) {
+	var req models.UpdateStatusWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := database.GetDB()
+
+	settings, err := models.GetOrCreateWorkflowSettings(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get workflow settings"})
+		return
+	}
+
+	if req.Transitions != nil {
+		if err := models.ValidateStatusTransitions(db, *req.Transitions); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		for _, transition := range *req.Transitions {
+			for _, role := range transition.Roles {
+				if role = strings.TrimSpace(role); role != "" && role != middleware.RoleAdmin && role != middleware.RoleEditor {
+					c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown role %q: use %s or %s", role, middleware.RoleAdmin, middleware.RoleEditor)})
+					return
+				}
+			}
+		}
+		if _, err := models.ReplaceStatusTransitions(db, *req.Transitions); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save status transitions"})
+			return
+		}
+	}
+
+	if req.Enabled != nil {
+		settings.Enabled = *req.Enabled
+		if err := db.Save(settings).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update workflow settings"})
+			return
+		}
+	}
+
+	GetStatusWorkflow(c)
+}
@@ -11,12 +11,22 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// GetTags returns all tags
+// GetTags returns all tags, optionally only those of a group (?group_id=)
 func GetTags(c *gin.Context) {
 	var tags []models.Tag
 
 	db := database.GetDB()
-	if err := db.Order("name ASC").Find(&tags).Error; err != nil {
+	query := db.Order("name ASC")
+	if groupID := c.Query("group_id"); groupID != "" {
+		id, err := strconv.ParseUint(groupID, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+			return
+		}
+		query = query.Where("group_id = ?", id)
+	}
+
+	if err := query.Find(&tags).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
 		return
 	}
@@ -63,6 +73,23 @@ func CreateTag(c *gin.Context) {
 	}
 
 	db := database.GetDB()
+
+	if req.GroupID != nil && *req.GroupID != 0 {
+		if err := db.First(&models.TagGroup{}, *req.GroupID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Tag group not found"})
+			return
+		}
+		tag.GroupID = req.GroupID
+	}
+
+	if req.ParentID != nil && *req.ParentID != 0 {
+		if err := models.ValidateTagParent(db, 0, *req.ParentID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		tag.ParentID = req.ParentID
+	}
+
 	if err := db.Create(&tag).Error; err != nil {
 		// Check if it's a unique constraint violation
 		if err.Error() == "UNIQUE constraint failed: tags.name" {
@@ -110,6 +137,44 @@ func UpdateTag(c *gin.Context) {
 		}
 		tag.Color = *req.Color
 	}
+	if req.GroupID != nil {
+		if *req.GroupID == 0 {
+			tag.GroupID = nil
+		} else if tag.GroupID == nil || *tag.GroupID != *req.GroupID {
+			var group models.TagGroup
+			if err := db.First(&group, *req.GroupID).Error; err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Tag group not found"})
+				return
+			}
+			// Moving the tag must not give events two tags of a single-select group
+			if group.SelectionMode == models.TagGroupSelectionSingle {
+				conflicts, err := models.CountSingleSelectConflicts(db, group.ID, tag.ID)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check tag group selection"})
+					return
+				}
+				if conflicts > 0 {
+					c.JSON(http.StatusConflict, gin.H{
+						"error":           "Group " + group.Name + " is single-select and some events would have several of its tags",
+						"conflict_events": conflicts,
+					})
+					return
+				}
+			}
+			tag.GroupID = &group.ID
+		}
+	}
+	if req.ParentID != nil {
+		if *req.ParentID == 0 {
+			tag.ParentID = nil
+		} else {
+			if err := models.ValidateTagParent(db, tag.ID, *req.ParentID); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			tag.ParentID = req.ParentID
+		}
+	}
 
 	if err := db.Save(&tag).Error; err != nil {
 		// Check if it's a unique constraint violation
@@ -163,6 +228,20 @@ func DeleteTag(c *gin.Context) {
 		return
 	}
 
+	// Remove the tag from subscriber topics
+	if err := tx.Exec("DELETE FROM subscriber_topic_tags WHERE tag_id = ?", tagID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove tag from subscriber topics"})
+		return
+	}
+
+	// Attach child tags to the deleted tag's parent
+	if err := tx.Model(&models.Tag{}).Where("parent_id = ?", tag.ID).Update("parent_id", tag.ParentID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reparent child tags"})
+		return
+	}
+
 	// Delete the tag itself
 	if err := tx.Delete(&tag).Error; err != nil {
 		tx.Rollback()
@@ -179,13 +258,17 @@ func DeleteTag(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Tag deleted successfully"})
 }
 
-// GetTagUsage returns usage statistics for all tags
+// GetTagUsage returns usage statistics for all tags.
+// TotalCount also includes events tagged with any descendant of the tag.
 func GetTagUsage(c *gin.Context) {
 	type TagUsage struct {
-		ID    uint   `json:"id"`
-		Name  string `json:"name"`
-		Color string `json:"color"`
-		Count int64  `json:"count"`
+		ID         uint   `json:"id"`
+		Name       string `json:"name"`
+		Color      string `json:"color"`
+		GroupID    *uint  `json:"group_id"`
+		ParentID   *uint  `json:"parent_id"`
+		Count      int64  `json:"count"`
+		TotalCount int64  `json:"total_count"`
 	}
 
 	var tagUsage []TagUsage
@@ -197,16 +280,172 @@ func GetTagUsage(c *gin.Context) {
 			t.id,
 			t.name,
 			t.color,
+			t.group_id,
+			t.parent_id,
 			COALESCE(COUNT(et.event_id), 0) as count
 		FROM tags t
 		LEFT JOIN event_tags et ON t.id = et.tag_id
 		LEFT JOIN events e ON et.event_id = e.id AND e.deleted_at IS NULL
-		GROUP BY t.id, t.name, t.color
+		GROUP BY t.id, t.name, t.color, t.group_id, t.parent_id
 		ORDER BY count DESC, t.name ASC
 	`).Scan(&tagUsage).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tag usage"})
 		return
 	}
 
+	type eventTag struct {
+		TagID   uint
+		EventID uint
+	}
+	var eventTags []eventTag
+	if err := db.Raw(`
+		SELECT et.tag_id, et.event_id
+		FROM event_tags et
+		JOIN events e ON et.event_id = e.id AND e.deleted_at IS NULL
+	`).Scan(&eventTags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tag usage"})
+		return
+	}
+
+	eventsByTag := make(map[uint][]uint)
+	for _, et := range eventTags {
+		eventsByTag[et.TagID] = append(eventsByTag[et.TagID], et.EventID)
+	}
+	children := make(map[uint][]uint)
+	for _, usage := range tagUsage {
+		if usage.ParentID != nil {
+			children[*usage.ParentID] = append(children[*usage.ParentID], usage.ID)
+		}
+	}
+
+	for i := range tagUsage {
+		events := make(map[uint]bool)
+		visited := make(map[uint]bool)
+		queue := []uint{tagUsage[i].ID}
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			if visited[id] {
+				continue
+			}
+			visited[id] = true
+			for _, eventID := range eventsByTag[id] {
+				events[eventID] = true
+			}
+			queue = append(queue, children[id]...)
+		}
+		tagUsage[i].TotalCount = int64(len(events))
+	}
+
 	c.JSON(http.StatusOK, tagUsage)
 }
+
+// MergeTag moves all events from one tag to another and deletes the source tag
+func MergeTag(c *gin.Context) {
+	sourceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
+		return
+	}
+	targetID, err := strconv.ParseUint(c.Param("targetId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target tag ID"})
+		return
+	}
+	if sourceID == targetID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot merge a tag into itself"})
+		return
+	}
+
+	db := database.GetDB()
+
+	var source, target models.Tag
+	if err := db.First(&source, sourceID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+	if err := db.First(&target, targetID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Target tag not found"})
+		return
+	}
+
+	// The Feedback tag is recreated by feedback submissions, so it can't go away
+	if strings.ToLower(source.Name) == "feedback" {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "The 'Feedback' tag cannot be merged away as it's used by the system",
+		})
+		return
+	}
+
+	conflicts, err := models.SingleSelectMergeConflicts(db, &source, &target)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check tag group selection"})
+		return
+	}
+	if conflicts > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":           "Some events would have several tags of the target's single-select group",
+			"conflict_events": conflicts,
+		})
+		return
+	}
+
+	result, err := models.MergeTag(db, source.ID, target.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tag " + source.Name + " merged into " + target.Name,
+		"result":  result,
+	})
+}
+
+// RenameTags renames several tags at once, refusing names that would collide
+func RenameTags(c *gin.Context) {
+	var req struct {
+		Renames []models.TagRename `json:"renames" binding:"required,min=1,dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := database.GetDB()
+
+	// The Feedback tag is looked up by name by feedback submissions
+	ids := make([]uint, 0, len(req.Renames))
+	for _, rename := range req.Renames {
+		ids = append(ids, rename.ID)
+	}
+	var feedbackCount int64
+	if err := db.Model(&models.Tag{}).Where("id IN ? AND LOWER(name) = ?", ids, "feedback").Count(&feedbackCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+	if feedbackCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "The 'Feedback' tag cannot be renamed as it's used by the system",
+		})
+		return
+	}
+
+	tags, err := models.RenameTags(db, req.Renames)
+	if conflict, ok := err.(*models.TagRenameConflictError); ok {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Several tags would have the same name",
+			"names": conflict.Names,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tags renamed successfully",
+		"tags":    tags,
+	})
+}
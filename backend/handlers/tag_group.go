@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"shipshipship/database"
+	"shipshipship/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetTagGroups returns all tag groups with their tags
+func GetTagGroups(c *gin.Context) {
+	db := database.GetDB()
+
+	var groups []models.TagGroup
+	if err := db.Preload("Tags", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("name ASC")
+	}).Order("`order` ASC, name ASC").Find(&groups).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tag groups"})
+		return
+	}
+
+	c.JSON(http.StatusOK, groups)
+}
+
+// CreateTagGroup creates a new tag group
+func CreateTagGroup(c *gin.Context) {
+	var req models.CreateTagGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+		return
+	}
+
+	mode := req.SelectionMode
+	if mode == "" {
+		mode = models.TagGroupSelectionMulti
+	}
+	if !models.IsValidTagGroupSelection(mode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "selection_mode must be single or multi"})
+		return
+	}
+
+	db := database.GetDB()
+
+	var count int64
+	db.Model(&models.TagGroup{}).Where("LOWER(name) = ?", strings.ToLower(name)).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Tag group with this name already exists"})
+		return
+	}
+
+	group := models.TagGroup{Name: name, SelectionMode: mode}
+	if req.Order != nil {
+		group.Order = *req.Order
+	} else {
+		var maxOrder int
+		db.Model(&models.TagGroup{}).Select("COALESCE(MAX(`order`),0)").Scan(&maxOrder)
+		group.Order = maxOrder + 1
+	}
+
+	if err := db.Create(&group).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tag group"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, group)
+}
+
+// UpdateTagGroup updates a tag group
+func UpdateTagGroup(c *gin.Context) {
+	groupID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag group ID"})
+		return
+	}
+
+	var req models.UpdateTagGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := database.GetDB()
+	var group models.TagGroup
+	if err := db.First(&group, groupID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag group not found"})
+		return
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+			return
+		}
+		var count int64
+		db.Model(&models.TagGroup{}).Where("id != ? AND LOWER(name) = ?", group.ID, strings.ToLower(name)).Count(&count)
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Another tag group with this name already exists"})
+			return
+		}
+		group.Name = name
+	}
+
+	if req.SelectionMode != nil {
+		if !models.IsValidTagGroupSelection(*req.SelectionMode) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "selection_mode must be single or multi"})
+			return
+		}
+		// Switching to single-select requires every event to have at most one tag of the group
+		if *req.SelectionMode == models.TagGroupSelectionSingle && group.SelectionMode != models.TagGroupSelectionSingle {
+			conflicts, err := models.CountSingleSelectConflicts(db, group.ID, 0)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check tag group selection"})
+				return
+			}
+			if conflicts > 0 {
+				c.JSON(http.StatusConflict, gin.H{
+					"error":           "Some events have several tags of this group",
+					"conflict_events": conflicts,
+				})
+				return
+			}
+		}
+		group.SelectionMode = *req.SelectionMode
+	}
+
+	if req.Order != nil {
+		group.Order = *req.Order
+	}
+
+	if err := db.Save(&group).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tag group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// DeleteTagGroup deletes a tag group, leaving its tags ungrouped
+func DeleteTagGroup(c *gin.Context) {
+	groupID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag group ID"})
+		return
+	}
+
+	db := database.GetDB()
+	var group models.TagGroup
+	if err := db.First(&group, groupID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag group not found"})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Tag{}).Where("group_id = ?", group.ID).Update("group_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM subscriber_topic_groups WHERE tag_group_id = ?", group.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&group).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tag group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag group deleted successfully"})
+}
+
+// applyEventTagQuery applies the ?tag= and ?tag_group= query parameters (ID or name).
+// Both match events carrying the tag, any tag of the group, or any of their descendants.
+func applyEventTagQuery(c *gin.Context, db *gorm.DB, query *gorm.DB) (*gorm.DB, bool) {
+	if tagParam := c.Query("tag"); tagParam != "" {
+		var tag models.Tag
+		if err := db.Where("id = ? OR LOWER(name) = ?", tagParam, strings.ToLower(tagParam)).First(&tag).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown tag: " + tagParam})
+			return nil, false
+		}
+		tagIDs, err := models.TagDescendantIDs(db, []uint{tag.ID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve tag"})
+			return nil, false
+		}
+		query = query.Where("id IN (SELECT event_id FROM event_tags WHERE tag_id IN ?)", tagIDs)
+	}
+
+	if groupParam := c.Query("tag_group"); groupParam != "" {
+		var group models.TagGroup
+		if err := db.Where("id = ? OR LOWER(name) = ?", groupParam, strings.ToLower(groupParam)).First(&group).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown tag group: " + groupParam})
+			return nil, false
+		}
+		tagIDs, err := models.TagGroupTagIDs(db, group.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve tag group"})
+			return nil, false
+		}
+		if len(tagIDs) == 0 {
+			tagIDs = []uint{0}
+		}
+		query = query.Where("id IN (SELECT event_id FROM event_tags WHERE tag_id IN ?)", tagIDs)
+	}
+
+	return query, true
+}
@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type ApplyThemeRequest struct {
@@ -22,6 +23,9 @@ type ApplyThemeRequest struct {
 	ThemeVersion  string              `json:"themeVersion" binding:"required"`
 	BuildFileURL  string              `json:"buildFileUrl" binding:"required"`
 	Compatibility *ThemeCompatibility `json:"compatibility,omitempty"`
+	// CategoryOverrides maps old category IDs to new category IDs, overriding the computed migration.
+	// An empty value leaves the statuses of that category unmapped.
+	CategoryOverrides map[string]string `json:"categoryOverrides,omitempty"`
 }
 
 type ThemeCompatibility struct {
@@ -42,11 +46,12 @@ type ThemeStoreResponse struct {
 }
 
 type ApplyThemeResponse struct {
-	Success    bool   `json:"success"`
-	Message    string `json:"message"`
-	IsUpdate   bool   `json:"isUpdate"`
-	OldVersion string `json:"oldVersion,omitempty"`
-	NewVersion string `json:"newVersion"`
+	Success          bool                         `json:"success"`
+	Message          string                       `json:"message"`
+	IsUpdate         bool                         `json:"isUpdate"`
+	OldVersion       string                       `json:"oldVersion,omitempty"`
+	NewVersion       string                       `json:"newVersion"`
+	MappingMigration *models.MappingMigrationPlan `json:"mappingMigration,omitempty"`
 }
 
 // ApplyTheme downloads a theme ZIP file and extracts it to replace the admin build
@@ -88,6 +93,9 @@ func ApplyTheme(c *gin.Context) {
 	}
 	defer os.Remove(tempFile) // Clean up temp file
 
+	// Keep the outgoing theme's manifest so its mappings can be migrated
+	oldManifest := loadCurrentManifestOrNil()
+
 	// Create backup of current theme build
 	// Create backup of current theme
 	backupDir := "./data/themes/backup"
@@ -108,49 +116,58 @@ func ApplyTheme(c *gin.Context) {
 	// Check if this is an update or new application
 	db := database.GetDB()
 	settings, err := models.GetOrCreateSettings(db)
+	if err != nil {
+		restoreThemeBackup(backupDir, themeDir)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load settings", "details": err.Error()})
+		return
+	}
+
 	isUpdate := false
 	oldVersion := ""
+	previousThemeID := settings.CurrentThemeID
 
+	// Check if we're updating an existing theme
+	if settings.CurrentThemeID == req.ThemeID && settings.CurrentThemeVersion != "" {
+		isUpdate = true
+		oldVersion = settings.CurrentThemeVersion
+	}
+
+	// Load theme manifest and create default statuses/mappings
+	manifest, err := models.LoadThemeManifest(themeDir)
 	if err != nil {
-		// Theme was applied but we couldn't update settings - log but don't fail
-		fmt.Printf("Warning: Theme applied but couldn't update settings: %v\n", err)
+		fmt.Printf("Warning: Theme applied but failed to load manifest: %v\n", err)
+	} else if err := models.CreateDefaultStatusesFromTheme(db, req.ThemeID, manifest); err != nil {
+		fmt.Printf("Warning: Theme applied but failed to create default statuses: %v\n", err)
 	} else {
-		// Check if we're updating an existing theme
-		if settings.CurrentThemeID == req.ThemeID && settings.CurrentThemeVersion != "" {
-			isUpdate = true
-			oldVersion = settings.CurrentThemeVersion
-		}
+		fmt.Printf("Successfully created default statuses from theme %s\n", req.ThemeID)
+	}
 
-		// Update theme ID and version
+	// Record the new theme and carry mappings over from the previous one together, so a failed
+	// migration leaves the previous theme in place rather than categories without statuses
+	var migrationPlan *models.MappingMigrationPlan
+	err = db.Transaction(func(tx *gorm.DB) error {
 		settings.CurrentThemeID = req.ThemeID
 		settings.CurrentThemeVersion = req.ThemeVersion
-		if err := db.Save(settings).Error; err != nil {
-			fmt.Printf("Warning: Theme applied but couldn't save theme info: %v\n", err)
+		if err := tx.Save(settings).Error; err != nil {
+			return fmt.Errorf("failed to save theme info: %w", err)
 		}
-
-		// Load theme manifest and create default statuses/mappings
-		manifest, err := models.LoadThemeManifest(themeDir)
-		if err != nil {
-			fmt.Printf("Warning: Theme applied but failed to load manifest: %v\n", err)
-		} else {
-			// Create default statuses from theme categories if none exist
-			if err := models.CreateDefaultStatusesFromTheme(db, req.ThemeID, manifest); err != nil {
-				fmt.Printf("Warning: Theme applied but failed to create default statuses: %v\n", err)
-			} else {
-				fmt.Printf("Successfully created default statuses from theme %s\n", req.ThemeID)
-			}
-
-			// Create default mappings for all statuses
-			if err := models.CreateDefaultMappings(db, req.ThemeID, manifest); err != nil {
-				fmt.Printf("Warning: Theme applied but failed to create default mappings: %v\n", err)
-			} else {
-				fmt.Printf("Successfully created default status mappings for theme %s\n", req.ThemeID)
-			}
+		if manifest == nil {
+			return nil
+		}
+		var err error
+		if migrationPlan, err = migrateThemeMappings(tx, previousThemeID, oldManifest, req.ThemeID, manifest, req.CategoryOverrides); err != nil {
+			return fmt.Errorf("failed to migrate status mappings: %w", err)
 		}
+		return nil
+	})
+	if err != nil {
+		restoreThemeBackup(backupDir, themeDir)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply theme", "details": err.Error()})
+		return
+	}
+	if migrationPlan != nil {
+		fmt.Printf("Successfully migrated status mappings to theme %s\n", req.ThemeID)
 	}
-
-	// Clean up backup after successful application
-	os.RemoveAll(backupDir)
 
 	// Clean up backup after successful application
 	os.RemoveAll(backupDir)
@@ -161,14 +178,122 @@ func ApplyTheme(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, ApplyThemeResponse{
-		Success:    true,
-		Message:    message,
-		IsUpdate:   isUpdate,
-		OldVersion: oldVersion,
-		NewVersion: req.ThemeVersion,
+		Success:          true,
+		Message:          message,
+		IsUpdate:         isUpdate,
+		OldVersion:       oldVersion,
+		NewVersion:       req.ThemeVersion,
+		MappingMigration: migrationPlan,
+	})
+}
+
+// PreviewThemeMappingMigration downloads a theme and reports how status mappings would be
+// migrated if it were applied, without changing anything (dry run for ApplyTheme)
+func PreviewThemeMappingMigration(c *gin.Context) {
+	var req ApplyThemeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+		return
+	}
+
+	tempFile, err := downloadThemeFile(req.BuildFileURL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to download theme file", "details": err.Error()})
+		return
+	}
+	defer os.Remove(tempFile)
+
+	newManifest, err := readManifestFromThemeZip(tempFile)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read theme manifest", "details": err.Error()})
+		return
+	}
+
+	db := database.GetDB()
+	settings, err := models.GetOrCreateSettings(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get settings"})
+		return
+	}
+
+	plan, err := models.PlanMappingMigration(db, settings.CurrentThemeID, loadCurrentManifestOrNil(), req.ThemeID, newManifest, req.CategoryOverrides)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute mapping migration", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"dry_run": true,
+		"plan":    plan,
 	})
 }
 
+// migrateThemeMappings carries status mappings over from the previous theme to the newly applied one
+func migrateThemeMappings(db *gorm.DB, fromThemeID string, oldManifest *models.ThemeManifest, toThemeID string, newManifest *models.ThemeManifest, overrides map[string]string) (*models.MappingMigrationPlan, error) {
+	plan, err := models.PlanMappingMigration(db, fromThemeID, oldManifest, toThemeID, newManifest, overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := models.ApplyMappingMigration(db, plan); err != nil {
+		return nil, err
+	}
+
+	if len(plan.UnmappedStatuses) > 0 {
+		fmt.Printf("Theme %s: %d statuses left unmapped, hiding %d public events\n",
+			toThemeID, len(plan.UnmappedStatuses), plan.HiddenPublicEvents)
+	}
+
+	return plan, nil
+}
+
+// loadCurrentManifestOrNil returns the installed theme's manifest, or nil if it can't be read
+func loadCurrentManifestOrNil() *models.ThemeManifest {
+	manifest, err := models.LoadThemeManifest("./data/themes/current")
+	if err != nil {
+		return nil
+	}
+	return manifest
+}
+
+// readManifestFromThemeZip reads theme.json from a theme package without extracting it
+func readManifestFromThemeZip(zipFile string) (*models.ThemeManifest, error) {
+	reader, err := zip.OpenReader(zipFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ZIP file: %w", err)
+	}
+	defer reader.Close()
+
+	// Use the shallowest theme.json, which sits next to the build's index.html
+	var manifestFile *zip.File
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() || filepath.Base(file.Name) != "theme.json" {
+			continue
+		}
+		if manifestFile == nil || strings.Count(file.Name, "/") < strings.Count(manifestFile.Name, "/") {
+			manifestFile = file
+		}
+	}
+
+	if manifestFile == nil {
+		return nil, fmt.Errorf("theme.json not found in theme package")
+	}
+
+	rc, err := manifestFile.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read theme.json: %w", err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read theme.json: %w", err)
+	}
+
+	return models.ParseThemeManifest(data)
+}
+
 // GetCurrentTheme returns the currently applied theme ID and version
 func GetCurrentTheme(c *gin.Context) {
 	db := database.GetDB()
@@ -690,6 +815,9 @@ func applyThemeInternal(themeID, themeVersion, buildFileURL string) error {
 	}
 	defer os.Remove(tempFile)
 
+	// Keep the outgoing theme's manifest so its mappings can be migrated
+	oldManifest := loadCurrentManifestOrNil()
+
 	// Create backup of current theme (if any)
 	backupDir := "./data/themes/backup"
 	if err := backupCurrentTheme(backupDir); err != nil {
@@ -708,6 +836,7 @@ func applyThemeInternal(themeID, themeVersion, buildFileURL string) error {
 	db := database.GetDB()
 	settings, err := models.GetOrCreateSettings(db)
 	if err == nil {
+		previousThemeID := settings.CurrentThemeID
 		settings.CurrentThemeID = themeID
 		settings.CurrentThemeVersion = themeVersion
 		if err := db.Save(settings).Error; err != nil {
@@ -726,11 +855,11 @@ func applyThemeInternal(themeID, themeVersion, buildFileURL string) error {
 				fmt.Printf("Successfully created default statuses from theme %s\n", themeID)
 			}
 
-			// Create default mappings for all statuses
-			if err := models.CreateDefaultMappings(db, themeID, manifest); err != nil {
-				fmt.Printf("Warning: Theme applied but failed to create default mappings: %v\n", err)
+			// Carry mappings over from the previous theme
+			if _, err := migrateThemeMappings(db, previousThemeID, oldManifest, themeID, manifest, nil); err != nil {
+				fmt.Printf("Warning: Theme applied but failed to migrate status mappings: %v\n", err)
 			} else {
-				fmt.Printf("Successfully created default status mappings for theme %s\n", themeID)
+				fmt.Printf("Successfully migrated status mappings to theme %s\n", themeID)
 			}
 		}
 	}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"shipshipship/database"
+	"shipshipship/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportThemeBundle returns the current theme's setting values and status mappings as a portable bundle
+func ExportThemeBundle(c *gin.Context) {
+	db := database.GetDB()
+
+	settings, err := models.GetOrCreateSettings(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get settings"})
+		return
+	}
+
+	if settings.CurrentThemeID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No theme is currently applied"})
+		return
+	}
+
+	// The manifest is only used to type setting values, so a missing one is not fatal
+	manifest, err := models.LoadThemeManifest("./data/themes/current")
+	if err != nil {
+		fmt.Printf("Warning: Exporting theme bundle without manifest: %v\n", err)
+		manifest = nil
+	}
+
+	bundle, err := models.ExportThemeBundle(db, settings.CurrentThemeID, settings.CurrentThemeVersion, manifest)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export theme bundle", "details": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("theme-%s-%s.json", settings.CurrentThemeID, time.Now().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.JSON(http.StatusOK, bundle)
+}
+
+// ImportThemeBundle validates a bundle against the installed theme and applies it.
+// Query parameters:
+//   - dry_run=true: only report what would change
+//   - force=true: apply valid entries even if some entries conflict
+func ImportThemeBundle(c *gin.Context) {
+	var bundle models.ThemeBundle
+	if err := c.ShouldBindJSON(&bundle); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bundle format", "details": err.Error()})
+		return
+	}
+
+	dryRun := c.Query("dry_run") == "true"
+	force := c.Query("force") == "true"
+
+	db := database.GetDB()
+
+	settings, err := models.GetOrCreateSettings(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get settings"})
+		return
+	}
+
+	if settings.CurrentThemeID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No theme is currently applied"})
+		return
+	}
+
+	manifest, err := models.LoadThemeManifest("./data/themes/current")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to load theme manifest",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	plan, err := models.PlanThemeBundleImport(db, &bundle, settings.CurrentThemeID, settings.CurrentThemeVersion, manifest)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate theme bundle", "details": err.Error()})
+		return
+	}
+
+	if dryRun {
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"dry_run": true,
+			"plan":    plan,
+		})
+		return
+	}
+
+	if plan.HasBlockingConflicts() {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":     "Theme bundle cannot be imported into the installed theme",
+			"conflicts": plan.Conflicts,
+		})
+		return
+	}
+
+	// Non-blocking conflicts (other than a version difference) skip entries, so require explicit consent
+	if !force {
+		for _, conflict := range plan.Conflicts {
+			if conflict.Code != models.BundleConflictVersionMismatch {
+				c.JSON(http.StatusConflict, gin.H{
+					"error":     "Theme bundle has conflicting entries. Retry with force=true to skip them",
+					"conflicts": plan.Conflicts,
+				})
+				return
+			}
+		}
+	}
+
+	if err := models.ApplyThemeBundlePlan(db, plan); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply theme bundle", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":          true,
+		"settings_applied": len(plan.SettingChanges),
+		"mappings_applied": len(plan.MappingChanges),
+		"conflicts":        plan.Conflicts,
+	})
+}
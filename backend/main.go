@@ -13,6 +13,7 @@ import (
 	"shipshipship/middleware"
 	"shipshipship/models"
 	"shipshipship/services"
+	"shipshipship/utils"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -31,9 +32,14 @@ func isAdminRoute(path string) bool {
 		return false
 	}
 
-	// Check if first segment is admin or login
+	// Check if first segment is one of the admin prefixes
 	firstSegment := segments[0]
-	return firstSegment == "admin" || firstSegment == "login"
+	for _, prefix := range handlers.AdminRoutePrefixes {
+		if firstSegment == prefix {
+			return true
+		}
+	}
+	return false
 }
 
 // getAdminIndexPath returns the correct path to the admin index.html file
@@ -119,6 +125,17 @@ func main() {
 		log.Printf("Warning: Error loading .env file: %v", err)
 	}
 
+	// Maintenance commands run instead of the server
+	if len(os.Args) > 1 && os.Args[1] == "rekey" {
+		runRekey(os.Args[2:])
+		return
+	}
+
+	if !utils.HasSigningSecret() {
+		log.Printf("WARNING: JWT_SECRET is not set. Newsletter preference and confirmation links are disabled, " +
+			"since anyone could sign them with the public default.")
+	}
+
 	// Initialize database
 	database.InitDatabase()
 
@@ -134,6 +151,11 @@ func main() {
 	cleanupService.Start()
 	defer cleanupService.Stop()
 
+	// Start digest service for weekly and monthly newsletters
+	digestService := services.NewDigestService(db)
+	digestService.Start()
+	defer digestService.Stop()
+
 	// Set Gin mode
 	if os.Getenv("GIN_MODE") == "release" {
 		gin.SetMode(gin.ReleaseMode)
@@ -157,14 +179,14 @@ func main() {
 		api.GET("/events/slug/:slug", handlers.GetEventBySlug)
 
 		// Reaction routes (new system)
-		api.POST("/events/:id/reactions", handlers.AddOrRemoveReaction)
+		api.POST("/events/:id/reactions", middleware.ReactionRateLimit(), handlers.AddOrRemoveReaction)
 		api.GET("/events/:id/reactions", handlers.GetEventReactions)
 		api.GET("/events/:id/reactions/me", handlers.GetMyReactions)
 		api.GET("/events/reactions/counts", handlers.GetAllEventReactionsCount)
 		api.GET("/reactions/types", handlers.GetReactionTypes)
 
 		// Legacy vote routes (keep for backward compatibility)
-		api.POST("/events/:id/vote", handlers.VoteEvent)
+		api.POST("/events/:id/vote", middleware.ReactionRateLimit(), handlers.VoteEvent)
 		api.GET("/events/:id/vote-status", handlers.CheckVoteStatus)
 
 		api.POST("/feedback", middleware.FeedbackRateLimit(), handlers.SubmitFeedback)
@@ -174,13 +196,18 @@ func main() {
 
 		// Tag routes (public)
 		api.GET("/tags", handlers.GetTags)
+		api.GET("/tag-groups", handlers.GetTagGroups)
 		// Status routes (public)
 		api.GET("/statuses", handlers.GetStatuses)
+		api.GET("/releases", handlers.GetPublicReleases)
+		api.GET("/releases/:version", handlers.GetPublicRelease)
 
 		// Newsletter routes
 		api.POST("/newsletter/subscribe", handlers.SubscribeToNewsletter)
 		api.POST("/newsletter/unsubscribe", handlers.UnsubscribeFromNewsletter)
 		api.GET("/newsletter/status", handlers.CheckSubscriptionStatus)
+		api.GET("/newsletter/preferences", handlers.GetNewsletterPreferences)
+		api.PUT("/newsletter/preferences", handlers.UpdateNewsletterPreferences)
 
 		// Theme routes (public read access for admin interface)
 		api.GET("/themes/info", handlers.GetThemeInfo)
@@ -189,11 +216,18 @@ func main() {
 	// Protected admin routes
 	admin := api.Group("/admin")
 	admin.Use(middleware.AuthMiddleware())
+	// Editors manage content; workflow, mail and personal data settings are left to the admin
+	adminOnly := middleware.RequireRole(middleware.RoleAdmin)
 	{
 		admin.GET("/validate", handlers.ValidateToken)
 		admin.GET("/events", handlers.GetAllEvents)
 		admin.POST("/events", handlers.CreateEvent)
+		admin.PUT("/events/bulk/status", handlers.BulkUpdateEventStatus)
 		admin.PUT("/events/:id", handlers.UpdateEvent)
+		admin.GET("/events/:id/status-history", handlers.GetEventStatusHistory)
+		admin.GET("/events/:id/translations", handlers.GetEventTranslations)
+		admin.PUT("/events/:id/translations/:locale", handlers.SaveEventTranslation)
+		admin.DELETE("/events/:id/translations/:locale", handlers.DeleteEventTranslation)
 		admin.DELETE("/events/:id", handlers.DeleteEvent)
 		admin.PUT("/settings", handlers.UpdateSettings)
 		admin.POST("/upload/image", handlers.UploadImage)
@@ -203,31 +237,65 @@ func main() {
 		admin.GET("/tags/usage", handlers.GetTagUsage)
 		admin.GET("/tags/:id", handlers.GetTag)
 		admin.POST("/tags", handlers.CreateTag)
+		admin.POST("/tags/rename", handlers.RenameTags)
 		admin.PUT("/tags/:id", handlers.UpdateTag)
 		admin.DELETE("/tags/:id", handlers.DeleteTag)
+		admin.POST("/tags/:id/merge-into/:targetId", handlers.MergeTag)
+		admin.GET("/tag-groups", handlers.GetTagGroups)
+		admin.POST("/tag-groups", handlers.CreateTagGroup)
+		admin.PUT("/tag-groups/:id", handlers.UpdateTagGroup)
+		admin.DELETE("/tag-groups/:id", handlers.DeleteTagGroup)
 		// Status admin routes
 		admin.GET("/statuses", handlers.GetStatuses)
 		admin.GET("/statuses/:id", handlers.GetStatus)
-		admin.POST("/statuses", handlers.CreateStatus)
-		admin.PUT("/statuses/:id", handlers.UpdateStatus)
-		admin.DELETE("/statuses/:id", handlers.DeleteStatus)
-		admin.POST("/statuses/reorder", handlers.ReorderStatuses)
+		admin.POST("/statuses", adminOnly, handlers.CreateStatus)
+		admin.PUT("/statuses/:id", adminOnly, handlers.UpdateStatus)
+		admin.DELETE("/statuses/:id", adminOnly, handlers.DeleteStatus)
+		admin.POST("/statuses/reorder", adminOnly, handlers.ReorderStatuses)
+		admin.GET("/status-workflow", handlers.GetStatusWorkflow)
+		admin.GET("/analytics/status-times", handlers.GetStatusTimeAnalytics)
+		admin.PUT("/status-workflow", adminOnly, handlers.UpdateStatusWorkflow)
+
+		// Release management
+		admin.GET("/releases", handlers.GetReleases)
+		admin.GET("/releases/:id", handlers.GetRelease)
+		admin.POST("/releases", handlers.CreateRelease)
+		admin.PUT("/releases/:id", handlers.UpdateRelease)
+		admin.DELETE("/releases/:id", handlers.DeleteRelease)
+		admin.GET("/releases/:id/newsletter/preview", handlers.GetReleaseNewsletterPreview)
+		admin.POST("/releases/:id/newsletter/send", handlers.SendReleaseNewsletter)
 
 		// Mail settings routes
-		admin.GET("/settings/mail", handlers.GetMailSettings)
-		admin.POST("/settings/mail", handlers.UpdateMailSettings)
-		admin.POST("/settings/mail/test", handlers.TestMailSettings)
+		admin.GET("/settings/mail", adminOnly, handlers.GetMailSettings)
+		admin.POST("/settings/mail", adminOnly, handlers.UpdateMailSettings)
+		admin.POST("/settings/mail/test", adminOnly, handlers.TestMailSettings)
+		admin.GET("/settings/mail/dkim", adminOnly, handlers.GetDKIMRecords)
+		admin.POST("/settings/mail/dkim/generate", adminOnly, handlers.GenerateDKIMKey)
+		admin.POST("/settings/mail/dkim/activate", adminOnly, handlers.ActivateDKIMKey)
+		admin.DELETE("/settings/mail/dkim/pending", adminOnly, handlers.CancelDKIMRotation)
 
 		// Newsletter admin routes
 		admin.GET("/newsletter/stats", handlers.GetNewsletterStats)
 		admin.GET("/newsletter/subscribers", handlers.GetNewsletterSubscribers)
 		admin.GET("/newsletter/subscribers/paginated", handlers.GetNewsletterSubscribersPaginated)
+		admin.GET("/newsletter/subscribers/export", adminOnly, handlers.ExportNewsletterSubscribers)
+		admin.POST("/newsletter/subscribers/import", adminOnly, handlers.ImportNewsletterSubscribers)
 		admin.DELETE("/newsletter/subscribers/:email", handlers.DeleteNewsletterSubscriber)
 		admin.GET("/newsletter/history", handlers.GetNewsletterHistory)
 		admin.GET("/newsletter/templates", handlers.GetEmailTemplates)
 		admin.PUT("/newsletter/templates", handlers.UpdateEmailTemplates)
+		admin.POST("/newsletter/templates/preview", handlers.PreviewEmailTemplate)
+		admin.GET("/newsletter/templates/list", handlers.ListEmailTemplates)
+		admin.POST("/newsletter/templates", handlers.CreateEmailTemplate)
+		admin.GET("/newsletter/templates/:id", handlers.GetEmailTemplateByID)
+		admin.PUT("/newsletter/templates/:id", handlers.UpdateEmailTemplate)
+		admin.DELETE("/newsletter/templates/:id", handlers.DeleteEmailTemplate)
+		admin.POST("/newsletter/templates/:id/clone", handlers.CloneEmailTemplate)
+		admin.PUT("/newsletter/templates/:id/translations/:locale", handlers.SaveEmailTemplateTranslation)
+		admin.DELETE("/newsletter/templates/:id/translations/:locale", handlers.DeleteEmailTemplateTranslation)
 		admin.GET("/newsletter/automation", handlers.GetNewsletterAutomationSettings)
 		admin.PUT("/newsletter/automation", handlers.UpdateNewsletterAutomationSettings)
+		admin.GET("/newsletter/digest/preview", handlers.GetDigestPreview)
 
 		// Event publishing routes
 		admin.GET("/events/:id/publish", handlers.GetEventPublishStatus)
@@ -238,6 +306,7 @@ func main() {
 
 		// Theme admin routes
 		admin.POST("/themes/apply", handlers.ApplyTheme)
+		admin.POST("/themes/apply/preview", handlers.PreviewThemeMappingMigration)
 		admin.POST("/themes/redownload", handlers.RedownloadTheme)
 		admin.GET("/themes/current", handlers.GetCurrentTheme)
 		admin.GET("/themes/info", handlers.GetThemeInfo)
@@ -252,6 +321,15 @@ func main() {
 		admin.GET("/theme/settings", handlers.GetThemeSettings)
 		admin.PUT("/theme/settings", handlers.UpdateThemeSettings)
 
+		// Theme bundle routes (settings + status mappings)
+		admin.GET("/theme/export", handlers.ExportThemeBundle)
+		admin.POST("/theme/import", handlers.ImportThemeBundle)
+
+		// Personal data routes (data subject requests and IP retention)
+		admin.GET("/privacy/lookup", adminOnly, handlers.LookupPersonalData)
+		admin.POST("/privacy/erase", adminOnly, handlers.ErasePersonalData)
+		admin.POST("/privacy/anonymize-ips", adminOnly, handlers.AnonymizeIPAddresses)
+
 		// Migration route (one-time use)
 		admin.POST("/migrate/votes-to-reactions", handlers.MigrateVotesToReactions)
 	}
@@ -328,6 +406,16 @@ func main() {
 		c.File(filepath.Join(getAdminBuildPath(), "favicon.ico"))
 	})
 
+	// Search engine routes
+	r.GET("/sitemap.xml", handlers.GetSitemap)
+	r.GET("/robots.txt", handlers.GetRobotsTxt)
+
+	// Newsletter preferences and confirmation pages linked from emails
+	r.GET("/newsletter/preferences", handlers.ServePreferencesPage)
+	r.POST("/newsletter/preferences", handlers.SavePreferencesPage)
+	r.GET("/newsletter/confirm", handlers.ServeConfirmationPage)
+	r.POST("/newsletter/confirm", handlers.ConfirmSubscriptionPage)
+
 	// Public changelog routes - serve theme if available
 	r.GET("/", func(c *gin.Context) {
 		// Check if theme exists
@@ -370,6 +458,10 @@ func main() {
 
 		// For other routes, check if theme exists
 		if _, err := os.Stat("./data/themes/current/index.html"); err == nil {
+			// Public event pages get their SEO metadata rendered server-side
+			if handlers.ServeEventPage(c, "./data/themes/current/index.html") {
+				return
+			}
 			c.Header("Content-Type", "text/html; charset=utf-8")
 			c.File("./data/themes/current/index.html")
 			return
@@ -6,6 +6,8 @@ import (
 	"strings"
 	"time"
 
+	"shipshipship/utils"
+
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -13,22 +15,26 @@ import (
 var jwtSecret []byte
 
 func init() {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "your-secret-key-change-in-production"
-	}
-	jwtSecret = []byte(secret)
+	jwtSecret = utils.SigningSecret()
 }
 
+// Roles of the accounts allowed to sign in
+const (
+	RoleAdmin  = "admin"  // The account configured through ADMIN_USERNAME/ADMIN_PASSWORD
+	RoleEditor = "editor" // Accounts listed in EDITOR_ACCOUNTS
+)
+
 type Claims struct {
 	Username string `json:"username"`
+	Role     string `json:"role,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(username string) (string, error) {
+func GenerateToken(username, role string) (string, error) {
 	expirationTime := time.Now().Add(24 * time.Hour)
 	claims := &Claims{
 		Username: username,
+		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -61,6 +67,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		// Skip authentication in demo mode
 		if IsDemoMode() {
 			c.Set("username", "demo")
+			c.Set("role", RoleAdmin)
 			c.Next()
 			return
 		}
@@ -86,11 +93,63 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Tokens issued before roles existed carry none and must be renewed by signing in again
+		if claims.Role != RoleAdmin && claims.Role != RoleEditor {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+
 		c.Set("username", claims.Username)
+		c.Set("role", claims.Role)
 		c.Next()
 	}
 }
 
+// RequireRole restricts the routes to users with one of the roles. It must run after AuthMiddleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := GetRole(c)
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your account is not allowed to do this"})
+		c.Abort()
+	}
+}
+
+// GetRole returns the role of the authenticated user, or an empty string
+func GetRole(c *gin.Context) string {
+	return c.GetString("role")
+}
+
+// Authenticate checks the credentials against the admin account and the editor accounts,
+// and returns the role of the matching account
+func Authenticate(username, password string) (string, bool) {
+	if CheckAdminCredentials(username, password) {
+		return RoleAdmin, true
+	}
+	if editorPassword, ok := editorAccounts()[username]; ok && editorPassword != "" && password == editorPassword {
+		return RoleEditor, true
+	}
+	return "", false
+}
+
+// editorAccounts reads EDITOR_ACCOUNTS, a comma-separated list of username:password pairs
+func editorAccounts() map[string]string {
+	accounts := map[string]string{}
+	for _, entry := range strings.Split(os.Getenv("EDITOR_ACCOUNTS"), ",") {
+		username, password, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if ok && username != "" {
+			accounts[username] = password
+		}
+	}
+	return accounts
+}
+
 func CheckAdminCredentials(username, password string) bool {
 	adminUsername := os.Getenv("ADMIN_USERNAME")
 	adminPassword := os.Getenv("ADMIN_PASSWORD")
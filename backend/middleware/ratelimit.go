@@ -23,6 +23,15 @@ var feedbackLimiter = &RateLimiter{
 	clients: make(map[string]*ClientData),
 }
 
+var reactionLimiter = &RateLimiter{
+	clients: make(map[string]*ClientData),
+}
+
+const (
+	reactionRateLimitWindow = time.Minute
+	reactionRateLimitMax    = 30 // Reactions and votes per window
+)
+
 // CleanupOldEntries removes old entries from the rate limiter
 func (rl *RateLimiter) cleanupOldEntries() {
 	rl.mutex.Lock()
@@ -47,6 +56,8 @@ func init() {
 			select {
 			case <-ticker.C:
 				feedbackLimiter.cleanupOldEntries()
+				reactionLimiter.cleanupOldEntries()
+				visitorIDLimiter.cleanupOldEntries()
 			}
 		}
 	}()
@@ -116,3 +127,40 @@ func FeedbackRateLimit() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// ReactionRateLimit limits how often a visitor can react or vote. Visitors are counted by their
+// visitor ID; requests without one, which would each get a new ID, are counted by IP instead.
+func ReactionRateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := "ip:" + c.ClientIP()
+		if id, ok := VisitorID(c); ok {
+			key = "visitor:" + id
+		}
+		now := time.Now()
+
+		reactionLimiter.mutex.Lock()
+		clientData, exists := reactionLimiter.clients[key]
+		if !exists || now.After(clientData.resetTime) {
+			clientData = &ClientData{resetTime: now.Add(reactionRateLimitWindow)}
+			reactionLimiter.clients[key] = clientData
+		}
+		limited := clientData.submissionCount >= reactionRateLimitMax
+		retryAfter := int(clientData.resetTime.Sub(now).Seconds()) + 1
+		if !limited {
+			clientData.lastSubmission = now
+			clientData.submissionCount++
+		}
+		reactionLimiter.mutex.Unlock()
+
+		if limited {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Too many reactions. Please wait before reacting again.",
+				"retry_after": retryAfter,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"shipshipship/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VisitorCookieName is the cookie holding the anonymous visitor ID used for reactions
+const VisitorCookieName = "sss_visitor"
+
+const (
+	visitorCookiePurpose = "visitor-id"
+	visitorContextKey    = "visitor_id"
+	visitorCookieMaxAge  = 400 * 24 * 60 * 60 // Browsers cap cookie lifetimes at 400 days
+
+	visitorIDLimitWindow = 24 * time.Hour
+	visitorIDLimitMax    = 50 // New visitor IDs per IP address per window
+)
+
+// ErrVisitorIDLimit is returned by EnsureVisitorID when an IP address has been issued too many visitor IDs
+var ErrVisitorIDLimit = errors.New("too many visitor IDs issued to this IP address")
+
+var visitorIDLimiter = &RateLimiter{
+	clients: make(map[string]*ClientData),
+}
+
+// allowVisitorID counts a new visitor ID against the limit of an IP address, reporting whether it may be issued
+func allowVisitorID(ip string, now time.Time) bool {
+	visitorIDLimiter.mutex.Lock()
+	defer visitorIDLimiter.mutex.Unlock()
+
+	clientData, exists := visitorIDLimiter.clients[ip]
+	if !exists || now.After(clientData.resetTime) {
+		clientData = &ClientData{resetTime: now.Add(visitorIDLimitWindow)}
+		visitorIDLimiter.clients[ip] = clientData
+	}
+	if clientData.submissionCount >= visitorIDLimitMax {
+		return false
+	}
+	clientData.lastSubmission = now
+	clientData.submissionCount++
+	return true
+}
+
+// VisitorID returns the anonymous visitor ID of the request, if it carries a validly signed cookie
+func VisitorID(c *gin.Context) (string, bool) {
+	if id, ok := c.Get(visitorContextKey); ok {
+		return id.(string), true
+	}
+
+	value, err := c.Cookie(VisitorCookieName)
+	if err != nil {
+		return "", false
+	}
+	id, signature, ok := strings.Cut(value, ".")
+	if !ok || id == "" || !utils.VerifySignedValue(visitorCookiePurpose, id, signature) {
+		return "", false
+	}
+	c.Set(visitorContextKey, id)
+	return id, true
+}
+
+// EnsureVisitorID returns the visitor ID of the request, issuing a new one in a signed, HttpOnly
+// cookie when the request has none. issued reports whether the ID is new. Each IP address gets at
+// most visitorIDLimitMax new IDs a day, so clearing the cookie doesn't give unlimited identities.
+func EnsureVisitorID(c *gin.Context) (id string, issued bool, err error) {
+	if id, ok := VisitorID(c); ok {
+		return id, false, nil
+	}
+	if !allowVisitorID(c.ClientIP(), time.Now()) {
+		return "", false, ErrVisitorIDLimit
+	}
+
+	buffer := make([]byte, 16)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", false, err
+	}
+	id = base64.RawURLEncoding.EncodeToString(buffer)
+
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(VisitorCookieName, id+"."+utils.SignValue(visitorCookiePurpose, id), visitorCookieMaxAge, "/", "", secure, true)
+	c.Set(visitorContextKey, id)
+	return id, true, nil
+}
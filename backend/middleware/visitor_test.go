@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"shipshipship/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newVisitorContext(cookie string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	c.Request.RemoteAddr = "192.0.2.1:1234"
+	if cookie != "" {
+		c.Request.AddCookie(&http.Cookie{Name: VisitorCookieName, Value: cookie})
+	}
+	return c
+}
+
+func TestVisitorID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	signature := utils.SignValue(visitorCookiePurpose, "visitor")
+
+	tests := []struct {
+		name   string
+		cookie string
+		wantID string
+	}{
+		{"valid", "visitor." + signature, "visitor"},
+		{"no cookie", "", ""},
+		{"tampered id", "visitor2." + signature, ""},
+		{"tampered signature", "visitor." + signature[1:], ""},
+		{"missing signature", "visitor", ""},
+		{"empty id", "." + utils.SignValue(visitorCookiePurpose, ""), ""},
+		{"signature for another purpose", "visitor." + utils.SignValue("unsubscribe", "visitor"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, ok := VisitorID(newVisitorContext(tt.cookie))
+			if id != tt.wantID || ok != (tt.wantID != "") {
+				t.Errorf("VisitorID = %q, %v, want %q", id, ok, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestEnsureVisitorIDLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	visitorIDLimiter.clients = make(map[string]*ClientData)
+
+	for i := 0; i < visitorIDLimitMax; i++ {
+		if _, issued, err := EnsureVisitorID(newVisitorContext("")); err != nil || !issued {
+			t.Fatalf("ID %d: issued = %v, err = %v", i, issued, err)
+		}
+	}
+	if _, _, err := EnsureVisitorID(newVisitorContext("")); !errors.Is(err, ErrVisitorIDLimit) {
+		t.Fatalf("ID past the limit: err = %v, want ErrVisitorIDLimit", err)
+	}
+
+	// Visitors who already have an ID keep it
+	cookie := "visitor." + utils.SignValue(visitorCookiePurpose, "visitor")
+	if id, issued, err := EnsureVisitorID(newVisitorContext(cookie)); err != nil || issued || id != "visitor" {
+		t.Errorf("EnsureVisitorID = %q, %v, %v, want the existing ID", id, issued, err)
+	}
+
+	// The limit resets after the window
+	if !allowVisitorID("192.0.2.1", time.Now().Add(visitorIDLimitWindow+time.Second)) {
+		t.Error("allowVisitorID refused an ID after the window")
+	}
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Outcome of a delivery
+const (
+	EmailDeliverySent   = "sent"
+	EmailDeliveryFailed = "failed"
+)
+
+// EmailDelivery records one email handed to the mail transport
+type EmailDelivery struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Recipient string    `json:"recipient" gorm:"not null;index"`
+	Subject   string    `json:"subject"`
+	Status    string    `json:"status" gorm:"not null"` // sent or failed
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RecordEmailDelivery logs the outcome of sending an email to a recipient
+func RecordEmailDelivery(db *gorm.DB, recipient, subject string, sendErr error) error {
+	delivery := EmailDelivery{
+		Recipient: recipient,
+		Subject:   subject,
+		Status:    EmailDeliverySent,
+	}
+	if sendErr != nil {
+		delivery.Status = EmailDeliveryFailed
+		delivery.Error = sendErr.Error()
+	}
+	return db.Create(&delivery).Error
+}
+
+// DeleteEmailDeliveriesBefore removes the delivery records created before a time and returns their number
+func DeleteEmailDeliveriesBefore(db *gorm.DB, before time.Time) (int64, error) {
+	deleted := db.Where("created_at < ?", before).Delete(&EmailDelivery{})
+	return deleted.RowsAffected, deleted.Error
+}
+
+// GetEmailDeliveries returns the emails sent to a recipient, newest first
+func GetEmailDeliveries(db *gorm.DB, recipient string) ([]EmailDelivery, error) {
+	deliveries := []EmailDelivery{}
+	err := db.Where("LOWER(recipient) = LOWER(?)", recipient).Order("created_at DESC").Find(&deliveries).Error
+	return deliveries, err
+}
@@ -0,0 +1,192 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"shipshipship/constants"
+
+	"gorm.io/gorm"
+)
+
+// EmailTemplateTranslation is the subject and content of a template in another language
+type EmailTemplateTranslation struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	TemplateID  uint      `json:"template_id" gorm:"not null;uniqueIndex:idx_email_template_translation"`
+	Locale      string    `json:"locale" gorm:"not null;uniqueIndex:idx_email_template_translation"`
+	Subject     string    `json:"subject" gorm:"not null"`
+	Content     string    `json:"content" gorm:"type:text;not null"`
+	TextContent string    `json:"text_content" gorm:"type:text"` // Derived from Content when empty
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Translation returns the template's translation in a language, or nil
+func (t *EmailTemplate) Translation(locale string) *EmailTemplateTranslation {
+	for i := range t.Translations {
+		if t.Translations[i].Locale == locale {
+			return &t.Translations[i]
+		}
+	}
+	return nil
+}
+
+// Localized returns the template as written in the first of the locales it has a translation
+// for, with that locale, or the template itself and "". Translations must be loaded.
+func (t *EmailTemplate) Localized(locales ...string) (*EmailTemplate, string) {
+	for _, locale := range locales {
+		if translation := t.Translation(locale); translation != nil {
+			localized := *t
+			localized.Subject = translation.Subject
+			localized.Content = translation.Content
+			localized.TextContent = translation.TextContent
+			return &localized, translation.Locale
+		}
+	}
+	return t, ""
+}
+
+// Locales returns the languages the template is translated into
+func (t *EmailTemplate) Locales() []string {
+	locales := []string{}
+	for _, translation := range t.Translations {
+		locales = append(locales, translation.Locale)
+	}
+	return locales
+}
+
+// DisplayName returns the template's name, or its type for a default template
+func (t *EmailTemplate) DisplayName() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.Type
+}
+
+// StatusIDs returns the IDs of the statuses the template is mapped to
+func (t *EmailTemplate) StatusIDs() []uint {
+	ids := []uint{}
+	for _, status := range t.Statuses {
+		ids = append(ids, status.ID)
+	}
+	return ids
+}
+
+// ListEmailTemplates returns every template with its statuses, defaults first
+func ListEmailTemplates(db *gorm.DB) ([]EmailTemplate, error) {
+	var templates []EmailTemplate
+	err := db.Preload("Statuses", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("`order` ASC")
+	}).Preload("Translations", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("locale ASC")
+	}).Order("type ASC, is_default DESC, name ASC").Find(&templates).Error
+	return templates, err
+}
+
+// GetEmailTemplateByID returns a template with its statuses
+func GetEmailTemplateByID(db *gorm.DB, id uint) (*EmailTemplate, error) {
+	var template EmailTemplate
+	if err := db.Preload("Statuses").Preload("Translations").First(&template, id).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// GetEmailTemplateForStatus returns the event template mapped to a status, or the default event template
+func GetEmailTemplateForStatus(db *gorm.DB, statusID uint) (*EmailTemplate, error) {
+	var template EmailTemplate
+	err := db.Preload("Translations").Joins("JOIN email_template_statuses ON email_template_statuses.email_template_id = email_templates.id").
+		Where("email_template_statuses.event_status_definition_id = ? AND email_templates.type = ?", statusID, constants.TemplateTypeEvent).
+		First(&template).Error
+	if err == nil {
+		return &template, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return GetEmailTemplate(db, constants.TemplateTypeEvent)
+}
+
+// EmailTemplateNameTaken reports whether another template of the type already has the name
+func EmailTemplateNameTaken(db *gorm.DB, templateType, name string, excludeID uint) (bool, error) {
+	var count int64
+	err := db.Model(&EmailTemplate{}).
+		Where("type = ? AND LOWER(name) = ? AND id != ?", templateType, strings.ToLower(name), excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ResolveEmailTemplateStatuses loads the statuses to map to a template. A status can only use
+// one template, so statuses already mapped to another template are rejected.
+func ResolveEmailTemplateStatuses(db *gorm.DB, templateID uint, statusIDs []uint) ([]EventStatusDefinition, error) {
+	statuses := []EventStatusDefinition{}
+	if len(statusIDs) == 0 {
+		return statuses, nil
+	}
+
+	if err := db.Where("id IN ?", statusIDs).Find(&statuses).Error; err != nil {
+		return nil, err
+	}
+	if len(statuses) != len(UniqueIDs(statusIDs)) {
+		return nil, fmt.Errorf("unknown status in status_ids")
+	}
+
+	var taken []struct {
+		Name   string
+		Status string
+	}
+	if err := db.Table("email_template_statuses").
+		Select("email_templates.name AS name, event_status_definitions.display_name AS status").
+		Joins("JOIN email_templates ON email_templates.id = email_template_statuses.email_template_id AND email_templates.deleted_at IS NULL").
+		Joins("JOIN event_status_definitions ON event_status_definitions.id = email_template_statuses.event_status_definition_id").
+		Where("email_template_statuses.event_status_definition_id IN ? AND email_template_statuses.email_template_id != ?", statusIDs, templateID).
+		Scan(&taken).Error; err != nil {
+		return nil, err
+	}
+	if len(taken) > 0 {
+		return nil, fmt.Errorf("status %q already uses the template %q", taken[0].Status, taken[0].Name)
+	}
+	return statuses, nil
+}
+
+// SetEmailTemplateStatuses replaces the statuses a template is mapped to
+func SetEmailTemplateStatuses(db *gorm.DB, template *EmailTemplate, statuses []EventStatusDefinition) error {
+	if err := db.Model(template).Association("Statuses").Replace(statuses); err != nil {
+		return err
+	}
+	template.Statuses = statuses
+	return nil
+}
+
+// SaveEmailTemplateTranslation creates or replaces a template's translation in a language
+func SaveEmailTemplateTranslation(db *gorm.DB, translation *EmailTemplateTranslation) error {
+	var existing EmailTemplateTranslation
+	err := db.Where("template_id = ? AND locale = ?", translation.TemplateID, translation.Locale).First(&existing).Error
+	if err == nil {
+		translation.ID = existing.ID
+		translation.CreatedAt = existing.CreatedAt
+	} else if err != gorm.ErrRecordNotFound {
+		return err
+	}
+	return db.Save(translation).Error
+}
+
+// DeleteEmailTemplateTranslation removes a template's translation in a language
+func DeleteEmailTemplateTranslation(db *gorm.DB, templateID uint, locale string) (bool, error) {
+	result := db.Where("template_id = ? AND locale = ?", templateID, locale).Delete(&EmailTemplateTranslation{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteEmailTemplate removes a template with its translations and status mappings, which fall back to the default template
+func DeleteEmailTemplate(db *gorm.DB, template *EmailTemplate) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(template).Association("Statuses").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Where("template_id = ?", template.ID).Delete(&EmailTemplateTranslation{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(template).Error
+	})
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"errors"
 	"strings"
 	"time"
 
@@ -27,27 +29,55 @@ type Tag struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	Name      string    `json:"name" gorm:"not null;uniqueIndex"`
 	Color     string    `json:"color" gorm:"not null;default:#3B82F6"` // Default blue color
+	GroupID   *uint     `json:"group_id" gorm:"index"`
+	ParentID  *uint     `json:"parent_id" gorm:"index"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	Events    []Event   `json:"-" gorm:"many2many:event_tags;"`
+	Group     *TagGroup `json:"-" gorm:"foreignKey:GroupID"`
 }
 
 type Event struct {
-	ID           uint              `json:"id" gorm:"primaryKey"`
-	Title        string            `json:"title" gorm:"not null"`
-	Slug         string            `json:"slug" gorm:"uniqueIndex"`
-	Tags         []Tag             `json:"tags" gorm:"many2many:event_tags;"`
-	Media        string            `json:"media"` // JSON string of array
-	Status       EventStatus       `json:"status" gorm:"not null"`
-	Date         string            `json:"date"`
-	Votes        int               `json:"votes" gorm:"default:0"`
-	Content      string            `json:"content"` // Markdown content
-	CreatedAt    time.Time         `json:"created_at"`
-	UpdatedAt    time.Time         `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt    `json:"-" gorm:"index"`
-	IsPublic     bool              `json:"is_public" gorm:"default:true"`      // Controls if event appears on public page
-	HasPublicUrl bool              `json:"has_public_url" gorm:"default:true"` // Controls if event has individual public URL
-	Publication  *EventPublication `json:"publication,omitempty" gorm:"foreignKey:EventID"`
+	ID           uint               `json:"id" gorm:"primaryKey"`
+	Title        string             `json:"title" gorm:"not null"`
+	Slug         string             `json:"slug" gorm:"uniqueIndex"`
+	Tags         []Tag              `json:"tags" gorm:"many2many:event_tags;"`
+	Media        string             `json:"media"` // JSON string of array
+	StatusID     uint               `json:"status_id" gorm:"index"`
+	Status       EventStatus        `json:"status" gorm:"-"`       // Display name of the status, kept for API compatibility
+	Date         EventDate          `json:"date" gorm:"embedded"`  // e.g. "2026-03-14", "2026-03", "2026-Q3" or "2026"
+	LegacyDate   string             `json:"legacy_date,omitempty"` // Free-form date that couldn't be migrated, until a date is set
+	Votes        int                `json:"votes" gorm:"default:0"`
+	Content      string             `json:"content"` // Markdown content
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt     `json:"-" gorm:"index"`
+	IsPublic     bool               `json:"is_public" gorm:"default:true"`      // Controls if event appears on public page
+	HasPublicUrl bool               `json:"has_public_url" gorm:"default:true"` // Controls if event has individual public URL
+	Publication  *EventPublication  `json:"publication,omitempty" gorm:"foreignKey:EventID"`
+	Translations []EventTranslation `json:"translations,omitempty" gorm:"foreignKey:EventID"`
+
+	// Public responses: the language of Title and Content, and the languages the event is available in
+	Locale           string   `json:"locale,omitempty" gorm:"-"`
+	AvailableLocales []string `json:"available_locales,omitempty" gorm:"-"`
+
+	StatusDefinition *EventStatusDefinition `json:"-" gorm:"foreignKey:StatusID"`
+}
+
+// AfterFind fills the status name from the status definition.
+// Queries that expose the status must Preload("StatusDefinition").
+func (e *Event) AfterFind(tx *gorm.DB) error {
+	if e.StatusDefinition != nil {
+		e.Status = EventStatus(e.StatusDefinition.DisplayName)
+	}
+	return nil
+}
+
+// SetStatus points the event at a status definition
+func (e *Event) SetStatus(def *EventStatusDefinition) {
+	e.StatusID = def.ID
+	e.StatusDefinition = def
+	e.Status = EventStatus(def.DisplayName)
 }
 
 type EventPublication struct {
@@ -76,31 +106,124 @@ type EventEmailHistory struct {
 }
 
 type CreateTagRequest struct {
-	Name  string `json:"name" binding:"required"`
-	Color string `json:"color" binding:"required"`
+	Name     string `json:"name" binding:"required"`
+	Color    string `json:"color" binding:"required"`
+	GroupID  *uint  `json:"group_id"`
+	ParentID *uint  `json:"parent_id"`
 }
 
 type UpdateTagRequest struct {
-	Name  *string `json:"name"`
-	Color *string `json:"color"`
+	Name     *string `json:"name"`
+	Color    *string `json:"color"`
+	GroupID  *uint   `json:"group_id"`  // 0 removes the tag from its group
+	ParentID *uint   `json:"parent_id"` // 0 makes the tag a root tag
 }
 
 type CreateEventRequest struct {
-	Title   string      `json:"title" binding:"required"`
-	TagIDs  []uint      `json:"tag_ids"` // Array of tag IDs instead of strings
-	Media   []string    `json:"media"`
-	Status  EventStatus `json:"status" binding:"required"`
-	Date    string      `json:"date"`
-	Content string      `json:"content"`
+	Title    string    `json:"title" binding:"required"`
+	TagIDs   []uint    `json:"tag_ids"` // Array of tag IDs instead of strings
+	Media    []string  `json:"media"`
+	Status   StatusRef `json:"status"`    // Status ID or display name
+	StatusID *uint     `json:"status_id"` // Takes precedence over status
+	Date     EventDate `json:"date"`      // ISO date, month, quarter (2026-Q3) or year
+	Content  string    `json:"content"`
 }
 
 type UpdateEventRequest struct {
-	Title   *string      `json:"title"`
-	TagIDs  *[]uint      `json:"tag_ids"` // Pointer to array of tag IDs to distinguish nil from empty
-	Media   []string     `json:"media"`
-	Status  *EventStatus `json:"status"`
-	Date    *string      `json:"date"`
-	Content *string      `json:"content"`
+	Title    *string    `json:"title"`
+	TagIDs   *[]uint    `json:"tag_ids"` // Pointer to array of tag IDs to distinguish nil from empty
+	Media    []string   `json:"media"`
+	Status   *StatusRef `json:"status"`    // Status ID or display name
+	StatusID *uint      `json:"status_id"` // Takes precedence over status
+	Date     *EventDate `json:"date"`      // ISO date, month, quarter (2026-Q3) or year; "" clears it
+	Content  *string    `json:"content"`
+
+	OverrideWorkflow bool `json:"override_workflow"` // Admins may bypass the status workflow
+}
+
+type BulkUpdateEventStatusRequest struct {
+	EventIDs         []uint     `json:"event_ids" binding:"required"`
+	Status           *StatusRef `json:"status"`    // Status ID or display name
+	StatusID         *uint      `json:"status_id"` // Takes precedence over status
+	OverrideWorkflow bool       `json:"override_workflow"`
+}
+
+// StatusRef references a status definition by ID (JSON number) or display name (JSON string)
+type StatusRef struct {
+	ID   uint
+	Name string
+}
+
+// ErrStatusNotFound is returned when a referenced status definition doesn't exist
+var ErrStatusNotFound = errors.New("status not found")
+
+func (r *StatusRef) UnmarshalJSON(data []byte) error {
+	var id uint
+	if err := json.Unmarshal(data, &id); err == nil {
+		*r = StatusRef{ID: id}
+		return nil
+	}
+
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return errors.New("status must be a status ID or name")
+	}
+	*r = StatusRef{Name: strings.TrimSpace(name)}
+	return nil
+}
+
+func (r StatusRef) MarshalJSON() ([]byte, error) {
+	if r.ID != 0 {
+		return json.Marshal(r.ID)
+	}
+	return json.Marshal(r.Name)
+}
+
+// IsZero reports whether the reference is empty
+func (r StatusRef) IsZero() bool {
+	return r.ID == 0 && r.Name == ""
+}
+
+// StatusRefFromRequest combines the status and status_id request fields, status_id taking precedence
+func StatusRefFromRequest(status *StatusRef, statusID *uint) *StatusRef {
+	if statusID != nil {
+		return &StatusRef{ID: *statusID}
+	}
+	if status != nil && !status.IsZero() {
+		return status
+	}
+	return nil
+}
+
+// ResolveStatusRef returns the referenced status definition.
+// Unknown IDs return ErrStatusNotFound; unknown names are created when createMissing is set.
+func ResolveStatusRef(db *gorm.DB, ref StatusRef, createMissing bool) (*EventStatusDefinition, error) {
+	if ref.ID != 0 {
+		var def EventStatusDefinition
+		if err := db.Limit(1).Find(&def, ref.ID).Error; err != nil {
+			return nil, err
+		}
+		if def.ID == 0 {
+			return nil, ErrStatusNotFound
+		}
+		return &def, nil
+	}
+
+	if ref.Name == "" {
+		return nil, ErrStatusNotFound
+	}
+	if createMissing {
+		return GetOrCreateStatusDefinition(db, ref.Name)
+	}
+
+	var def EventStatusDefinition
+	if err := db.Where("LOWER(display_name) = ?", strings.ToLower(ref.Name)).Limit(1).Find(&def).Error; err != nil {
+		return nil, err
+	}
+	if def.ID == 0 {
+		return nil, ErrStatusNotFound
+	}
+	return &def, nil
 }
 
 type VoteRequest struct {
@@ -113,9 +236,12 @@ type EventPublishRequest struct {
 }
 
 type EventNewsletterRequest struct {
-	Subject  string `json:"subject" binding:"required"`
-	Content  string `json:"content" binding:"required"`
-	Template string `json:"template" binding:"required"`
+	Subject    string `json:"subject" binding:"required"`
+	Content    string `json:"content" binding:"required"`
+	Template   string `json:"template"`    // Set from the template used
+	TemplateID *uint  `json:"template_id"` // Defaults to the template mapped to the event's status
+	// Versions for subscribers of other languages, by locale; the others get Subject and Content
+	Localized map[string]NewsletterContent `json:"localized"`
 }
 
 // Requests for status definition management (admin CRUD)
@@ -162,19 +288,3 @@ func GetOrCreateStatusDefinition(db *gorm.DB, displayName string) (*EventStatusD
 	}
 	return &def, nil
 }
-
-// SeedStatusDefinitions initializes any legacy statuses found in existing events
-func SeedStatusDefinitions(db *gorm.DB) error {
-	// Detect distinct existing event statuses and seed definitions for them
-	var rawStatuses []string
-	if err := db.Model(&Event{}).Distinct().Pluck("status", &rawStatuses).Error; err == nil {
-		for _, rs := range rawStatuses {
-			if rs == "" {
-				continue
-			}
-			_, _ = GetOrCreateStatusDefinition(db, rs) // ignore errors to continue seeding
-		}
-	}
-
-	return nil
-}
@@ -0,0 +1,302 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DatePrecision tells how much of an event date is meaningful
+type DatePrecision string
+
+const (
+	DatePrecisionDay     DatePrecision = "day"
+	DatePrecisionMonth   DatePrecision = "month"
+	DatePrecisionQuarter DatePrecision = "quarter"
+	DatePrecisionYear    DatePrecision = "year"
+)
+
+// EventDate is a possibly fuzzy event date such as "2026-03-14", "2026-03", "2026-Q3" or "2026".
+// At holds the start of the period in UTC, nil when the event has no date.
+// It is serialized in JSON as that notation so the API keeps a single "date" string.
+type EventDate struct {
+	At        *time.Time    `gorm:"column:date_at;index"`
+	Precision DatePrecision `gorm:"column:date_precision"`
+}
+
+var (
+	yearPattern        = regexp.MustCompile(`^(\d{4})$`)
+	monthPattern       = regexp.MustCompile(`^(\d{4})-(\d{1,2})$`)
+	quarterPattern     = regexp.MustCompile(`(?i)^(?:(\d{4})[\s-]*Q([1-4])|Q([1-4])[\s-]*(\d{4}))$`)
+	numericDatePattern = regexp.MustCompile(`^(\d{1,2})[/.-](\d{1,2})[/.-](\d{4})$`)
+)
+
+// ParseEventDate parses an ISO date (2026-03-14 or RFC 3339), a month (2026-03),
+// a quarter (2026-Q3, Q3 2026) or a year (2026). An empty string means no date.
+func ParseEventDate(value string) (EventDate, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return EventDate{}, nil
+	}
+
+	if match := yearPattern.FindStringSubmatch(value); match != nil {
+		year, _ := strconv.Atoi(match[1])
+		return newEventDate(year, 1, 1, DatePrecisionYear), nil
+	}
+
+	if match := monthPattern.FindStringSubmatch(value); match != nil {
+		year, _ := strconv.Atoi(match[1])
+		month, _ := strconv.Atoi(match[2])
+		if month < 1 || month > 12 {
+			return EventDate{}, fmt.Errorf("invalid month in date %q", value)
+		}
+		return newEventDate(year, time.Month(month), 1, DatePrecisionMonth), nil
+	}
+
+	if match := quarterPattern.FindStringSubmatch(value); match != nil {
+		yearText, quarterText := match[1], match[2]
+		if yearText == "" {
+			yearText, quarterText = match[4], match[3]
+		}
+		year, _ := strconv.Atoi(yearText)
+		quarter, _ := strconv.Atoi(quarterText)
+		return newEventDate(year, time.Month((quarter-1)*3+1), 1, DatePrecisionQuarter), nil
+	}
+
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return newEventDate(t.Year(), t.Month(), t.Day(), DatePrecisionDay), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return newEventDate(t.Year(), t.Month(), t.Day(), DatePrecisionDay), nil
+	}
+
+	return EventDate{}, fmt.Errorf("invalid date %q: use YYYY-MM-DD, YYYY-MM, YYYY-Qn or YYYY", value)
+}
+
+// ParseLegacyEventDate also understands the free-form English dates stored before
+// dates were structured, such as "March 2026", "14 Mar. 2026" or "Mar 14, 2026".
+// Numeric dates such as 03/04/2026 are rejected unless the day is over 12, since the order is unknown.
+func ParseLegacyEventDate(value string) (EventDate, error) {
+	if date, err := ParseEventDate(value); err == nil {
+		return date, nil
+	}
+
+	// Day and month order can't be told apart unless one of them is over 12
+	if match := numericDatePattern.FindStringSubmatch(strings.TrimSpace(value)); match != nil {
+		first, _ := strconv.Atoi(match[1])
+		second, _ := strconv.Atoi(match[2])
+		year, _ := strconv.Atoi(match[3])
+		day, month := first, second
+		if first <= 12 && second <= 12 && first != second {
+			return EventDate{}, fmt.Errorf("ambiguous date %q: day and month order is unknown", value)
+		}
+		if second > 12 {
+			day, month = second, first
+		}
+		if month < 1 || month > 12 || day < 1 || day > daysIn(time.Month(month), year) {
+			return EventDate{}, fmt.Errorf("invalid date %q", value)
+		}
+		return newEventDate(year, time.Month(month), day, DatePrecisionDay), nil
+	}
+
+	cleaned := strings.Join(strings.Fields(strings.ReplaceAll(value, ".", " ")), " ")
+	for _, layout := range []string{"2 Jan 2006", "2 January 2006", "Jan 2 2006", "January 2 2006", "Jan 2, 2006", "January 2, 2006", "2006/01/02"} {
+		if t, err := time.Parse(layout, cleaned); err == nil {
+			return newEventDate(t.Year(), t.Month(), t.Day(), DatePrecisionDay), nil
+		}
+	}
+	for _, layout := range []string{"Jan 2006", "January 2006", "01/2006"} {
+		if t, err := time.Parse(layout, cleaned); err == nil {
+			return newEventDate(t.Year(), t.Month(), 1, DatePrecisionMonth), nil
+		}
+	}
+
+	return EventDate{}, fmt.Errorf("unrecognized date %q", value)
+}
+
+func daysIn(month time.Month, year int) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func newEventDate(year int, month time.Month, day int, precision DatePrecision) EventDate {
+	at := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return EventDate{At: &at, Precision: precision}
+}
+
+// IsZero reports whether the event has no date
+func (d EventDate) IsZero() bool {
+	return d.At == nil
+}
+
+// Quarter returns the quarter (1-4) the date falls in
+func (d EventDate) Quarter() int {
+	if d.At == nil {
+		return 0
+	}
+	return (int(d.At.Month())-1)/3 + 1
+}
+
+// End returns the exclusive end of the period covered by the date
+func (d EventDate) End() time.Time {
+	if d.At == nil {
+		return time.Time{}
+	}
+	switch d.Precision {
+	case DatePrecisionYear:
+		return d.At.AddDate(1, 0, 0)
+	case DatePrecisionQuarter:
+		return d.At.AddDate(0, 3, 0)
+	case DatePrecisionMonth:
+		return d.At.AddDate(0, 1, 0)
+	default:
+		return d.At.AddDate(0, 0, 1)
+	}
+}
+
+// String returns the canonical notation of the date, empty when there is none
+func (d EventDate) String() string {
+	if d.At == nil {
+		return ""
+	}
+	switch d.Precision {
+	case DatePrecisionYear:
+		return d.At.Format("2006")
+	case DatePrecisionQuarter:
+		return fmt.Sprintf("%d-Q%d", d.At.Year(), d.Quarter())
+	case DatePrecisionMonth:
+		return d.At.Format("2006-01")
+	default:
+		return d.At.Format("2006-01-02")
+	}
+}
+
+// MarshalJSON encodes the date as its canonical notation
+func (d EventDate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON accepts any notation understood by ParseEventDate
+func (d *EventDate) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*d = EventDate{}
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("date must be a string")
+	}
+	parsed, err := ParseEventDate(value)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// dateLocale holds the words needed to format dates in a language
+type dateLocale struct {
+	months        [12]string
+	shortMonths   [12]string // Empty to use the full month names for day dates
+	dayFormat     string     // %[1]d day, %[2]s month, %[3]d year
+	monthFormat   string     // %[1]s month, %[2]d year
+	quarterFormat string     // %[1]d quarter, %[2]d year
+}
+
+var dateLocales = map[string]dateLocale{
+	"en": {
+		months:        [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		shortMonths:   [12]string{"Jan.", "Feb.", "Mar.", "Apr.", "May", "Jun.", "Jul.", "Aug.", "Sep.", "Oct.", "Nov.", "Dec."},
+		dayFormat:     "%[1]d %[2]s %[3]d",
+		monthFormat:   "%[1]s %[2]d",
+		quarterFormat: "Q%[1]d %[2]d",
+	},
+	"fr": {
+		months:        [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		dayFormat:     "%[1]d %[2]s %[3]d",
+		monthFormat:   "%[1]s %[2]d",
+		quarterFormat: "T%[1]d %[2]d",
+	},
+	"de": {
+		months:        [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		dayFormat:     "%[1]d. %[2]s %[3]d",
+		monthFormat:   "%[1]s %[2]d",
+		quarterFormat: "Q%[1]d %[2]d",
+	},
+	"es": {
+		months:        [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		dayFormat:     "%[1]d de %[2]s de %[3]d",
+		monthFormat:   "%[1]s de %[2]d",
+		quarterFormat: "T%[1]d %[2]d",
+	},
+	"it": {
+		months:        [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		dayFormat:     "%[1]d %[2]s %[3]d",
+		monthFormat:   "%[1]s %[2]d",
+		quarterFormat: "T%[1]d %[2]d",
+	},
+	"pt": {
+		months:        [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		dayFormat:     "%[1]d de %[2]s de %[3]d",
+		monthFormat:   "%[1]s de %[2]d",
+		quarterFormat: "T%[1]d %[2]d",
+	},
+	"nl": {
+		months:        [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+		dayFormat:     "%[1]d %[2]s %[3]d",
+		monthFormat:   "%[1]s %[2]d",
+		quarterFormat: "K%[1]d %[2]d",
+	},
+}
+
+// DefaultLocale is used when no locale is configured or the locale isn't supported
+const DefaultLocale = "en"
+
+// SupportedDateLocales returns the languages dates can be formatted in
+func SupportedDateLocales() []string {
+	return sortedKeys(dateLocales)
+}
+
+// NormalizeLocale reduces a locale such as "fr-CA" or "de_DE" to a supported language, or "" if unsupported
+func NormalizeLocale(locale string) string {
+	language := strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(language, "-_"); i >= 0 {
+		language = language[:i]
+	}
+	if _, ok := dateLocales[language]; ok {
+		return language
+	}
+	return ""
+}
+
+// Format renders the date for humans in the given locale, e.g. "14 Mar. 2026", "March 2026" or "Q3 2026"
+func (d EventDate) Format(locale string) string {
+	if d.At == nil {
+		return ""
+	}
+
+	language := NormalizeLocale(locale)
+	if language == "" {
+		language = DefaultLocale
+	}
+	words := dateLocales[language]
+	year := d.At.Year()
+	monthIndex := int(d.At.Month()) - 1
+
+	switch d.Precision {
+	case DatePrecisionYear:
+		return strconv.Itoa(year)
+	case DatePrecisionQuarter:
+		return fmt.Sprintf(words.quarterFormat, d.Quarter(), year)
+	case DatePrecisionMonth:
+		return fmt.Sprintf(words.monthFormat, words.months[monthIndex], year)
+	default:
+		month := words.months[monthIndex]
+		if words.shortMonths[monthIndex] != "" {
+			month = words.shortMonths[monthIndex]
+		}
+		return fmt.Sprintf(words.dayFormat, d.At.Day(), month, year)
+	}
+}
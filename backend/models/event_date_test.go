@@ -0,0 +1,45 @@
+package models
+
+import "testing"
+
+func TestParseLegacyEventDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string // Canonical notation, empty when the date must be rejected
+	}{
+		{"2026-03-14", "2026-03-14"},
+		{"2026-03", "2026-03"},
+		{"Q3 2026", "2026-Q3"},
+		{"2026", "2026"},
+		{"March 2026", "2026-03"},
+		{"14 Mar. 2026", "2026-03-14"},
+		{"Mar 14, 2026", "2026-03-14"},
+		{"2026/03/14", "2026-03-14"},
+		{"03/2026", "2026-03"},
+		{"25/12/2026", "2026-12-25"},
+		{"12/25/2026", "2026-12-25"},
+		{"04/04/2026", "2026-04-04"},
+		{"03/04/2026", ""},
+		{"31/02/2026", ""},
+		{"13/13/2026", ""},
+		{"soon", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		date, err := ParseLegacyEventDate(tt.value)
+		if tt.want == "" {
+			if err == nil && !date.IsZero() {
+				t.Errorf("ParseLegacyEventDate(%q) = %q, want an error", tt.value, date.String())
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseLegacyEventDate(%q) returned %v", tt.value, err)
+			continue
+		}
+		if got := date.String(); got != tt.want {
+			t.Errorf("ParseLegacyEventDate(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
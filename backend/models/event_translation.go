@@ -0,0 +1,96 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EventTranslation is an event's title and content in another language
+type EventTranslation struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	EventID   uint      `json:"event_id" gorm:"not null;uniqueIndex:idx_event_translation"`
+	Locale    string    `json:"locale" gorm:"not null;uniqueIndex:idx_event_translation"`
+	Title     string    `json:"title" gorm:"not null"`
+	Content   string    `json:"content"` // Empty to keep the default content
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SaveEventTranslationRequest creates or updates an event translation
+type SaveEventTranslationRequest struct {
+	Title   string `json:"title" binding:"required"`
+	Content string `json:"content"`
+}
+
+// ContentLocale returns the language events are written in before translation, the project locale
+func ContentLocale(db *gorm.DB) string {
+	if settings, err := GetOrCreateSettings(db); err == nil {
+		if locale := NormalizeLocale(settings.Locale); locale != "" {
+			return locale
+		}
+	}
+	return DefaultLocale
+}
+
+// Localize switches the event's title and content to the first requested locale it's available in,
+// or leaves them in the default locale. A translation without content keeps the default content.
+// Translations must be loaded; they are dropped from the event.
+func (e *Event) Localize(requested []string, defaultLocale string) {
+	translations := make(map[string]*EventTranslation, len(e.Translations))
+	available := []string{defaultLocale}
+	for i := range e.Translations {
+		translation := &e.Translations[i]
+		if translation.Locale == defaultLocale {
+			continue
+		}
+		translations[translation.Locale] = translation
+		available = append(available, translation.Locale)
+	}
+	sort.Strings(available[1:])
+
+	e.Locale = defaultLocale
+	e.AvailableLocales = available
+	e.Translations = nil
+
+	for _, locale := range requested {
+		if locale == defaultLocale {
+			return
+		}
+		if translation, ok := translations[locale]; ok {
+			e.Title = translation.Title
+			if translation.Content != "" {
+				e.Content = translation.Content
+			}
+			e.Locale = locale
+			return
+		}
+	}
+}
+
+// GetEventTranslations returns an event's translations ordered by locale
+func GetEventTranslations(db *gorm.DB, eventID uint) ([]EventTranslation, error) {
+	translations := []EventTranslation{}
+	err := db.Where("event_id = ?", eventID).Order("locale ASC").Find(&translations).Error
+	return translations, err
+}
+
+// SaveEventTranslation creates or replaces an event's translation in a language
+func SaveEventTranslation(db *gorm.DB, translation *EventTranslation) error {
+	var existing EventTranslation
+	err := db.Where("event_id = ? AND locale = ?", translation.EventID, translation.Locale).First(&existing).Error
+	if err == nil {
+		translation.ID = existing.ID
+		translation.CreatedAt = existing.CreatedAt
+	} else if err != gorm.ErrRecordNotFound {
+		return err
+	}
+	return db.Save(translation).Error
+}
+
+// DeleteEventTranslation removes an event's translation in a language
+func DeleteEventTranslation(db *gorm.DB, eventID uint, locale string) (bool, error) {
+	result := db.Where("event_id = ? AND locale = ?", eventID, locale).Delete(&EventTranslation{})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// HashKey is a random key for hashing personal data. Unlike JWT_SECRET it is never shared or
+// set by hand, so hashes of small spaces such as IP addresses can't be reversed by guessing it.
+type HashKey struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Purpose   string    `json:"purpose" gorm:"not null;uniqueIndex"`
+	Secret    string    `json:"-" gorm:"column:secret;serializer:secret"` // base64; encrypted at rest
+	CreatedAt time.Time `json:"created_at"`
+}
+
+var (
+	hashKeysMutex sync.Mutex
+	hashKeys      = map[string][]byte{}
+)
+
+// GetHashKey returns the key of a purpose, generating it on first use
+func GetHashKey(db *gorm.DB, purpose string) ([]byte, error) {
+	hashKeysMutex.Lock()
+	defer hashKeysMutex.Unlock()
+
+	if key, ok := hashKeys[purpose]; ok {
+		return key, nil
+	}
+
+	db = db.Session(&gorm.Session{NewDB: true})
+	var hashKey HashKey
+	if err := db.Where("purpose = ?", purpose).Limit(1).Find(&hashKey).Error; err != nil {
+		return nil, err
+	}
+	// A key created inside a transaction may be rolled back, so only keys read back are cached
+	stored := hashKey.ID != 0
+	if !stored {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		hashKey = HashKey{Purpose: purpose, Secret: base64.StdEncoding.EncodeToString(key)}
+		if err := db.Create(&hashKey).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := base64.StdEncoding.DecodeString(hashKey.Secret)
+	if err != nil {
+		return nil, err
+	}
+	if stored {
+		hashKeys[purpose] = key
+	}
+	return key, nil
+}
+
+// KeyedHash returns the URL-safe HMAC-SHA256 of a value under the key of a purpose
+func KeyedHash(db *gorm.DB, purpose, value string) (string, error) {
+	key, err := GetHashKey(db, purpose)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AcceptedLocales returns the supported languages of an Accept-Language header, most preferred first
+func AcceptedLocales(acceptLanguage string) []string {
+	type candidate struct {
+		language string
+		quality  float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if language := NormalizeLocale(tag); language != "" && quality > 0 {
+			candidates = append(candidates, candidate{language, quality})
+		}
+	}
+
+	// Keep the header order between languages of equal quality
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+
+	locales := []string{}
+	seen := map[string]bool{}
+	for _, candidate := range candidates {
+		if !seen[candidate.language] {
+			seen[candidate.language] = true
+			locales = append(locales, candidate.language)
+		}
+	}
+	return locales
+}
+
+// PreferredLocale returns the first supported language of an Accept-Language header, or ""
+func PreferredLocale(acceptLanguage string) string {
+	if locales := AcceptedLocales(acceptLanguage); len(locales) > 0 {
+		return locales[0]
+	}
+	return ""
+}
+
+// LocaleFallbacks returns the languages to try for a recipient, most specific first:
+// their preferred language, then the project's
+func LocaleFallbacks(preferred, projectLocale string) []string {
+	locales := []string{}
+	for _, locale := range []string{preferred, projectLocale} {
+		locale = NormalizeLocale(locale)
+		if locale == "" {
+			continue
+		}
+		if len(locales) == 0 || locales[0] != locale {
+			locales = append(locales, locale)
+		}
+	}
+	return locales
+}
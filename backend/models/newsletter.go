@@ -1,6 +1,10 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"shipshipship/constants"
@@ -11,15 +15,33 @@ import (
 type NewsletterSubscriber struct {
 	ID           uint           `json:"id" gorm:"primaryKey"`
 	Email        string         `json:"email" gorm:"uniqueIndex;not null"`
-	IsActive     bool           `json:"is_active" gorm:"default:true"`
+	IsActive     bool           `json:"is_active" gorm:"default:true"` // False while an imported subscriber has not confirmed
 	SubscribedAt time.Time      `json:"subscribed_at"`
+	AllTopics    bool           `json:"all_topics" gorm:"default:true"` // Receive every newsletter regardless of its tags
+	TopicTags    []Tag          `json:"topic_tags" gorm:"many2many:subscriber_topic_tags;"`
+	TopicGroups  []TagGroup     `json:"topic_groups" gorm:"many2many:subscriber_topic_groups;"`
+	Frequency    string         `json:"frequency" gorm:"not null;default:'instant'"` // instant, weekly, monthly
+	LastDigestAt *time.Time     `json:"last_digest_at"`                              // End of the last period covered by a digest
+	Language     string         `json:"language" gorm:"not null;default:''"`         // Preferred email language; empty for the project locale
+	Source       string         `json:"source" gorm:"not null;default:'form'"`       // form, import, or the source given in an imported file
+	ConsentAt    *time.Time     `json:"consent_at"`                                  // When the subscriber agreed to receive emails
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// Where a subscriber came from
+const (
+	SubscriberSourceForm   = "form"
+	SubscriberSourceImport = "import"
+)
+
 type SubscribeRequest struct {
-	Email string `json:"email" binding:"required,email"`
+	Email       string `json:"email" binding:"required,email"`
+	TagIDs      []uint `json:"tag_ids"`       // Topics to follow; none means all topics
+	TagGroupIDs []uint `json:"tag_group_ids"` // Topic groups to follow
+	Frequency   string `json:"frequency"`     // instant (default), weekly or monthly
+	Language    string `json:"language"`      // Preferred email language; taken from Accept-Language when empty
 }
 
 type UnsubscribeRequest struct {
@@ -29,7 +51,7 @@ type UnsubscribeRequest struct {
 // GetActiveSubscriberCount returns the number of active newsletter subscribers
 func GetActiveSubscriberCount(db *gorm.DB) (int64, error) {
 	var count int64
-	err := db.Model(&NewsletterSubscriber{}).Count(&count).Error
+	err := db.Model(&NewsletterSubscriber{}).Where("is_active = ?", true).Count(&count).Error
 	return count, err
 }
 
@@ -52,10 +74,15 @@ func Subscribe(db *gorm.DB, email string) (*NewsletterSubscriber, error) {
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			// Create new subscriber
+			now := time.Now()
 			subscriber = NewsletterSubscriber{
 				Email:        email,
 				IsActive:     true,
-				SubscribedAt: time.Now(),
+				SubscribedAt: now,
+				AllTopics:    true,
+				Frequency:    DigestFrequencyInstant,
+				Source:       SubscriberSourceForm,
+				ConsentAt:    &now,
 			}
 			err = db.Create(&subscriber).Error
 			if err != nil {
@@ -68,9 +95,12 @@ func Subscribe(db *gorm.DB, email string) (*NewsletterSubscriber, error) {
 
 	// If record was soft-deleted, restore it
 	if subscriber.DeletedAt.Valid {
+		now := time.Now()
 		subscriber.DeletedAt = gorm.DeletedAt{}
 		subscriber.IsActive = true
-		subscriber.SubscribedAt = time.Now()
+		subscriber.SubscribedAt = now
+		subscriber.Source = SubscriberSourceForm
+		subscriber.ConsentAt = &now
 		err = db.Unscoped().Save(&subscriber).Error
 		if err != nil {
 			return nil, err
@@ -78,10 +108,87 @@ func Subscribe(db *gorm.DB, email string) (*NewsletterSubscriber, error) {
 		return &subscriber, nil
 	}
 
-	// Already active subscriber
+	// A pending subscriber stays pending: only the signed confirmation link proves consent
 	return &subscriber, nil
 }
 
+// ConfirmSubscriber activates a pending subscriber and records their consent.
+// Only call it once the subscriber followed their signed confirmation link.
+func ConfirmSubscriber(db *gorm.DB, subscriber *NewsletterSubscriber) error {
+	now := time.Now()
+	if err := db.Model(subscriber).Updates(map[string]interface{}{"is_active": true, "consent_at": now}).Error; err != nil {
+		return err
+	}
+	subscriber.IsActive = true
+	subscriber.ConsentAt = &now
+	return nil
+}
+
+// Locale returns the language emails to the subscriber are written in
+func (s *NewsletterSubscriber) Locale(projectLocale string) string {
+	if language := NormalizeLocale(s.Language); language != "" {
+		return language
+	}
+	if language := NormalizeLocale(projectLocale); language != "" {
+		return language
+	}
+	return DefaultLocale
+}
+
+// SetSubscriberLanguage saves a subscriber's preferred email language
+func SetSubscriberLanguage(db *gorm.DB, subscriber *NewsletterSubscriber, language string) error {
+	if language == subscriber.Language {
+		return nil
+	}
+	if err := db.Model(subscriber).Update("language", language).Error; err != nil {
+		return err
+	}
+	subscriber.Language = language
+	return nil
+}
+
+// SubscriberLocales returns the languages emails to the subscribers are written in, without duplicates
+func SubscriberLocales(subscribers []NewsletterSubscriber, projectLocale string) []string {
+	seen := map[string]bool{}
+	languages := []string{}
+	for i := range subscribers {
+		language := subscribers[i].Locale(projectLocale)
+		if !seen[language] {
+			seen[language] = true
+			languages = append(languages, language)
+		}
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// NewsletterContent is the subject and content of a newsletter in one language
+type NewsletterContent struct {
+	Subject string `json:"subject"`
+	Content string `json:"content"`
+}
+
+// ValidateLocalizedContent checks the per-language versions of a newsletter
+func ValidateLocalizedContent(localized map[string]NewsletterContent) error {
+	for locale, content := range localized {
+		if NormalizeLocale(locale) != locale {
+			return fmt.Errorf("unsupported locale: %s", locale)
+		}
+		if strings.TrimSpace(content.Subject) == "" || strings.TrimSpace(content.Content) == "" {
+			return fmt.Errorf("subject and content are required for locale %s", locale)
+		}
+	}
+	return nil
+}
+
+// LocalizedNewsletterContent returns the version of a newsletter for a subscriber, or the default one
+func LocalizedNewsletterContent(subject, content string, localized map[string]NewsletterContent, locale string) (string, string) {
+	if version, ok := localized[locale]; ok {
+		return version.Subject, version.Content
+	}
+	return subject, content
+}
+
 // Unsubscribe removes a newsletter subscription using soft delete
 func Unsubscribe(db *gorm.DB, email string) error {
 	return db.Where("email = ?", email).Delete(&NewsletterSubscriber{}).Error
@@ -134,37 +241,43 @@ func GetSubscribersPaginated(db *gorm.DB, page, limit int) ([]NewsletterSubscrib
 }
 
 type EmailTemplate struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Type      string         `json:"type" gorm:"not null;uniqueIndex"` // newsletter, welcome
-	Subject   string         `json:"subject" gorm:"not null"`
-	Content   string         `json:"content" gorm:"type:text;not null"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID           uint                       `json:"id" gorm:"primaryKey"`
+	Type         string                     `json:"type" gorm:"not null;index"`               // event, welcome, release, digest
+	Name         string                     `json:"name"`                                     // Empty for the default template of a type
+	IsDefault    bool                       `json:"is_default" gorm:"not null;default:false"` // Used when no other template applies
+	Subject      string                     `json:"subject" gorm:"not null"`
+	Content      string                     `json:"content" gorm:"type:text;not null"`
+	TextContent  string                     `json:"text_content" gorm:"type:text"` // Plain-text body; derived from Content when empty
+	Statuses     []EventStatusDefinition    `json:"statuses,omitempty" gorm:"many2many:email_template_statuses;"`
+	Translations []EmailTemplateTranslation `json:"translations,omitempty" gorm:"foreignKey:TemplateID"`
+	CreatedAt    time.Time                  `json:"created_at"`
+	UpdatedAt    time.Time                  `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt             `json:"-" gorm:"index"`
 }
 
-// GetEmailTemplate returns an email template by type
+// GetEmailTemplate returns the default email template of a type
 func GetEmailTemplate(db *gorm.DB, templateType string) (*EmailTemplate, error) {
 	var template EmailTemplate
-	err := db.Where("type = ?", templateType).First(&template).Error
+	err := db.Preload("Translations").Where("type = ? AND is_default = ?", templateType, true).First(&template).Error
 	if err != nil {
 		return nil, err
 	}
 	return &template, nil
 }
 
-// SaveEmailTemplate creates or updates an email template
+// SaveEmailTemplate creates or updates the default email template of a type
 func SaveEmailTemplate(db *gorm.DB, templateType, subject, content string) error {
 	var template EmailTemplate
 
-	err := db.Where("type = ?", templateType).First(&template).Error
+	err := db.Where("type = ? AND is_default = ?", templateType, true).First(&template).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			// Create new template
 			template = EmailTemplate{
-				Type:    templateType,
-				Subject: subject,
-				Content: content,
+				Type:      templateType,
+				IsDefault: true,
+				Subject:   subject,
+				Content:   content,
 			}
 			return db.Create(&template).Error
 		}
@@ -177,10 +290,15 @@ func SaveEmailTemplate(db *gorm.DB, templateType, subject, content string) error
 	return db.Save(&template).Error
 }
 
-// GetAllEmailTemplates returns all email templates
+// SaveEmailTemplateText sets the plain-text body of the default email template of a type
+func SaveEmailTemplateText(db *gorm.DB, templateType, textContent string) error {
+	return db.Model(&EmailTemplate{}).Where("type = ? AND is_default = ?", templateType, true).Update("text_content", textContent).Error
+}
+
+// GetAllEmailTemplates returns the default email template of each type
 func GetAllEmailTemplates(db *gorm.DB) (map[string]EmailTemplate, error) {
 	var templates []EmailTemplate
-	err := db.Find(&templates).Error
+	err := db.Where("is_default = ?", true).Find(&templates).Error
 	if err != nil {
 		return nil, err
 	}
@@ -196,7 +314,7 @@ func GetAllEmailTemplates(db *gorm.DB) (map[string]EmailTemplate, error) {
 // GetActiveNewsletterSubscribers returns all active newsletter subscribers
 func GetActiveNewsletterSubscribers(db *gorm.DB) ([]NewsletterSubscriber, error) {
 	var subscribers []NewsletterSubscriber
-	err := db.Find(&subscribers).Error
+	err := db.Where("is_active = ?", true).Find(&subscribers).Error
 	return subscribers, err
 }
 
@@ -207,7 +325,7 @@ func InitializeDefaultEmailTemplates(db *gorm.DB) error {
 	for _, template := range templates {
 		// Check if template already exists
 		var existingTemplate EmailTemplate
-		err := db.Where("type = ?", template.Type).First(&existingTemplate).Error
+		err := db.Where("type = ? AND is_default = ?", template.Type, true).First(&existingTemplate).Error
 		if err != nil && err == gorm.ErrRecordNotFound {
 			// Template doesn't exist, create it
 			err = SaveEmailTemplate(db, template.Type, template.Subject, template.Content)
@@ -241,12 +359,46 @@ func UpdateEmailTemplatesToMobileFriendly(db *gorm.DB) error {
 type NewsletterAutomationSettings struct {
 	ID              uint           `json:"id" gorm:"primaryKey"`
 	Enabled         bool           `json:"enabled" gorm:"default:false"`
-	TriggerStatuses string         `json:"trigger_statuses" gorm:"type:text"` // JSON array of status strings
+	TriggerStatuses string         `json:"trigger_statuses" gorm:"type:text"` // JSON array of status definition IDs
 	CreatedAt       time.Time      `json:"created_at"`
 	UpdatedAt       time.Time      `json:"updated_at"`
 	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// GetTriggerStatusIDs returns the status definition IDs that trigger automated newsletters
+func (s *NewsletterAutomationSettings) GetTriggerStatusIDs() []uint {
+	ids := []uint{}
+	if s.TriggerStatuses == "" {
+		return ids
+	}
+	if err := json.Unmarshal([]byte(s.TriggerStatuses), &ids); err != nil {
+		return []uint{}
+	}
+	return ids
+}
+
+// HasTriggerStatus reports whether the status definition triggers automated newsletters
+func (s *NewsletterAutomationSettings) HasTriggerStatus(statusID uint) bool {
+	for _, id := range s.GetTriggerStatusIDs() {
+		if id == statusID {
+			return true
+		}
+	}
+	return false
+}
+
+// EncodeTriggerStatusIDs serializes trigger status IDs for storage
+func EncodeTriggerStatusIDs(ids []uint) string {
+	if len(ids) == 0 {
+		return "[]"
+	}
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
 // GetOrCreateAutomationSettings returns the automation settings or creates default ones
 func GetOrCreateAutomationSettings(db *gorm.DB) (*NewsletterAutomationSettings, error) {
 	var settings NewsletterAutomationSettings
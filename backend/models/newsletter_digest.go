@@ -0,0 +1,154 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Newsletter delivery frequencies chosen by subscribers
+const (
+	DigestFrequencyInstant = "instant" // One email per automated newsletter
+	DigestFrequencyWeekly  = "weekly"  // One summary email per week
+	DigestFrequencyMonthly = "monthly" // One summary email per month
+)
+
+// IsValidDigestFrequency reports whether the frequency is supported
+func IsValidDigestFrequency(frequency string) bool {
+	switch frequency {
+	case DigestFrequencyInstant, DigestFrequencyWeekly, DigestFrequencyMonthly:
+		return true
+	}
+	return false
+}
+
+// DigestPeriodStart returns the start of the digest period containing t:
+// Monday 00:00 for weekly digests, the 1st of the month for monthly ones
+func DigestPeriodStart(frequency string, t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if frequency == DigestFrequencyMonthly {
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	offset := (int(day.Weekday()) + 6) % 7 // Days since Monday
+	return day.AddDate(0, 0, -offset)
+}
+
+// PreviousDigestPeriodStart returns the start of the period before the one starting at periodStart
+func PreviousDigestPeriodStart(frequency string, periodStart time.Time) time.Time {
+	if frequency == DigestFrequencyMonthly {
+		return periodStart.AddDate(0, -1, 0)
+	}
+	return periodStart.AddDate(0, 0, -7)
+}
+
+// NextDigestPeriodStart returns the start of the period after the one starting at periodStart
+func NextDigestPeriodStart(frequency string, periodStart time.Time) time.Time {
+	if frequency == DigestFrequencyMonthly {
+		return periodStart.AddDate(0, 1, 0)
+	}
+	return periodStart.AddDate(0, 0, 7)
+}
+
+// DigestItem is an event that reached a trigger status during a digest period
+type DigestItem struct {
+	Event     Event     `json:"event"`
+	Status    string    `json:"status"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// GetDigestItems returns the public events that reached one of the statuses in [from, to),
+// most recent first. An event reaching several statuses appears once, with its latest one.
+func GetDigestItems(db *gorm.DB, statusIDs []uint, from, to time.Time) ([]DigestItem, error) {
+	items := []DigestItem{}
+	if len(statusIDs) == 0 {
+		return items, nil
+	}
+
+	var changes []EventStatusChange
+	if err := db.Where("to_status_id IN ? AND changed_at >= ? AND changed_at < ?", statusIDs, from, to).
+		Order("changed_at DESC, id DESC").Find(&changes).Error; err != nil {
+		return nil, err
+	}
+
+	latest := make(map[uint]EventStatusChange)
+	eventIDs := []uint{}
+	for _, change := range changes {
+		if _, seen := latest[change.EventID]; seen {
+			continue
+		}
+		latest[change.EventID] = change
+		eventIDs = append(eventIDs, change.EventID)
+	}
+	if len(eventIDs) == 0 {
+		return items, nil
+	}
+
+	var events []Event
+	if err := db.Preload("Tags").Preload("StatusDefinition").Where("id IN ? AND is_public = ?", eventIDs, true).Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	for _, event := range events {
+		change := latest[event.ID]
+		items = append(items, DigestItem{Event: event, Status: change.ToStatus, ChangedAt: change.ChangedAt})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].ChangedAt.After(items[j].ChangedAt)
+	})
+	return items, nil
+}
+
+// FilterDigestItems keeps the items carrying topics the subscriber follows
+func FilterDigestItems(index *TopicIndex, subscriber *NewsletterSubscriber, items []DigestItem) []DigestItem {
+	filtered := []DigestItem{}
+	for _, item := range items {
+		tagIDs := make([]uint, len(item.Event.Tags))
+		for i, tag := range item.Event.Tags {
+			tagIDs[i] = tag.ID
+		}
+		if index.Follows(subscriber, tagIDs) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// GetDigestSubscribersDue returns the subscribers of a frequency whose last digest predates periodStart
+func GetDigestSubscribersDue(db *gorm.DB, frequency string, periodStart time.Time) ([]NewsletterSubscriber, error) {
+	var subscribers []NewsletterSubscriber
+	err := db.Preload("TopicTags").Preload("TopicGroups").
+		Where("is_active = ? AND frequency = ? AND (last_digest_at IS NULL OR last_digest_at < ?)", true, frequency, periodStart).
+		Find(&subscribers).Error
+	return subscribers, err
+}
+
+// MarkDigestSent records that a subscriber's digests are covered up to periodEnd
+func MarkDigestSent(db *gorm.DB, subscriber *NewsletterSubscriber, periodEnd time.Time) error {
+	subscriber.LastDigestAt = &periodEnd
+	return db.Model(subscriber).Update("last_digest_at", periodEnd).Error
+}
+
+// SetSubscriberFrequency changes how often a subscriber receives newsletters
+func SetSubscriberFrequency(db *gorm.DB, subscriber *NewsletterSubscriber, frequency string) error {
+	if frequency == subscriber.Frequency {
+		return nil
+	}
+	updates := map[string]interface{}{"frequency": frequency}
+	if frequency != DigestFrequencyInstant {
+		// The first digest covers the period in progress rather than older history
+		periodStart := DigestPeriodStart(frequency, time.Now())
+		updates["last_digest_at"] = periodStart
+		subscriber.LastDigestAt = &periodStart
+	}
+	if err := db.Model(subscriber).Updates(updates).Error; err != nil {
+		return err
+	}
+	subscriber.Frequency = frequency
+	return nil
+}
+
+// ReceivesInstantNewsletters reports whether automated newsletters go to the subscriber as they happen
+func (s *NewsletterSubscriber) ReceivesInstantNewsletters() bool {
+	return s.Frequency != DigestFrequencyWeekly && s.Frequency != DigestFrequencyMonthly
+}
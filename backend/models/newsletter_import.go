@@ -0,0 +1,414 @@
+package models
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+
+	"shipshipship/utils"
+
+	"gorm.io/gorm"
+)
+
+// confirmationTokenPurpose scopes the signature of subscription confirmation links
+const confirmationTokenPurpose = "newsletter-confirm"
+
+// ConfirmationTokenTTL is how long a confirmation link stays valid
+const ConfirmationTokenTTL = 30 * 24 * time.Hour
+
+const (
+	MaxSubscriberImportRows    = 10000 // Rows accepted in one import file
+	subscriberImportPreviewLen = 20    // Subscribers shown in an import preview
+)
+
+// Outcome of an import row
+const (
+	ImportRowInvalid      = "invalid"      // Rejected by validation
+	ImportRowDuplicate    = "duplicate"    // Same email earlier in the file
+	ImportRowExists       = "exists"       // Already a subscriber
+	ImportRowUnsubscribed = "unsubscribed" // Unsubscribed before, so not added back
+)
+
+// subscriberImportColumns are the columns read from an import file; others are ignored
+var subscriberImportColumns = []string{"email", "language", "frequency", "subscribed_at", "consent_at", "source"}
+
+// subscriberExportColumns are the columns written by WriteSubscribersCSV
+var subscriberExportColumns = []string{"email", "status", "source", "language", "frequency", "subscribed_at", "consent_at"}
+
+var importTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+// SubscriberImportRow reports what happens to one row of an import file
+type SubscriberImportRow struct {
+	Row    int    `json:"row"` // Line in the file, the header being line 1
+	Email  string `json:"email"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// SubscriberImportPlan is the validated content of an import file
+type SubscriberImportPlan struct {
+	RequireConfirmation bool                   `json:"require_confirmation"`
+	ToCreate            int                    `json:"to_create"`
+	Skipped             int                    `json:"skipped"` // Duplicates, existing and unsubscribed addresses
+	Invalid             int                    `json:"invalid"`
+	Preview             []NewsletterSubscriber `json:"preview"` // First subscribers to be created
+	Rows                []SubscriberImportRow  `json:"rows"`    // Rows that won't be created, with the reason
+	subscribers         []NewsletterSubscriber
+}
+
+// SubscriberExportRow is a subscriber as written to an export
+type SubscriberExportRow struct {
+	Email        string     `json:"email"`
+	Status       string     `json:"status"` // active or pending
+	Source       string     `json:"source"`
+	Language     string     `json:"language"`
+	Frequency    string     `json:"frequency"`
+	SubscribedAt time.Time  `json:"subscribed_at"`
+	ConsentAt    *time.Time `json:"consent_at"`
+}
+
+// ConfirmationToken returns the token authorizing a pending subscriber to confirm their subscription
+// until it expires. It fails when JWT_SECRET is unset.
+func ConfirmationToken(email string) (string, error) {
+	return utils.SignExpiringValue(confirmationTokenPurpose, strings.ToLower(strings.TrimSpace(email)), time.Now(), ConfirmationTokenTTL)
+}
+
+// VerifyConfirmationToken checks a token produced by ConfirmationToken that has not expired
+func VerifyConfirmationToken(email, token string) bool {
+	return utils.VerifyExpiringValue(confirmationTokenPurpose, strings.ToLower(strings.TrimSpace(email)), token, time.Now())
+}
+
+// formulaPrefixes start cells that spreadsheets evaluate as formulas. Exported cells starting
+// with one, or with the quote escaping them, are prefixed with a quote.
+const formulaPrefixes = "=+-@\t\r"
+
+// escapeSpreadsheetCell keeps a value from being run as a formula when the export is opened in a spreadsheet
+func escapeSpreadsheetCell(value string) string {
+	if value != "" && strings.ContainsAny(value[:1], formulaPrefixes+"'") {
+		return "'" + value
+	}
+	return value
+}
+
+// unescapeSpreadsheetCell reverses escapeSpreadsheetCell
+func unescapeSpreadsheetCell(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsAny(value[1:2], formulaPrefixes+"'") {
+		return value[1:]
+	}
+	return value
+}
+
+func parseImportTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range importTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid date %q; use YYYY-MM-DD or RFC 3339", value)
+}
+
+// readImportHeader maps column names to their position. A file whose first row holds an
+// email address has no header, and its first column is the email.
+func readImportHeader(record []string) (map[string]int, bool, error) {
+	if len(record) > 0 {
+		record[0] = strings.TrimPrefix(record[0], "\ufeff")
+	}
+	if len(record) > 0 && strings.Contains(record[0], "@") {
+		return map[string]int{"email": 0}, false, nil
+	}
+
+	columns := map[string]int{}
+	for i, name := range record {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for _, known := range subscriberImportColumns {
+			if name == known {
+				if _, seen := columns[name]; !seen {
+					columns[name] = i
+				}
+			}
+		}
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, false, errors.New("the file has no email column")
+	}
+	return columns, true, nil
+}
+
+// parseImportRecord builds the subscriber described by a row
+func parseImportRecord(record []string, columns map[string]int, requireConfirmation bool, now time.Time) (NewsletterSubscriber, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(unescapeSpreadsheetCell(strings.TrimSpace(record[i])))
+		}
+		return ""
+	}
+
+	subscriber := NewsletterSubscriber{
+		Email:        field("email"),
+		IsActive:     !requireConfirmation,
+		SubscribedAt: now,
+		AllTopics:    true,
+		Frequency:    DigestFrequencyInstant,
+		Source:       SubscriberSourceImport,
+	}
+
+	if subscriber.Email == "" {
+		return subscriber, errors.New("email is required")
+	}
+	if address, err := mail.ParseAddress(subscriber.Email); err != nil || address.Address != subscriber.Email {
+		return subscriber, errors.New("invalid email address")
+	}
+
+	if language := field("language"); language != "" {
+		subscriber.Language = NormalizeLocale(language)
+		if subscriber.Language == "" {
+			return subscriber, fmt.Errorf("unsupported language %q", language)
+		}
+	}
+
+	if frequency := strings.ToLower(field("frequency")); frequency != "" {
+		if !IsValidDigestFrequency(frequency) {
+			return subscriber, fmt.Errorf("frequency must be instant, weekly or monthly, not %q", frequency)
+		}
+		subscriber.Frequency = frequency
+	}
+	if !subscriber.ReceivesInstantNewsletters() {
+		// The first digest covers the period in progress rather than older history
+		periodStart := DigestPeriodStart(subscriber.Frequency, now)
+		subscriber.LastDigestAt = &periodStart
+	}
+
+	subscribedAt, err := parseImportTime(field("subscribed_at"))
+	if err != nil {
+		return subscriber, fmt.Errorf("subscribed_at: %v", err)
+	}
+	if subscribedAt != nil {
+		subscriber.SubscribedAt = *subscribedAt
+	}
+
+	// Subscribers asked to confirm give their consent when they do
+	consentAt, err := parseImportTime(field("consent_at"))
+	if err != nil {
+		return subscriber, fmt.Errorf("consent_at: %v", err)
+	}
+	if !requireConfirmation {
+		subscriber.ConsentAt = consentAt
+	}
+
+	if source := strings.ToLower(field("source")); source != "" {
+		if len(source) > 64 {
+			return subscriber, errors.New("source must be at most 64 characters")
+		}
+		subscriber.Source = source
+	}
+	return subscriber, nil
+}
+
+// PlanSubscriberImport reads a CSV file of subscribers and checks each row against the file and the
+// existing subscribers. Addresses are compared without case. Nothing is written.
+func PlanSubscriberImport(db *gorm.DB, r io.Reader, requireConfirmation bool) (*SubscriberImportPlan, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("the file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+	columns, hasHeader, err := readImportHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &SubscriberImportPlan{
+		RequireConfirmation: requireConfirmation,
+		Preview:             []NewsletterSubscriber{},
+		Rows:                []SubscriberImportRow{},
+	}
+	now := time.Now()
+
+	records := [][]string{}
+	if !hasHeader {
+		records = append(records, header)
+	}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %v", err)
+		}
+		records = append(records, record)
+		if len(records) > MaxSubscriberImportRows {
+			return nil, fmt.Errorf("the file has more than %d rows", MaxSubscriberImportRows)
+		}
+	}
+
+	firstRow := 1
+	if hasHeader {
+		firstRow = 2
+	}
+	rowNumbers := map[string]int{} // lowercase email -> row of the candidate
+	candidates := []NewsletterSubscriber{}
+	candidateRows := []int{}
+	for i, record := range records {
+		row := firstRow + i
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		subscriber, err := parseImportRecord(record, columns, requireConfirmation, now)
+		if err != nil {
+			plan.Invalid++
+			plan.Rows = append(plan.Rows, SubscriberImportRow{Row: row, Email: subscriber.Email, Status: ImportRowInvalid, Error: err.Error()})
+			continue
+		}
+
+		key := strings.ToLower(subscriber.Email)
+		if first, seen := rowNumbers[key]; seen {
+			plan.Skipped++
+			plan.Rows = append(plan.Rows, SubscriberImportRow{Row: row, Email: subscriber.Email, Status: ImportRowDuplicate,
+				Error: fmt.Sprintf("same email as row %d", first)})
+			continue
+		}
+		rowNumbers[key] = row
+		candidates = append(candidates, subscriber)
+		candidateRows = append(candidateRows, row)
+	}
+
+	// Look up the candidates among current and unsubscribed subscribers
+	existing := map[string]bool{} // lowercase email -> unsubscribed
+	keys := make([]string, 0, len(rowNumbers))
+	for key := range rowNumbers {
+		keys = append(keys, key)
+	}
+	for start := 0; start < len(keys); start += 500 {
+		end := start + 500
+		if end > len(keys) {
+			end = len(keys)
+		}
+		var found []NewsletterSubscriber
+		if err := db.Unscoped().Select("email", "deleted_at").Where("LOWER(email) IN ?", keys[start:end]).Find(&found).Error; err != nil {
+			return nil, err
+		}
+		for _, subscriber := range found {
+			existing[strings.ToLower(subscriber.Email)] = subscriber.DeletedAt.Valid
+		}
+	}
+
+	for i, subscriber := range candidates {
+		unsubscribed, exists := existing[strings.ToLower(subscriber.Email)]
+		switch {
+		case exists && unsubscribed:
+			plan.Skipped++
+			plan.Rows = append(plan.Rows, SubscriberImportRow{Row: candidateRows[i], Email: subscriber.Email, Status: ImportRowUnsubscribed,
+				Error: "this address unsubscribed and is not added back"})
+		case exists:
+			plan.Skipped++
+			plan.Rows = append(plan.Rows, SubscriberImportRow{Row: candidateRows[i], Email: subscriber.Email, Status: ImportRowExists,
+				Error: "already subscribed"})
+		default:
+			plan.subscribers = append(plan.subscribers, subscriber)
+			if len(plan.Preview) < subscriberImportPreviewLen {
+				plan.Preview = append(plan.Preview, subscriber)
+			}
+		}
+	}
+	sort.Slice(plan.Rows, func(i, j int) bool { return plan.Rows[i].Row < plan.Rows[j].Row })
+	plan.ToCreate = len(plan.subscribers)
+	return plan, nil
+}
+
+// ApplySubscriberImport creates the subscribers of a plan and returns them
+func ApplySubscriberImport(db *gorm.DB, plan *SubscriberImportPlan) ([]NewsletterSubscriber, error) {
+	subscribers := plan.subscribers
+	if len(subscribers) == 0 {
+		return []NewsletterSubscriber{}, nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(&subscribers, 100).Error; err != nil {
+			return err
+		}
+		if !plan.RequireConfirmation {
+			return nil
+		}
+		// is_active defaults to true, so a false value is not inserted
+		ids := make([]uint, len(subscribers))
+		for i := range subscribers {
+			ids[i] = subscribers[i].ID
+		}
+		return tx.Model(&NewsletterSubscriber{}).Where("id IN ?", ids).Update("is_active", false).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subscribers, nil
+}
+
+// ExportSubscribers returns the current subscribers, active and pending, oldest first
+func ExportSubscribers(db *gorm.DB) ([]SubscriberExportRow, error) {
+	var subscribers []NewsletterSubscriber
+	if err := db.Order("subscribed_at ASC, id ASC").Find(&subscribers).Error; err != nil {
+		return nil, err
+	}
+
+	rows := make([]SubscriberExportRow, len(subscribers))
+	for i, subscriber := range subscribers {
+		status := "active"
+		if !subscriber.IsActive {
+			status = "pending"
+		}
+		rows[i] = SubscriberExportRow{
+			Email:        subscriber.Email,
+			Status:       status,
+			Source:       subscriber.Source,
+			Language:     subscriber.Language,
+			Frequency:    subscriber.Frequency,
+			SubscribedAt: subscriber.SubscribedAt,
+			ConsentAt:    subscriber.ConsentAt,
+		}
+	}
+	return rows, nil
+}
+
+// WriteSubscribersCSV writes exported subscribers as CSV that PlanSubscriberImport can read back.
+// Cells a spreadsheet would take for a formula are escaped.
+func WriteSubscribersCSV(w io.Writer, rows []SubscriberExportRow) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(subscriberExportColumns); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		consentAt := ""
+		if row.ConsentAt != nil {
+			consentAt = row.ConsentAt.UTC().Format(time.RFC3339)
+		}
+		record := []string{
+			escapeSpreadsheetCell(row.Email),
+			row.Status,
+			escapeSpreadsheetCell(row.Source),
+			escapeSpreadsheetCell(row.Language),
+			escapeSpreadsheetCell(row.Frequency),
+			row.SubscribedAt.UTC().Format(time.RFC3339),
+			consentAt,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
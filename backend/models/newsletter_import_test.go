@@ -0,0 +1,164 @@
+package models
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newImportTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&TagGroup{}, &Tag{}, &NewsletterSubscriber{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, email := range []string{"existing@example.com", "gone@example.com"} {
+		if err := db.Create(&NewsletterSubscriber{Email: email, SubscribedAt: time.Now(), Frequency: DigestFrequencyInstant}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Unsubscribe(db, "gone@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestPlanSubscriberImport(t *testing.T) {
+	db := newImportTestDB(t)
+
+	tests := []struct {
+		name     string
+		csv      string
+		toCreate int
+		rows     map[int]string // Row number -> status of the rows that won't be created
+	}{
+		{
+			name:     "header with BOM and extra columns",
+			csv:      "\ufeffName,Email,Language,Frequency\nAda,ada@example.com,fr-CA,weekly\nBob,bob@example.com,,\n",
+			toCreate: 2,
+		},
+		{
+			name:     "no header",
+			csv:      "ada@example.com\nbob@example.com\n\n",
+			toCreate: 2,
+		},
+		{
+			name:     "invalid rows",
+			csv:      "email,language,frequency,subscribed_at\nnot-an-email,,,\nada@example.com,xx,,\nbob@example.com,,daily,\ncy@example.com,,,yesterday\n,,,\ndee@example.com,,,2024-05-01\n",
+			toCreate: 1,
+			rows:     map[int]string{2: ImportRowInvalid, 3: ImportRowInvalid, 4: ImportRowInvalid, 5: ImportRowInvalid, 6: ImportRowInvalid},
+		},
+		{
+			name:     "duplicates and existing subscribers",
+			csv:      "email\nada@example.com\nADA@example.com\nExisting@Example.com\ngone@example.com\n",
+			toCreate: 1,
+			rows:     map[int]string{3: ImportRowDuplicate, 4: ImportRowExists, 5: ImportRowUnsubscribed},
+		},
+		{
+			name:     "escaped spreadsheet cells",
+			csv:      "email,source\nada@example.com,'=cmd\n",
+			toCreate: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := PlanSubscriberImport(db, strings.NewReader(tt.csv), false)
+			if err != nil {
+				t.Fatalf("PlanSubscriberImport: %v", err)
+			}
+			if plan.ToCreate != tt.toCreate {
+				t.Errorf("ToCreate = %d, want %d", plan.ToCreate, tt.toCreate)
+			}
+			if len(plan.Rows) != len(tt.rows) {
+				t.Errorf("got %d reported rows, want %d: %+v", len(plan.Rows), len(tt.rows), plan.Rows)
+			}
+			for _, row := range plan.Rows {
+				if want := tt.rows[row.Row]; row.Status != want {
+					t.Errorf("row %d (%s) has status %q, want %q", row.Row, row.Email, row.Status, want)
+				}
+			}
+		})
+	}
+
+	if _, err := PlanSubscriberImport(db, strings.NewReader("name,language\nAda,fr\n"), false); err == nil {
+		t.Error("a file without an email column was accepted")
+	}
+	if _, err := PlanSubscriberImport(db, strings.NewReader(""), false); err == nil {
+		t.Error("an empty file was accepted")
+	}
+}
+
+func TestPlanSubscriberImportValues(t *testing.T) {
+	db := newImportTestDB(t)
+
+	plan, err := PlanSubscriberImport(db, strings.NewReader("email,language,frequency,consent_at,source\nada@example.com,de_DE,Monthly,2024-05-01,'@campaign\n"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Preview) != 1 {
+		t.Fatalf("got %d subscribers in the preview, want 1", len(plan.Preview))
+	}
+	subscriber := plan.Preview[0]
+	if subscriber.Language != "de" || subscriber.Frequency != DigestFrequencyMonthly || subscriber.Source != "@campaign" {
+		t.Errorf("got language %q, frequency %q, source %q", subscriber.Language, subscriber.Frequency, subscriber.Source)
+	}
+	if subscriber.IsActive || subscriber.ConsentAt != nil {
+		t.Error("a subscriber asked to confirm is active or has a consent time before confirming")
+	}
+	if subscriber.LastDigestAt == nil {
+		t.Error("a digest subscriber has no digest period start")
+	}
+
+	// Nothing is written until the plan is applied
+	var count int64
+	db.Model(&NewsletterSubscriber{}).Count(&count)
+	if count != 1 {
+		t.Errorf("planning an import changed the subscribers: %d, want 1", count)
+	}
+}
+
+func TestSubscribersCSVRoundTrip(t *testing.T) {
+	consentAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	rows := []SubscriberExportRow{
+		{Email: "ada@example.com", Status: "active", Source: "=HYPERLINK(\"http://evil.example\")", Frequency: DigestFrequencyInstant, SubscribedAt: consentAt, ConsentAt: &consentAt},
+		{Email: "bob@example.com", Status: "active", Source: "+1", Language: "fr", Frequency: DigestFrequencyWeekly, SubscribedAt: consentAt},
+		{Email: "cy@example.com", Status: "active", Source: "'quoted", Frequency: DigestFrequencyInstant, SubscribedAt: consentAt},
+	}
+
+	var buffer bytes.Buffer
+	if err := WriteSubscribersCSV(&buffer, rows); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(buffer.String(), "\n") {
+		for _, cell := range strings.Split(line, ",") {
+			if cell = strings.Trim(cell, "\""); cell != "" && strings.ContainsAny(cell[:1], "=+-@") {
+				t.Errorf("cell %q of the export starts a formula", cell)
+			}
+		}
+	}
+
+	plan, err := PlanSubscriberImport(newImportTestDB(t), &buffer, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.ToCreate != len(rows) {
+		t.Fatalf("reimported %d subscribers, want %d: %+v", plan.ToCreate, len(rows), plan.Rows)
+	}
+	for i, subscriber := range plan.Preview {
+		if subscriber.Email != rows[i].Email || subscriber.Source != strings.ToLower(rows[i].Source) || subscriber.Frequency != rows[i].Frequency {
+			t.Errorf("row %d reimported as %q %q %q", i, subscriber.Email, subscriber.Source, subscriber.Frequency)
+		}
+	}
+	if consent := plan.Preview[0].ConsentAt; consent == nil || !consent.Equal(consentAt) {
+		t.Errorf("consent time reimported as %v, want %v", consent, consentAt)
+	}
+}
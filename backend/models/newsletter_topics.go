@@ -0,0 +1,179 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"shipshipship/utils"
+
+	"gorm.io/gorm"
+)
+
+// preferencesTokenPurpose scopes the signature of subscriber preference links
+const preferencesTokenPurpose = "newsletter-preferences"
+
+// PreferencesTokenTTL is how long the preferences link in an email stays valid
+const PreferencesTokenTTL = 90 * 24 * time.Hour
+
+// SubscriberTopicsRequest selects the topics a subscriber wants to hear about
+type SubscriberTopicsRequest struct {
+	AllTopics   *bool  `json:"all_topics"`
+	TagIDs      []uint `json:"tag_ids"`
+	TagGroupIDs []uint `json:"tag_group_ids"`
+}
+
+// UpdatePreferencesRequest updates a subscriber's topics through a signed link
+type UpdatePreferencesRequest struct {
+	Email     string  `json:"email" binding:"required,email"`
+	Token     string  `json:"token" binding:"required"`
+	Frequency string  `json:"frequency"` // instant, weekly or monthly; unchanged when empty
+	Language  *string `json:"language"`  // Preferred email language, empty for the project locale; unchanged when omitted
+	SubscriberTopicsRequest
+}
+
+// PreferencesToken returns the token authorizing changes to a subscriber's preferences until it
+// expires. It fails when JWT_SECRET is unset.
+func PreferencesToken(email string) (string, error) {
+	return utils.SignExpiringValue(preferencesTokenPurpose, strings.ToLower(strings.TrimSpace(email)), time.Now(), PreferencesTokenTTL)
+}
+
+// VerifyPreferencesToken checks a token produced by PreferencesToken that has not expired
+func VerifyPreferencesToken(email, token string) bool {
+	return utils.VerifyExpiringValue(preferencesTokenPurpose, strings.ToLower(strings.TrimSpace(email)), token, time.Now())
+}
+
+// SetSubscriberTopics replaces a subscriber's topics. Selecting no tag or group means all topics.
+func SetSubscriberTopics(db *gorm.DB, subscriber *NewsletterSubscriber, req SubscriberTopicsRequest) error {
+	tags := []Tag{}
+	if len(req.TagIDs) > 0 {
+		if err := db.Where("id IN ?", req.TagIDs).Find(&tags).Error; err != nil {
+			return err
+		}
+		if len(tags) != len(UniqueIDs(req.TagIDs)) {
+			return fmt.Errorf("one or more topic tags were not found")
+		}
+	}
+
+	groups := []TagGroup{}
+	if len(req.TagGroupIDs) > 0 {
+		if err := db.Where("id IN ?", req.TagGroupIDs).Find(&groups).Error; err != nil {
+			return err
+		}
+		if len(groups) != len(UniqueIDs(req.TagGroupIDs)) {
+			return fmt.Errorf("one or more topic groups were not found")
+		}
+	}
+
+	allTopics := len(tags) == 0 && len(groups) == 0
+	if req.AllTopics != nil && *req.AllTopics {
+		allTopics = true
+	}
+	if allTopics {
+		tags, groups = []Tag{}, []TagGroup{}
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(subscriber).Update("all_topics", allTopics).Error; err != nil {
+			return err
+		}
+		subscriber.AllTopics = allTopics
+		if err := tx.Model(subscriber).Association("TopicTags").Replace(tags); err != nil {
+			return err
+		}
+		return tx.Model(subscriber).Association("TopicGroups").Replace(groups)
+	})
+}
+
+// TopicIndex resolves subscriber topics to the tags they cover
+type TopicIndex struct {
+	children    map[uint][]uint
+	groupTagIDs map[uint][]uint
+}
+
+// LoadTopicIndex loads the tag hierarchy and groups needed to match subscriber topics
+func LoadTopicIndex(db *gorm.DB) (*TopicIndex, error) {
+	var tags []Tag
+	if err := db.Select("id", "parent_id", "group_id").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	index := &TopicIndex{children: make(map[uint][]uint), groupTagIDs: make(map[uint][]uint)}
+	for _, tag := range tags {
+		if tag.ParentID != nil {
+			index.children[*tag.ParentID] = append(index.children[*tag.ParentID], tag.ID)
+		}
+		if tag.GroupID != nil {
+			index.groupTagIDs[*tag.GroupID] = append(index.groupTagIDs[*tag.GroupID], tag.ID)
+		}
+	}
+	return index, nil
+}
+
+// coveredTags returns the tags a subscriber's topics cover, including descendants
+func (index *TopicIndex) coveredTags(subscriber *NewsletterSubscriber) map[uint]bool {
+	queue := []uint{}
+	for _, tag := range subscriber.TopicTags {
+		queue = append(queue, tag.ID)
+	}
+	for _, group := range subscriber.TopicGroups {
+		queue = append(queue, index.groupTagIDs[group.ID]...)
+	}
+
+	covered := make(map[uint]bool)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if covered[id] {
+			continue
+		}
+		covered[id] = true
+		queue = append(queue, index.children[id]...)
+	}
+	return covered
+}
+
+// Follows reports whether a subscriber, with topics preloaded, wants content carrying any of the tags
+func (index *TopicIndex) Follows(subscriber *NewsletterSubscriber, tagIDs []uint) bool {
+	if subscriber.AllTopics {
+		return true
+	}
+	covered := index.coveredTags(subscriber)
+	for _, tagID := range tagIDs {
+		if covered[tagID] {
+			return true
+		}
+	}
+	return false
+}
+
+// GetNewsletterSubscribersForTags returns the active subscribers interested in content
+// carrying any of the given tags: those following all topics, plus those whose topics intersect
+func GetNewsletterSubscribersForTags(db *gorm.DB, tagIDs []uint) ([]NewsletterSubscriber, error) {
+	var subscribers []NewsletterSubscriber
+	if err := db.Preload("TopicTags").Preload("TopicGroups").Where("is_active = ?", true).Find(&subscribers).Error; err != nil {
+		return nil, err
+	}
+
+	index, err := LoadTopicIndex(db)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := []NewsletterSubscriber{}
+	for i := range subscribers {
+		if index.Follows(&subscribers[i], tagIDs) {
+			recipients = append(recipients, subscribers[i])
+		}
+	}
+	return recipients, nil
+}
+
+// GetNewsletterSubscribersForEvent returns the subscribers targeted by a newsletter about the event
+func GetNewsletterSubscribersForEvent(db *gorm.DB, event *Event) ([]NewsletterSubscriber, error) {
+	tagIDs := make([]uint, len(event.Tags))
+	for i, tag := range event.Tags {
+		tagIDs[i] = tag.ID
+	}
+	return GetNewsletterSubscribersForTags(db, tagIDs)
+}
@@ -0,0 +1,242 @@
+package models
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ipHashPurpose names the hash key that replaces IP addresses past the retention period
+const ipHashPurpose = "ip-address"
+
+// AnonymizedIPPrefix marks a hashed IP address
+const AnonymizedIPPrefix = "anon:"
+
+// HashIPAddress returns the keyed hash stored in place of an IP address once it is anonymized.
+// The same address always gives the same hash, so reactions can still be toggled.
+func HashIPAddress(db *gorm.DB, ip string) (string, error) {
+	hash, err := KeyedHash(db, ipHashPurpose, ip)
+	if err != nil {
+		return "", err
+	}
+	return AnonymizedIPPrefix + hash, nil
+}
+
+// IPAddressKeys returns the stored values that identify a visitor by IP: the address and its hash
+func IPAddressKeys(db *gorm.DB, ip string) []string {
+	hash, err := HashIPAddress(db, ip)
+	if err != nil {
+		log.Printf("Failed to hash IP address: %v", err)
+		return []string{ip}
+	}
+	return []string{ip, hash}
+}
+
+// PersonalDataQuery identifies a data subject by email address, IP address or visitor ID
+type PersonalDataQuery struct {
+	Email     string `json:"email"`
+	IPAddress string `json:"ip_address"`
+	VisitorID string `json:"visitor_id"` // The visitor cookie value, or the ID before its signature
+}
+
+// IsEmpty reports whether the query identifies no one
+func (q PersonalDataQuery) IsEmpty() bool {
+	return strings.TrimSpace(q.Email) == "" && strings.TrimSpace(q.IPAddress) == "" && strings.TrimSpace(q.VisitorID) == ""
+}
+
+// reactionOwners returns the conditions matching the reactions and votes of the data subject
+func (q PersonalDataQuery) reactionOwners(db *gorm.DB) *gorm.DB {
+	owners := db.Where("1 = 0")
+	if ip := strings.TrimSpace(q.IPAddress); ip != "" {
+		keys := IPAddressKeys(db, ip)
+		owners = owners.Or("ip_address IN ?", keys).Or("ip_hash IN ?", keys)
+	}
+	if visitorID, _, _ := strings.Cut(strings.TrimSpace(q.VisitorID), "."); visitorID != "" {
+		if hash, err := VisitorHash(db, visitorID); err != nil {
+			log.Printf("Failed to hash visitor ID: %v", err)
+		} else {
+			owners = owners.Or("visitor_hash = ?", hash)
+		}
+	}
+	return owners
+}
+
+// PersonalData is everything stored about an email address, IP address or visitor
+type PersonalData struct {
+	PersonalDataQuery
+	Subscriber   *NewsletterSubscriber `json:"subscriber"`   // Includes unsubscribed records
+	Unsubscribed bool                  `json:"unsubscribed"` // The subscriber record is kept only to honor the unsubscription
+	Deliveries   []EmailDelivery       `json:"deliveries"`
+	Reactions    []EventReaction       `json:"reactions"`
+	Votes        []Vote                `json:"votes"`
+}
+
+// ErasureResult counts the records removed by ErasePersonalData
+type ErasureResult struct {
+	Subscribers int64 `json:"subscribers"`
+	Deliveries  int64 `json:"deliveries"`
+	Reactions   int64 `json:"reactions"`
+	Votes       int64 `json:"votes"`
+}
+
+// FindPersonalData returns the records held about a data subject.
+// Emails are matched without case; IP addresses also match their anonymized form.
+func FindPersonalData(db *gorm.DB, query PersonalDataQuery) (*PersonalData, error) {
+	data := &PersonalData{
+		PersonalDataQuery: query,
+		Deliveries:        []EmailDelivery{},
+		Reactions:         []EventReaction{},
+		Votes:             []Vote{},
+	}
+
+	email := strings.TrimSpace(query.Email)
+	if email != "" {
+		var subscriber NewsletterSubscriber
+		err := db.Unscoped().Preload("TopicTags").Preload("TopicGroups").
+			Where("LOWER(email) = LOWER(?)", email).First(&subscriber).Error
+		if err == nil {
+			data.Subscriber = &subscriber
+			data.Unsubscribed = subscriber.DeletedAt.Valid
+		} else if err != gorm.ErrRecordNotFound {
+			return nil, err
+		}
+
+		deliveries, err := GetEmailDeliveries(db, email)
+		if err != nil {
+			return nil, err
+		}
+		data.Deliveries = deliveries
+	}
+
+	if err := db.Unscoped().Where(query.reactionOwners(db)).Order("created_at DESC").Find(&data.Reactions).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Unscoped().Where(query.reactionOwners(db)).Order("created_at DESC").Find(&data.Votes).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ErasePersonalData permanently deletes the records held about a data subject.
+// An erased email is forgotten entirely, so it is no longer protected from being imported again.
+func ErasePersonalData(db *gorm.DB, query PersonalDataQuery) (*ErasureResult, error) {
+	email := strings.TrimSpace(query.Email)
+	result := &ErasureResult{}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if email != "" {
+			var subscribers []NewsletterSubscriber
+			if err := tx.Unscoped().Where("LOWER(email) = LOWER(?)", email).Find(&subscribers).Error; err != nil {
+				return err
+			}
+			for i := range subscribers {
+				if err := tx.Unscoped().Model(&subscribers[i]).Association("TopicTags").Clear(); err != nil {
+					return err
+				}
+				if err := tx.Unscoped().Model(&subscribers[i]).Association("TopicGroups").Clear(); err != nil {
+					return err
+				}
+				if err := tx.Unscoped().Delete(&subscribers[i]).Error; err != nil {
+					return err
+				}
+				result.Subscribers++
+			}
+
+			deleted := tx.Where("LOWER(recipient) = LOWER(?)", email).Delete(&EmailDelivery{})
+			if deleted.Error != nil {
+				return deleted.Error
+			}
+			result.Deliveries = deleted.RowsAffected
+		}
+
+		deleted := tx.Unscoped().Where(query.reactionOwners(tx)).Delete(&EventReaction{})
+		if deleted.Error != nil {
+			return deleted.Error
+		}
+		result.Reactions = deleted.RowsAffected
+
+		// Votes not already withdrawn are counted on their event
+		var counts []struct {
+			EventID uint
+			Count   int
+		}
+		if err := tx.Model(&Vote{}).Select("event_id, COUNT(*) AS count").
+			Where(query.reactionOwners(tx)).Group("event_id").Scan(&counts).Error; err != nil {
+			return err
+		}
+		for _, count := range counts {
+			if err := tx.Unscoped().Model(&Event{}).Where("id = ?", count.EventID).
+				UpdateColumn("votes", gorm.Expr("CASE WHEN votes > ? THEN votes - ? ELSE 0 END", count.Count, count.Count)).Error; err != nil {
+				return err
+			}
+		}
+
+		deleted = tx.Unscoped().Where(query.reactionOwners(tx)).Delete(&Vote{})
+		if deleted.Error != nil {
+			return deleted.Error
+		}
+		result.Votes = deleted.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// AnonymizeIPAddresses replaces the IP addresses of reactions and votes created before a time
+// with their hash, and returns the number of records changed
+func AnonymizeIPAddresses(db *gorm.DB, before time.Time) (int64, error) {
+	var total int64
+	for _, model := range []interface{}{&EventReaction{}, &Vote{}} {
+		stale := func() *gorm.DB {
+			return db.Unscoped().Model(model).
+				Where("created_at < ? AND ip_address <> '' AND ip_address NOT LIKE ?", before, AnonymizedIPPrefix+"%")
+		}
+
+		var addresses []string
+		if err := stale().Distinct().Pluck("ip_address", &addresses).Error; err != nil {
+			return total, err
+		}
+		for _, address := range addresses {
+			hash, err := HashIPAddress(db, address)
+			if err != nil {
+				return total, err
+			}
+			updated := stale().Where("ip_address = ?", address).Update("ip_address", hash)
+			if updated.Error != nil {
+				return total, updated.Error
+			}
+			total += updated.RowsAffected
+		}
+	}
+	return total, nil
+}
+
+// RetentionResult counts the records changed by ApplyRetention
+type RetentionResult struct {
+	AnonymizedIPs     int64 `json:"anonymized"`         // Reactions and votes whose IP address was hashed
+	DeletedDeliveries int64 `json:"deleted_deliveries"` // Email delivery records removed
+}
+
+// ApplyRetention anonymizes the IP addresses and deletes the email delivery records older than
+// the retention period in the settings
+func ApplyRetention(db *gorm.DB, now time.Time) (*RetentionResult, error) {
+	result := &RetentionResult{}
+	settings, err := GetOrCreateSettings(db)
+	if err != nil {
+		return result, err
+	}
+	if settings.IPRetentionDays <= 0 {
+		return result, nil
+	}
+	before := now.AddDate(0, 0, -settings.IPRetentionDays)
+
+	if result.AnonymizedIPs, err = AnonymizeIPAddresses(db, before); err != nil {
+		return result, err
+	}
+	result.DeletedDeliveries, err = DeleteEmailDeliveriesBefore(db, before)
+	return result, err
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"shipshipship/utils"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newPrivacyTestDB opens a database with the tables of models and the hash keys, under a fresh secrets key
+func newPrivacyTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	encoded, _ := utils.GenerateSecretsKey()
+	key, _ := utils.ParseSecretsKey(encoded)
+	utils.SetSecretsKey(key)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(append(models, &HashKey{})...); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestApplyRetention(t *testing.T) {
+	db := newPrivacyTestDB(t, &ProjectSettings{}, &EventReaction{}, &Vote{}, &EmailDelivery{})
+	settings, err := GetOrCreateSettings(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Model(settings).Update("ip_retention_days", 30)
+
+	now := time.Now()
+	old, recent := now.AddDate(0, 0, -31), now.AddDate(0, 0, -1)
+	db.Create(&[]EventReaction{
+		{EventID: 1, ReactionType: ReactionHeart, IPAddress: "192.0.2.1", CreatedAt: old},
+		{EventID: 1, ReactionType: ReactionHeart, IPAddress: "192.0.2.2", CreatedAt: recent},
+	})
+	db.Create(&Vote{EventID: 1, IPAddress: "192.0.2.1", CreatedAt: old})
+	db.Create(&[]EmailDelivery{
+		{Recipient: "old@example.com", Status: EmailDeliverySent, CreatedAt: old},
+		{Recipient: "recent@example.com", Status: EmailDeliverySent, CreatedAt: recent},
+	})
+
+	result, err := ApplyRetention(db, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.AnonymizedIPs != 2 || result.DeletedDeliveries != 1 {
+		t.Fatalf("ApplyRetention = %+v, want 2 anonymized and 1 deleted", *result)
+	}
+
+	var addresses []string
+	db.Model(&EventReaction{}).Order("id").Pluck("ip_address", &addresses)
+	if !strings.HasPrefix(addresses[0], AnonymizedIPPrefix) || addresses[1] != "192.0.2.2" {
+		t.Errorf("reaction addresses = %v, want the old one hashed", addresses)
+	}
+	if strings.Contains(addresses[0], "192.0.2.1") {
+		t.Errorf("hash %q contains the address", addresses[0])
+	}
+
+	// The hash still identifies the visitor, so their old reactions can be found
+	var owned int64
+	db.Model(&EventReaction{}).Where(PersonalDataQuery{IPAddress: "192.0.2.1"}.reactionOwners(db)).Count(&owned)
+	if owned != 1 {
+		t.Errorf("reactions found by IP = %d, want 1", owned)
+	}
+
+	// The hash key is stored encrypted
+	var stored string
+	db.Raw("SELECT secret FROM hash_keys WHERE purpose = ?", ipHashPurpose).Scan(&stored)
+	if !utils.IsEncryptedSecret(stored) {
+		t.Errorf("hash key stored as %q, want it encrypted", stored)
+	}
+
+	var recipients []string
+	db.Model(&EmailDelivery{}).Pluck("recipient", &recipients)
+	if len(recipients) != 1 || recipients[0] != "recent@example.com" {
+		t.Errorf("remaining deliveries = %v, want only the recent one", recipients)
+	}
+}
+
+func TestErasePersonalDataVotes(t *testing.T) {
+	db := newPrivacyTestDB(t, &Event{}, &EventReaction{}, &Vote{})
+
+	visitor, err := VisitorHash(db, "visitor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&Event{Title: "First", Slug: "first", Votes: 2})
+	db.Create(&Event{Title: "Second", Slug: "second", Votes: 1})
+	db.Create(&[]Vote{
+		{EventID: 1, VisitorHash: visitor},
+		{EventID: 1, VisitorHash: "someone-else"},
+		{EventID: 2, VisitorHash: visitor},
+	})
+	// A withdrawn vote is no longer counted on its event
+	withdrawn := Vote{EventID: 2, VisitorHash: visitor}
+	db.Create(&withdrawn)
+	db.Delete(&withdrawn)
+
+	result, err := ErasePersonalData(db, PersonalDataQuery{VisitorID: "visitor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Votes != 3 {
+		t.Errorf("erased %d votes, want 3", result.Votes)
+	}
+
+	var votes []int
+	db.Model(&Event{}).Order("id").Pluck("votes", &votes)
+	if len(votes) != 2 || votes[0] != 1 || votes[1] != 0 {
+		t.Errorf("event votes = %v, want [1 0]", votes)
+	}
+}
@@ -25,8 +25,10 @@ type EventReaction struct {
 	ID           uint           `json:"id" gorm:"primaryKey"`
 	EventID      uint           `json:"event_id" gorm:"not null;index"`
 	ReactionType ReactionType   `json:"reaction_type" gorm:"not null;index"`
-	IPAddress    string         `json:"ip_address" gorm:"index"`
-	UserID       *uint          `json:"user_id" gorm:"index"` // nullable for anonymous reactions
+	VisitorHash  string         `json:"-" gorm:"not null;default:'';index"` // Hash of the visitor ID cookie
+	IPAddress    string         `json:"ip_address" gorm:"index"`            // Only set on reactions made before visitor IDs
+	IPHash       string         `json:"-" gorm:"not null;default:'';index"` // Keyed hash of the visitor's IP address, for IPVoteLimit
+	UserID       *uint          `json:"user_id" gorm:"index"`               // nullable for anonymous reactions
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
@@ -51,7 +53,7 @@ type ReactionSummary struct {
 	EventID       uint            `json:"event_id"`
 	TotalCount    int64           `json:"total_count"`
 	Reactions     []ReactionCount `json:"reactions"`
-	UserReactions []ReactionType  `json:"user_reactions"` // reactions by current user/IP
+	UserReactions []ReactionType  `json:"user_reactions"` // reactions by the current visitor
 }
 
 // GetReactionEmoji returns the emoji for a reaction type
@@ -0,0 +1,247 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Release groups events shipped together under a semantic version
+type Release struct {
+	ID                   uint       `json:"id" gorm:"primaryKey"`
+	Version              string     `json:"version" gorm:"not null;uniqueIndex"` // Semantic version without the "v" prefix, e.g. 1.4.0
+	Name                 string     `json:"name"`
+	ReleaseDate          string     `json:"release_date"` // YYYY-MM-DD, same format as event dates
+	Notes                string     `json:"notes" gorm:"type:text"`
+	Events               []Event    `json:"events" gorm:"many2many:release_events;"`
+	NewsletterSentAt     *time.Time `json:"newsletter_sent_at"`
+	NewsletterRecipients int        `json:"newsletter_recipients" gorm:"default:0"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+}
+
+type CreateReleaseRequest struct {
+	Version     string `json:"version" binding:"required"`
+	Name        string `json:"name"`
+	ReleaseDate string `json:"release_date"`
+	Notes       string `json:"notes"`
+	EventIDs    []uint `json:"event_ids"`
+}
+
+type UpdateReleaseRequest struct {
+	Version     *string `json:"version"`
+	Name        *string `json:"name"`
+	ReleaseDate *string `json:"release_date"`
+	Notes       *string `json:"notes"`
+	EventIDs    *[]uint `json:"event_ids"` // Replaces the linked events when provided
+}
+
+type ReleaseNewsletterRequest struct {
+	Subject string `json:"subject" binding:"required"`
+	Content string `json:"content" binding:"required"`
+	// Versions for subscribers of other languages, by locale; the others get Subject and Content
+	Localized map[string]NewsletterContent `json:"localized"`
+}
+
+// ErrInvalidVersion is returned for versions that aren't valid semantic versions
+var ErrInvalidVersion = errors.New("version must be a semantic version such as 1.2.3")
+
+var semverPattern = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// Semver is a parsed semantic version (https://semver.org)
+type Semver struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease []string
+	Build      string
+}
+
+// ParseSemver parses a semantic version, accepting an optional leading "v"
+func ParseSemver(version string) (Semver, error) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	match := semverPattern.FindStringSubmatch(version)
+	if match == nil {
+		return Semver{}, ErrInvalidVersion
+	}
+
+	var parsed Semver
+	var err error
+	if parsed.Major, err = strconv.ParseUint(match[1], 10, 64); err != nil {
+		return Semver{}, ErrInvalidVersion
+	}
+	if parsed.Minor, err = strconv.ParseUint(match[2], 10, 64); err != nil {
+		return Semver{}, ErrInvalidVersion
+	}
+	if parsed.Patch, err = strconv.ParseUint(match[3], 10, 64); err != nil {
+		return Semver{}, ErrInvalidVersion
+	}
+	if match[4] != "" {
+		parsed.Prerelease = strings.Split(match[4], ".")
+	}
+	parsed.Build = match[5]
+	return parsed, nil
+}
+
+// String formats the version without the "v" prefix
+func (v Semver) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 following semver precedence (build metadata is ignored)
+func (v Semver) Compare(other Semver) int {
+	for _, pair := range [][2]uint64{{v.Major, other.Major}, {v.Minor, other.Minor}, {v.Patch, other.Patch}} {
+		if pair[0] != pair[1] {
+			if pair[0] < pair[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	// A version without prerelease identifiers has higher precedence
+	switch {
+	case len(v.Prerelease) == 0 && len(other.Prerelease) == 0:
+		return 0
+	case len(v.Prerelease) == 0:
+		return 1
+	case len(other.Prerelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(v.Prerelease) && i < len(other.Prerelease); i++ {
+		a, b := v.Prerelease[i], other.Prerelease[i]
+		if a == b {
+			continue
+		}
+		aNum, aErr := strconv.ParseUint(a, 10, 64)
+		bNum, bErr := strconv.ParseUint(b, 10, 64)
+		switch {
+		case aErr == nil && bErr == nil:
+			if aNum < bNum {
+				return -1
+			}
+			return 1
+		case aErr == nil:
+			return -1 // Numeric identifiers sort before alphanumeric ones
+		case bErr == nil:
+			return 1
+		case a < b:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	switch {
+	case len(v.Prerelease) < len(other.Prerelease):
+		return -1
+	case len(v.Prerelease) > len(other.Prerelease):
+		return 1
+	}
+	return 0
+}
+
+// NormalizeReleaseVersion validates a version and returns its canonical form
+func NormalizeReleaseVersion(version string) (string, error) {
+	parsed, err := ParseSemver(version)
+	if err != nil {
+		return "", err
+	}
+	return parsed.String(), nil
+}
+
+// ValidateReleaseDate checks that a release date is empty or formatted as YYYY-MM-DD
+func ValidateReleaseDate(date string) error {
+	if date == "" {
+		return nil
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return fmt.Errorf("release_date must be formatted as YYYY-MM-DD")
+	}
+	return nil
+}
+
+// SortReleasesByVersion orders releases newest version first
+func SortReleasesByVersion(releases []Release) {
+	sort.SliceStable(releases, func(i, j int) bool {
+		a, errA := ParseSemver(releases[i].Version)
+		b, errB := ParseSemver(releases[j].Version)
+		if errA != nil || errB != nil {
+			return releases[i].Version > releases[j].Version
+		}
+		return a.Compare(b) > 0
+	})
+}
+
+// ReleaseEventGroup lists a release's events sharing a status
+type ReleaseEventGroup struct {
+	StatusID uint    `json:"status_id"`
+	Status   string  `json:"status"`
+	Order    int     `json:"-"`
+	Events   []Event `json:"events"`
+}
+
+// GroupReleaseEvents groups events by status, following the status order
+func GroupReleaseEvents(events []Event) []ReleaseEventGroup {
+	groups := []ReleaseEventGroup{}
+	indexByStatus := make(map[uint]int)
+
+	for _, event := range events {
+		index, ok := indexByStatus[event.StatusID]
+		if !ok {
+			group := ReleaseEventGroup{StatusID: event.StatusID, Status: string(event.Status), Events: []Event{}}
+			if event.StatusDefinition != nil {
+				group.Order = event.StatusDefinition.Order
+			}
+			groups = append(groups, group)
+			index = len(groups) - 1
+			indexByStatus[event.StatusID] = index
+		}
+		groups[index].Events = append(groups[index].Events, event)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Order != groups[j].Order {
+			return groups[i].Order < groups[j].Order
+		}
+		return groups[i].Status < groups[j].Status
+	})
+	return groups
+}
+
+// SetReleaseEvents replaces the events linked to a release
+func SetReleaseEvents(db *gorm.DB, release *Release, eventIDs []uint) error {
+	events := []Event{}
+	if len(eventIDs) > 0 {
+		if err := db.Preload("StatusDefinition").Where("id IN ?", eventIDs).Find(&events).Error; err != nil {
+			return err
+		}
+		if len(events) != len(UniqueIDs(eventIDs)) {
+			return fmt.Errorf("one or more events were not found")
+		}
+	}
+	return db.Model(release).Association("Events").Replace(events)
+}
+
+// UniqueIDs returns the set of distinct IDs, for comparing against the records found for them
+func UniqueIDs(ids []uint) map[uint]bool {
+	unique := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		unique[id] = true
+	}
+	return unique
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"shipshipship/utils"
+
+	"gorm.io/gorm/schema"
+)
+
+// SecretMask is returned by the API in place of a stored secret. Sending it back leaves the secret unchanged.
+const SecretMask = "********"
+
+// SecretColumn is a column whose values are encrypted at rest
+type SecretColumn struct {
+	Table  string
+	Column string
+}
+
+// SecretColumns lists the columns using the secret serializer, for migrations and re-keying
+var SecretColumns = []SecretColumn{
+	{"mail_settings", "smtp_password"},
+	{"mail_settings", "api_key"},
+	{"mail_settings", "dkim_private_key"},
+	{"mail_settings", "dkim_pending_private_key"},
+	{"hash_keys", "secret"},
+}
+
+// SecretSerializer encrypts string fields tagged serializer:secret. Values saved before
+// encryption was added are read as plaintext and encrypted on the next save.
+type SecretSerializer struct{}
+
+// Scan decrypts the stored value
+func (SecretSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case []byte:
+		value = string(v)
+	case string:
+		value = v
+	default:
+		return fmt.Errorf("unsupported secret value type %T", dbValue)
+	}
+
+	if utils.IsEncryptedSecret(value) {
+		plaintext, err := utils.DecryptSecret(value)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s: %v", field.DBName, err)
+		}
+		value = plaintext
+	}
+
+	field.ReflectValueOf(ctx, dst).SetString(value)
+	return nil
+}
+
+// Value encrypts the value to store
+func (SecretSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, _ := fieldValue.(string)
+	if value == "" {
+		return "", nil
+	}
+	return utils.EncryptSecret(value)
+}
+
+func init() {
+	schema.RegisterSerializer("secret", SecretSerializer{})
+}
+
+// MaskSecret returns the mask for a set secret, or an empty string
+func MaskSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	return SecretMask
+}
+
+// IsSecretUpdate reports whether a write-only field in a request carries a new value
+func IsSecretUpdate(value *string) bool {
+	return value != nil && *value != SecretMask
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -13,6 +15,10 @@ type ProjectSettings struct {
 	WebsiteURL          string         `json:"website_url" gorm:"column:website_url"`
 	CurrentThemeID      string         `json:"current_theme_id" gorm:"column:current_theme_id"`
 	CurrentThemeVersion string         `json:"current_theme_version" gorm:"column:current_theme_version"`
+	RobotsDisallowAdmin bool           `json:"robots_disallow_admin" gorm:"column:robots_disallow_admin;default:true"`
+	RobotsExtraRules    string         `json:"robots_extra_rules" gorm:"column:robots_extra_rules"`         // Appended verbatim to robots.txt
+	Locale              string         `json:"locale" gorm:"column:locale;default:'en'"`                    // Language used to format dates
+	IPRetentionDays     int            `json:"ip_retention_days" gorm:"column:ip_retention_days;default:0"` // Days before reaction IPs are hashed and email delivery records deleted; 0 keeps them
 	CreatedAt           time.Time      `json:"created_at"`
 	UpdatedAt           time.Time      `json:"updated_at"`
 	DeletedAt           gorm.DeletedAt `json:"-" gorm:"index"`
@@ -24,6 +30,10 @@ type UpdateSettingsRequest struct {
 	WebsiteURL          *string `json:"website_url"`
 	CurrentThemeID      *string `json:"current_theme_id"`
 	CurrentThemeVersion *string `json:"current_theme_version"`
+	RobotsDisallowAdmin *bool   `json:"robots_disallow_admin"`
+	RobotsExtraRules    *string `json:"robots_extra_rules"`
+	Locale              *string `json:"locale"`
+	IPRetentionDays     *int    `json:"ip_retention_days"`
 }
 
 // GetOrCreateSettings ensures there's always a settings record
@@ -41,6 +51,8 @@ func GetOrCreateSettings(db *gorm.DB) (*ProjectSettings, error) {
 				WebsiteURL:          "",
 				CurrentThemeID:      "",
 				CurrentThemeVersion: "",
+				RobotsDisallowAdmin: true,
+				Locale:              DefaultLocale,
 			}
 			if err := db.Create(&settings).Error; err != nil {
 				return nil, err
@@ -54,27 +66,124 @@ func GetOrCreateSettings(db *gorm.DB) (*ProjectSettings, error) {
 }
 
 type MailSettings struct {
-	ID             uint           `json:"id" gorm:"primaryKey"`
-	SMTPHost       string         `json:"smtp_host" gorm:"column:smtp_host"`
-	SMTPPort       int            `json:"smtp_port" gorm:"column:smtp_port;default:587"`
-	SMTPUsername   string         `json:"smtp_username" gorm:"column:smtp_username"`
-	SMTPPassword   string         `json:"smtp_password" gorm:"column:smtp_password"`
-	SMTPEncryption string         `json:"smtp_encryption" gorm:"column:smtp_encryption;default:'tls'"`
-	FromEmail      string         `json:"from_email" gorm:"column:from_email"`
-	FromName       string         `json:"from_name" gorm:"column:from_name"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                    uint           `json:"id" gorm:"primaryKey"`
+	SMTPHost              string         `json:"smtp_host" gorm:"column:smtp_host"`
+	SMTPPort              int            `json:"smtp_port" gorm:"column:smtp_port;default:587"`
+	SMTPUsername          string         `json:"smtp_username" gorm:"column:smtp_username"`
+	SMTPPassword          string         `json:"smtp_password" gorm:"column:smtp_password;serializer:secret"` // Encrypted at rest; masked in responses
+	SMTPEncryption        string         `json:"smtp_encryption" gorm:"column:smtp_encryption;default:'tls'"`
+	SMTPMaxPerConnection  int            `json:"smtp_max_per_connection" gorm:"column:smtp_max_per_connection;default:100"` // Messages sent before reconnecting; 0 for no limit
+	SMTPMessageDelayMs    int            `json:"smtp_message_delay_ms" gorm:"column:smtp_message_delay_ms;default:0"`       // Pause between messages, to stay under provider rate limits
+	FromEmail             string         `json:"from_email" gorm:"column:from_email"`
+	FromName              string         `json:"from_name" gorm:"column:from_name"`
+	Transport             string         `json:"transport" gorm:"column:transport;default:'smtp'"` // smtp, sendmail, file, maildir or api
+	SendmailPath          string         `json:"sendmail_path" gorm:"column:sendmail_path"`        // Defaults to /usr/sbin/sendmail
+	FileDirectory         string         `json:"file_directory" gorm:"column:file_directory"`      // Where the file and maildir transports write
+	APIURL                string         `json:"api_url" gorm:"column:api_url"`                    // Endpoint the api transport posts JSON to
+	APIKey                string         `json:"api_key" gorm:"column:api_key;serializer:secret"`  // Sent as a bearer token; encrypted at rest
+	DKIMEnabled           bool           `json:"dkim_enabled" gorm:"column:dkim_enabled;default:false"`
+	DKIMDomain            string         `json:"dkim_domain" gorm:"column:dkim_domain"` // Defaults to the domain of the from email
+	DKIMSelector          string         `json:"dkim_selector" gorm:"column:dkim_selector"`
+	DKIMPrivateKey        string         `json:"dkim_private_key" gorm:"column:dkim_private_key;type:text;serializer:secret"` // PEM; encrypted at rest
+	DKIMPendingSelector   string         `json:"dkim_pending_selector" gorm:"column:dkim_pending_selector"`                   // Key being rotated in, published before it signs
+	DKIMPendingPrivateKey string         `json:"-" gorm:"column:dkim_pending_private_key;type:text;serializer:secret"`
+	CreatedAt             time.Time      `json:"created_at"`
+	UpdatedAt             time.Time      `json:"updated_at"`
+	DeletedAt             gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 type UpdateMailSettingsRequest struct {
-	SMTPHost       *string `json:"smtp_host"`
-	SMTPPort       *int    `json:"smtp_port"`
-	SMTPUsername   *string `json:"smtp_username"`
-	SMTPPassword   *string `json:"smtp_password"`
-	SMTPEncryption *string `json:"smtp_encryption"`
-	FromEmail      *string `json:"from_email"`
-	FromName       *string `json:"from_name"`
+	SMTPHost             *string `json:"smtp_host"`
+	SMTPPort             *int    `json:"smtp_port"`
+	SMTPUsername         *string `json:"smtp_username"`
+	SMTPPassword         *string `json:"smtp_password"`
+	SMTPEncryption       *string `json:"smtp_encryption"`
+	SMTPMaxPerConnection *int    `json:"smtp_max_per_connection"`
+	SMTPMessageDelayMs   *int    `json:"smtp_message_delay_ms"`
+	FromEmail            *string `json:"from_email"`
+	FromName             *string `json:"from_name"`
+	Transport            *string `json:"transport"`
+	SendmailPath         *string `json:"sendmail_path"`
+	FileDirectory        *string `json:"file_directory"`
+	APIURL               *string `json:"api_url"`
+	APIKey               *string `json:"api_key"`
+	DKIMEnabled          *bool   `json:"dkim_enabled"`
+	DKIMDomain           *string `json:"dkim_domain"`
+	DKIMSelector         *string `json:"dkim_selector"`
+	DKIMPrivateKey       *string `json:"dkim_private_key"`
+}
+
+// Mail transports
+const (
+	MailTransportSMTP     = "smtp"
+	MailTransportSendmail = "sendmail"
+	MailTransportFile     = "file"
+	MailTransportMaildir  = "maildir"
+	MailTransportAPI      = "api"
+)
+
+// IsValidMailTransport reports whether transport names a supported mail transport
+func IsValidMailTransport(transport string) bool {
+	switch transport {
+	case MailTransportSMTP, MailTransportSendmail, MailTransportFile, MailTransportMaildir, MailTransportAPI:
+		return true
+	}
+	return false
+}
+
+// TransportName returns the configured transport, SMTP for settings saved before transports existed
+func (s *MailSettings) TransportName() string {
+	if transport := strings.ToLower(strings.TrimSpace(s.Transport)); transport != "" {
+		return transport
+	}
+	return MailTransportSMTP
+}
+
+// Masked returns a copy of the settings safe to return from the API, with secrets replaced by SecretMask
+func (s *MailSettings) Masked() *MailSettings {
+	masked := *s
+	masked.SMTPPassword = MaskSecret(s.SMTPPassword)
+	masked.APIKey = MaskSecret(s.APIKey)
+	masked.DKIMPrivateKey = MaskSecret(s.DKIMPrivateKey)
+	masked.DKIMPendingPrivateKey = ""
+	return &masked
+}
+
+// DKIMSigningDomain returns the domain messages are signed for
+func (s *MailSettings) DKIMSigningDomain() string {
+	if s.DKIMDomain != "" {
+		return s.DKIMDomain
+	}
+	if at := strings.LastIndex(s.FromEmail, "@"); at >= 0 {
+		return s.FromEmail[at+1:]
+	}
+	return ""
+}
+
+// Validate checks that the settings the selected transport needs are configured
+func (s *MailSettings) Validate() error {
+	if s.FromEmail == "" {
+		return fmt.Errorf("from email must be configured")
+	}
+
+	switch s.TransportName() {
+	case MailTransportSMTP:
+		if s.SMTPHost == "" {
+			return fmt.Errorf("SMTP host and from email must be configured")
+		}
+	case MailTransportSendmail:
+	case MailTransportFile, MailTransportMaildir:
+		if s.FileDirectory == "" {
+			return fmt.Errorf("a directory must be configured for the %s transport", s.TransportName())
+		}
+	case MailTransportAPI:
+		if s.APIURL == "" {
+			return fmt.Errorf("an API URL must be configured for the api transport")
+		}
+	default:
+		return fmt.Errorf("unknown mail transport %q", s.Transport)
+	}
+	return nil
 }
 
 // GetOrCreateMailSettings ensures there's always a mail settings record
@@ -88,13 +197,15 @@ func GetOrCreateMailSettings(db *gorm.DB) (*MailSettings, error) {
 	if count == 0 {
 		// Create default settings if none exist
 		settings = MailSettings{
-			SMTPHost:       "",
-			SMTPPort:       587,
-			SMTPUsername:   "",
-			SMTPPassword:   "",
-			SMTPEncryption: "tls",
-			FromEmail:      "",
-			FromName:       "",
+			SMTPHost:             "",
+			SMTPPort:             587,
+			SMTPUsername:         "",
+			SMTPPassword:         "",
+			SMTPEncryption:       "tls",
+			SMTPMaxPerConnection: 100,
+			FromEmail:            "",
+			FromName:             "",
+			Transport:            MailTransportSMTP,
 		}
 		if err := db.Create(&settings).Error; err != nil {
 			return nil, err
@@ -114,6 +225,16 @@ type BrandingSettings struct {
 	ProjectName string
 	ProjectURL  string // External website URL
 	BaseURL     string // Base URL of this instance (for unsubscribe links)
+	Locale      string // Language used to format dates
+}
+
+// WithLocale returns a copy of the branding for emails written in another language
+func (b *BrandingSettings) WithLocale(locale string) *BrandingSettings {
+	localized := *b
+	if language := NormalizeLocale(locale); language != "" {
+		localized.Locale = language
+	}
+	return &localized
 }
 
 // GetBrandingSettings returns branding settings for email generation
@@ -126,6 +247,7 @@ func GetBrandingSettings(db *gorm.DB) (*BrandingSettings, error) {
 	return &BrandingSettings{
 		ProjectName: settings.Title,
 		ProjectURL:  settings.WebsiteURL,
+		Locale:      settings.Locale,
 	}, nil
 }
 
@@ -140,5 +262,6 @@ func GetBrandingSettingsWithBaseURL(db *gorm.DB, baseURL string) (*BrandingSetti
 		ProjectName: settings.Title,
 		ProjectURL:  settings.WebsiteURL,
 		BaseURL:     baseURL,
+		Locale:      settings.Locale,
 	}, nil
 }
@@ -0,0 +1,215 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EventStatusChange records an event entering a status.
+// FromStatusID is 0 for the initial status of a newly created event.
+type EventStatusChange struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	EventID         uint      `json:"event_id" gorm:"not null;index"`
+	FromStatusID    uint      `json:"from_status_id"`
+	FromStatus      string    `json:"from_status"` // Names are kept so the timeline survives renames and deletions
+	ToStatusID      uint      `json:"to_status_id" gorm:"not null;index"`
+	ToStatus        string    `json:"to_status"`
+	ChangedAt       time.Time `json:"changed_at" gorm:"not null;index"`
+	Actor           string    `json:"actor"`
+	AutomationFired bool      `json:"automation_fired" gorm:"default:false"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// PublicStatusChange is the timeline entry shown on the public event page
+type PublicStatusChange struct {
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+// StatusPairTimes aggregates the time taken to get from one status to another
+type StatusPairTimes struct {
+	FromStatusID uint   `json:"from_status_id"`
+	FromStatus   string `json:"from_status"`
+	ToStatusID   uint   `json:"to_status_id"`
+	ToStatus     string `json:"to_status"`
+	// Lead time: from first entering FromStatus to first entering ToStatus afterwards, whatever the path
+	LeadTime DurationStats `json:"lead_time"`
+	// Cycle time: time spent in FromStatus before moving directly to ToStatus
+	CycleTime DurationStats `json:"cycle_time"`
+}
+
+// DurationStats summarizes a set of durations in hours
+type DurationStats struct {
+	Count        int     `json:"count"`
+	AverageHours float64 `json:"average_hours"`
+	MedianHours  float64 `json:"median_hours"`
+	P90Hours     float64 `json:"p90_hours"`
+	MinHours     float64 `json:"min_hours"`
+	MaxHours     float64 `json:"max_hours"`
+}
+
+// RecordStatusChange stores a status-change row for an event
+func RecordStatusChange(db *gorm.DB, eventID uint, from, to *EventStatusDefinition, actor string) (*EventStatusChange, error) {
+	change := &EventStatusChange{
+		EventID:    eventID,
+		ToStatusID: to.ID,
+		ToStatus:   to.DisplayName,
+		ChangedAt:  time.Now(),
+		Actor:      actor,
+	}
+	if from != nil {
+		change.FromStatusID = from.ID
+		change.FromStatus = from.DisplayName
+	}
+
+	if err := db.Create(change).Error; err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// MarkAutomationFired flags a status change as having sent an automated newsletter
+func MarkAutomationFired(db *gorm.DB, changeID uint) error {
+	return db.Model(&EventStatusChange{}).Where("id = ?", changeID).Update("automation_fired", true).Error
+}
+
+// GetEventStatusTimeline returns an event's status changes, oldest first
+func GetEventStatusTimeline(db *gorm.DB, eventID uint) ([]EventStatusChange, error) {
+	changes := []EventStatusChange{}
+	err := db.Where("event_id = ?", eventID).Order("changed_at ASC, id ASC").Find(&changes).Error
+	return changes, err
+}
+
+// GetPublicEventStatusTimeline returns the timeline without admin-only details
+func GetPublicEventStatusTimeline(db *gorm.DB, eventID uint) ([]PublicStatusChange, error) {
+	changes, err := GetEventStatusTimeline(db, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := make([]PublicStatusChange, len(changes))
+	for i, change := range changes {
+		timeline[i] = PublicStatusChange{
+			FromStatus: change.FromStatus,
+			ToStatus:   change.ToStatus,
+			ChangedAt:  change.ChangedAt,
+		}
+	}
+	return timeline, nil
+}
+
+// ComputeStatusPairTimes computes lead and cycle times for every status pair
+// whose end transition happened within [from, to]
+func ComputeStatusPairTimes(db *gorm.DB, from, to time.Time) ([]StatusPairTimes, error) {
+	// Earlier history is needed to know when the starting status was entered
+	var changes []EventStatusChange
+	if err := db.Where("changed_at <= ?", to).Order("event_id ASC, changed_at ASC, id ASC").Find(&changes).Error; err != nil {
+		return nil, err
+	}
+
+	type pairKey struct{ from, to uint }
+	leadTimes := make(map[pairKey][]time.Duration)
+	cycleTimes := make(map[pairKey][]time.Duration)
+	names := make(map[uint]string)
+
+	for start := 0; start < len(changes); {
+		end := start
+		for end < len(changes) && changes[end].EventID == changes[start].EventID {
+			end++
+		}
+		history := changes[start:end]
+		start = end
+
+		// First time the event entered each status so far
+		firstEntered := make(map[uint]time.Time)
+		for i, change := range history {
+			names[change.ToStatusID] = change.ToStatus
+			inRange := !change.ChangedAt.Before(from)
+
+			if inRange && i > 0 {
+				previous := history[i-1]
+				key := pairKey{previous.ToStatusID, change.ToStatusID}
+				cycleTimes[key] = append(cycleTimes[key], change.ChangedAt.Sub(previous.ChangedAt))
+			}
+
+			// Only the first arrival in a status counts for lead time
+			if _, seen := firstEntered[change.ToStatusID]; !seen {
+				if inRange {
+					for statusID, enteredAt := range firstEntered {
+						key := pairKey{statusID, change.ToStatusID}
+						leadTimes[key] = append(leadTimes[key], change.ChangedAt.Sub(enteredAt))
+					}
+				}
+				firstEntered[change.ToStatusID] = change.ChangedAt
+			}
+		}
+	}
+
+	keys := make(map[pairKey]bool)
+	for key := range leadTimes {
+		keys[key] = true
+	}
+	for key := range cycleTimes {
+		keys[key] = true
+	}
+
+	result := make([]StatusPairTimes, 0, len(keys))
+	for key := range keys {
+		result = append(result, StatusPairTimes{
+			FromStatusID: key.from,
+			FromStatus:   names[key.from],
+			ToStatusID:   key.to,
+			ToStatus:     names[key.to],
+			LeadTime:     summarizeDurations(leadTimes[key]),
+			CycleTime:    summarizeDurations(cycleTimes[key]),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].FromStatusID != result[j].FromStatusID {
+			return result[i].FromStatusID < result[j].FromStatusID
+		}
+		return result[i].ToStatusID < result[j].ToStatusID
+	})
+
+	return result, nil
+}
+
+func summarizeDurations(durations []time.Duration) DurationStats {
+	stats := DurationStats{Count: len(durations)}
+	if len(durations) == 0 {
+		return stats
+	}
+
+	hours := make([]float64, len(durations))
+	total := 0.0
+	for i, d := range durations {
+		hours[i] = d.Hours()
+		total += hours[i]
+	}
+	sort.Float64s(hours)
+
+	stats.AverageHours = total / float64(len(hours))
+	stats.MinHours = hours[0]
+	stats.MaxHours = hours[len(hours)-1]
+	stats.MedianHours = percentile(hours, 0.5)
+	stats.P90Hours = percentile(hours, 0.9)
+	return stats
+}
+
+// percentile interpolates linearly between the closest ranks of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	rank := p * float64(len(sorted)-1)
+	lower := int(rank)
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	fraction := rank - float64(lower)
+	return sorted[lower] + fraction*(sorted[lower+1]-sorted[lower])
+}
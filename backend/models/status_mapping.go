@@ -89,6 +89,11 @@ func LoadThemeManifest(themePath string) (*ThemeManifest, error) {
 		return nil, fmt.Errorf("failed to read theme.json: %w", err)
 	}
 
+	return ParseThemeManifest(data)
+}
+
+// ParseThemeManifest parses and validates the contents of a theme.json file
+func ParseThemeManifest(data []byte) (*ThemeManifest, error) {
 	// Parse JSON
 	var manifest ThemeManifest
 	if err := json.Unmarshal(data, &manifest); err != nil {
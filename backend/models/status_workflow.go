@@ -0,0 +1,237 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StatusTransition allows events to move from one status to another.
+// Roles restricts who may perform the transition (comma-separated, empty means any role).
+type StatusTransition struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	FromStatusID uint      `json:"from_status_id" gorm:"not null;uniqueIndex:idx_status_transition"`
+	ToStatusID   uint      `json:"to_status_id" gorm:"not null;uniqueIndex:idx_status_transition"`
+	Roles        string    `json:"-" gorm:"type:text"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// MarshalJSON exposes the roles as a list
+func (t StatusTransition) MarshalJSON() ([]byte, error) {
+	type transition StatusTransition
+	return json.Marshal(struct {
+		transition
+		Roles []string `json:"roles"`
+	}{transition(t), t.GetRoles()})
+}
+
+// GetRoles returns the roles allowed to perform the transition
+func (t *StatusTransition) GetRoles() []string {
+	roles := []string{}
+	for _, role := range strings.Split(t.Roles, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// SetRoles stores the roles allowed to perform the transition
+func (t *StatusTransition) SetRoles(roles []string) {
+	cleaned := []string{}
+	for _, role := range roles {
+		if role = strings.TrimSpace(role); role != "" {
+			cleaned = append(cleaned, role)
+		}
+	}
+	t.Roles = strings.Join(cleaned, ",")
+}
+
+// AllowsRole reports whether the role may perform the transition
+func (t *StatusTransition) AllowsRole(role string) bool {
+	roles := t.GetRoles()
+	if len(roles) == 0 {
+		return true
+	}
+	for _, allowed := range roles {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusWorkflowSettings toggles enforcement of the status transitions
+type StatusWorkflowSettings struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	Enabled   bool           `json:"enabled" gorm:"default:false"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+type StatusTransitionRequest struct {
+	FromStatusID uint     `json:"from_status_id" binding:"required"`
+	ToStatusID   uint     `json:"to_status_id" binding:"required"`
+	Roles        []string `json:"roles"`
+}
+
+type UpdateStatusWorkflowRequest struct {
+	Enabled     *bool                      `json:"enabled"`
+	Transitions *[]StatusTransitionRequest `json:"transitions"` // Replaces all transitions when provided
+}
+
+// TransitionError describes a status change rejected by the workflow
+type TransitionError struct {
+	FromStatusID uint     `json:"from_status_id"`
+	FromStatus   string   `json:"from_status"`
+	ToStatusID   uint     `json:"to_status_id"`
+	ToStatus     string   `json:"to_status"`
+	Reason       string   `json:"reason"`
+	Allowed      []string `json:"allowed_statuses"` // Statuses the event may move to instead
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("transition from %s to %s is not allowed: %s", e.FromStatus, e.ToStatus, e.Reason)
+}
+
+// GetOrCreateWorkflowSettings returns the workflow settings or creates default ones
+func GetOrCreateWorkflowSettings(db *gorm.DB) (*StatusWorkflowSettings, error) {
+	var settings StatusWorkflowSettings
+	err := db.First(&settings).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			settings = StatusWorkflowSettings{Enabled: false}
+			if err := db.Create(&settings).Error; err != nil {
+				return nil, err
+			}
+			return &settings, nil
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// CheckStatusTransition returns a *TransitionError when the workflow doesn't allow role
+// to move an event from one status to another. New events (fromStatusID 0) are never restricted.
+func CheckStatusTransition(db *gorm.DB, fromStatusID, toStatusID uint, role string) error {
+	if fromStatusID == 0 || fromStatusID == toStatusID {
+		return nil
+	}
+
+	settings, err := GetOrCreateWorkflowSettings(db)
+	if err != nil {
+		return err
+	}
+	if !settings.Enabled {
+		return nil
+	}
+
+	var transitions []StatusTransition
+	if err := db.Where("from_status_id = ?", fromStatusID).Find(&transitions).Error; err != nil {
+		return err
+	}
+
+	var matched *StatusTransition
+	allowedIDs := []uint{}
+	for i := range transitions {
+		if transitions[i].AllowsRole(role) {
+			allowedIDs = append(allowedIDs, transitions[i].ToStatusID)
+		}
+		if transitions[i].ToStatusID == toStatusID {
+			matched = &transitions[i]
+		}
+	}
+
+	if matched != nil && matched.AllowsRole(role) {
+		return nil
+	}
+
+	transitionErr := &TransitionError{
+		FromStatusID: fromStatusID,
+		ToStatusID:   toStatusID,
+		Reason:       "no such transition in the workflow",
+		Allowed:      []string{},
+	}
+	if matched != nil {
+		transitionErr.Reason = fmt.Sprintf("role %q may not perform this transition", role)
+	}
+
+	// Resolve names for a readable error
+	ids := append([]uint{fromStatusID, toStatusID}, allowedIDs...)
+	var statuses []EventStatusDefinition
+	if err := db.Where("id IN ?", ids).Order("`order` ASC").Find(&statuses).Error; err != nil {
+		return err
+	}
+	namesByID := make(map[uint]string)
+	for _, status := range statuses {
+		namesByID[status.ID] = status.DisplayName
+	}
+	transitionErr.FromStatus = namesByID[fromStatusID]
+	transitionErr.ToStatus = namesByID[toStatusID]
+	for _, status := range statuses {
+		for _, id := range allowedIDs {
+			if status.ID == id {
+				transitionErr.Allowed = append(transitionErr.Allowed, status.DisplayName)
+				break
+			}
+		}
+	}
+
+	return transitionErr
+}
+
+// ValidateStatusTransitions checks that transitions reference existing, distinct statuses
+func ValidateStatusTransitions(db *gorm.DB, requests []StatusTransitionRequest) error {
+	seen := make(map[[2]uint]bool)
+	for _, req := range requests {
+		if req.FromStatusID == req.ToStatusID {
+			return fmt.Errorf("transition from status %d to itself is implicit", req.FromStatusID)
+		}
+		key := [2]uint{req.FromStatusID, req.ToStatusID}
+		if seen[key] {
+			return fmt.Errorf("duplicate transition from status %d to %d", req.FromStatusID, req.ToStatusID)
+		}
+		seen[key] = true
+
+		var count int64
+		if err := db.Model(&EventStatusDefinition{}).Where("id IN ?", []uint{req.FromStatusID, req.ToStatusID}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count != 2 {
+			return fmt.Errorf("transition from status %d to %d references an unknown status", req.FromStatusID, req.ToStatusID)
+		}
+	}
+	return nil
+}
+
+// ReplaceStatusTransitions swaps the whole transition set atomically
+func ReplaceStatusTransitions(db *gorm.DB, requests []StatusTransitionRequest) ([]StatusTransition, error) {
+	transitions := make([]StatusTransition, 0, len(requests))
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&StatusTransition{}).Error; err != nil {
+			return err
+		}
+
+		for _, req := range requests {
+			transition := StatusTransition{FromStatusID: req.FromStatusID, ToStatusID: req.ToStatusID}
+			transition.SetRoles(req.Roles)
+			if err := tx.Create(&transition).Error; err != nil {
+				return err
+			}
+			transitions = append(transitions, transition)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return transitions, nil
+}
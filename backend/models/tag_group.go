@@ -0,0 +1,159 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Tag group selection rules
+const (
+	TagGroupSelectionSingle = "single" // An event may carry at most one tag of the group
+	TagGroupSelectionMulti  = "multi"
+)
+
+// TagGroup organizes tags by dimension, such as "Platform" or "Area"
+type TagGroup struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	Name          string    `json:"name" gorm:"not null;uniqueIndex"`
+	SelectionMode string    `json:"selection_mode" gorm:"not null;default:'multi'"`
+	Order         int       `json:"order" gorm:"default:0"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Tags          []Tag     `json:"tags,omitempty" gorm:"foreignKey:GroupID"`
+}
+
+type CreateTagGroupRequest struct {
+	Name          string `json:"name" binding:"required"`
+	SelectionMode string `json:"selection_mode"`
+	Order         *int   `json:"order"`
+}
+
+type UpdateTagGroupRequest struct {
+	Name          *string `json:"name"`
+	SelectionMode *string `json:"selection_mode"`
+	Order         *int    `json:"order"`
+}
+
+// IsValidTagGroupSelection reports whether mode is a known selection rule
+func IsValidTagGroupSelection(mode string) bool {
+	return mode == TagGroupSelectionSingle || mode == TagGroupSelectionMulti
+}
+
+// TagDescendantIDs returns the given tags plus all their descendants through parent links
+func TagDescendantIDs(db *gorm.DB, rootIDs []uint) ([]uint, error) {
+	var tags []Tag
+	if err := db.Select("id", "parent_id").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	children := make(map[uint][]uint)
+	for _, tag := range tags {
+		if tag.ParentID != nil {
+			children[*tag.ParentID] = append(children[*tag.ParentID], tag.ID)
+		}
+	}
+
+	seen := make(map[uint]bool)
+	queue := append([]uint{}, rootIDs...)
+	result := []uint{}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+		queue = append(queue, children[id]...)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result, nil
+}
+
+// TagGroupTagIDs returns the tags of a group plus all their descendants
+func TagGroupTagIDs(db *gorm.DB, groupID uint) ([]uint, error) {
+	var ids []uint
+	if err := db.Model(&Tag{}).Where("group_id = ?", groupID).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return TagDescendantIDs(db, ids)
+}
+
+// ValidateTagParent checks that parentID exists and doesn't make tagID its own ancestor
+func ValidateTagParent(db *gorm.DB, tagID, parentID uint) error {
+	if tagID != 0 && tagID == parentID {
+		return fmt.Errorf("a tag cannot be its own parent")
+	}
+
+	var parent Tag
+	if err := db.First(&parent, parentID).Error; err != nil {
+		return fmt.Errorf("parent tag %d not found", parentID)
+	}
+
+	if tagID == 0 {
+		return nil
+	}
+	descendants, err := TagDescendantIDs(db, []uint{tagID})
+	if err != nil {
+		return err
+	}
+	for _, id := range descendants {
+		if id == parentID {
+			return fmt.Errorf("tag %q is a descendant of this tag", parent.Name)
+		}
+	}
+	return nil
+}
+
+// ValidateTagSelection checks that a set of tags respects the single-select groups
+func ValidateTagSelection(db *gorm.DB, tagIDs []uint) error {
+	if len(tagIDs) < 2 {
+		return nil
+	}
+
+	var tags []Tag
+	if err := db.Preload("Group").Where("id IN ? AND group_id IS NOT NULL", tagIDs).Find(&tags).Error; err != nil {
+		return err
+	}
+
+	byGroup := make(map[uint][]string)
+	groups := make(map[uint]*TagGroup)
+	for _, tag := range tags {
+		if tag.Group == nil || tag.Group.SelectionMode != TagGroupSelectionSingle {
+			continue
+		}
+		byGroup[tag.Group.ID] = append(byGroup[tag.Group.ID], tag.Name)
+		groups[tag.Group.ID] = tag.Group
+	}
+
+	for groupID, names := range byGroup {
+		if len(names) > 1 {
+			sort.Strings(names)
+			return fmt.Errorf("only one tag of group %q may be selected, got %s", groups[groupID].Name, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+// CountSingleSelectConflicts counts events carrying more than one tag of a group,
+// optionally treating extraTagID as already belonging to it
+func CountSingleSelectConflicts(db *gorm.DB, groupID uint, extraTagID uint) (int64, error) {
+	var count int64
+	err := db.Raw(`
+		SELECT COUNT(*) FROM (
+			SELECT et.event_id
+			FROM event_tags et
+			JOIN tags t ON t.id = et.tag_id
+			JOIN events e ON e.id = et.event_id AND e.deleted_at IS NULL
+			WHERE t.group_id = ? OR t.id = ?
+			GROUP BY et.event_id
+			HAVING COUNT(DISTINCT et.tag_id) > 1
+		)
+	`, groupID, extraTagID).Scan(&count).Error
+	return count, err
+}
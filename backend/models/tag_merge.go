@@ -0,0 +1,216 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// TagMergeResult reports what merging one tag into another changed
+type TagMergeResult struct {
+	SourceID       uint  `json:"source_id"`
+	TargetID       uint  `json:"target_id"`
+	AffectedEvents int64 `json:"affected_events"` // Events that carried the source tag
+	AlreadyTagged  int64 `json:"already_tagged"`  // Of those, events that already carried the target tag
+	ReparentedTags int64 `json:"reparented_tags"` // Child tags moved under the target
+	Subscribers    int64 `json:"subscribers"`     // Subscribers whose topics included the source tag
+}
+
+// SingleSelectMergeConflicts counts events that would end up with two tags of the
+// target's single-select group once the source tag is merged into the target
+func SingleSelectMergeConflicts(db *gorm.DB, source, target *Tag) (int64, error) {
+	if target.GroupID == nil {
+		return 0, nil
+	}
+	var group TagGroup
+	if err := db.First(&group, *target.GroupID).Error; err != nil {
+		return 0, err
+	}
+	if group.SelectionMode != TagGroupSelectionSingle {
+		return 0, nil
+	}
+
+	var count int64
+	err := db.Raw(`
+		SELECT COUNT(DISTINCT src.event_id)
+		FROM event_tags src
+		JOIN event_tags other ON other.event_id = src.event_id
+		JOIN tags t ON t.id = other.tag_id
+		WHERE src.tag_id = ? AND t.group_id = ? AND t.id NOT IN (?, ?)
+	`, source.ID, group.ID, source.ID, target.ID).Scan(&count).Error
+	return count, err
+}
+
+// MergeTag moves every use of the source tag to the target tag and deletes the source,
+// all in one transaction. Events already carrying the target aren't duplicated.
+func MergeTag(db *gorm.DB, sourceID, targetID uint) (*TagMergeResult, error) {
+	if sourceID == targetID {
+		return nil, fmt.Errorf("cannot merge a tag into itself")
+	}
+
+	result := &TagMergeResult{SourceID: sourceID, TargetID: targetID}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var source, target Tag
+		if err := tx.First(&source, sourceID).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&target, targetID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Raw("SELECT COUNT(DISTINCT event_id) FROM event_tags WHERE tag_id = ?", source.ID).Scan(&result.AffectedEvents).Error; err != nil {
+			return err
+		}
+		if err := tx.Raw(`
+			SELECT COUNT(DISTINCT event_id) FROM event_tags
+			WHERE tag_id = ? AND event_id IN (SELECT event_id FROM event_tags WHERE tag_id = ?)
+		`, source.ID, target.ID).Scan(&result.AlreadyTagged).Error; err != nil {
+			return err
+		}
+
+		// Re-point associations, skipping events that already have the target
+		if err := tx.Exec(`
+			INSERT INTO event_tags (event_id, tag_id)
+			SELECT DISTINCT event_id, ? FROM event_tags
+			WHERE tag_id = ? AND event_id NOT IN (SELECT event_id FROM event_tags WHERE tag_id = ?)
+		`, target.ID, source.ID, target.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM event_tags WHERE tag_id = ?", source.ID).Error; err != nil {
+			return err
+		}
+
+		// Subscribers following the source now follow the target
+		if err := tx.Raw("SELECT COUNT(*) FROM subscriber_topic_tags WHERE tag_id = ?", source.ID).Scan(&result.Subscribers).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`
+			INSERT INTO subscriber_topic_tags (newsletter_subscriber_id, tag_id)
+			SELECT newsletter_subscriber_id, ? FROM subscriber_topic_tags
+			WHERE tag_id = ? AND newsletter_subscriber_id NOT IN (SELECT newsletter_subscriber_id FROM subscriber_topic_tags WHERE tag_id = ?)
+		`, target.ID, source.ID, target.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM subscriber_topic_tags WHERE tag_id = ?", source.ID).Error; err != nil {
+			return err
+		}
+
+		// If the target sits below the source, lift it first so reparenting can't create a cycle
+		descendants, err := TagDescendantIDs(tx, []uint{source.ID})
+		if err != nil {
+			return err
+		}
+		for _, id := range descendants {
+			if id == target.ID {
+				if err := tx.Model(&Tag{}).Where("id = ?", target.ID).Update("parent_id", source.ParentID).Error; err != nil {
+					return err
+				}
+				break
+			}
+		}
+
+		reparent := tx.Model(&Tag{}).Where("parent_id = ? AND id != ?", source.ID, target.ID).Update("parent_id", target.ID)
+		if reparent.Error != nil {
+			return reparent.Error
+		}
+		result.ReparentedTags = reparent.RowsAffected
+
+		return tx.Delete(&source).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// TagRename gives a tag a new name
+type TagRename struct {
+	ID   uint   `json:"id" binding:"required"`
+	Name string `json:"name" binding:"required"`
+}
+
+// TagRenameConflictError lists the names that several tags would share after a bulk rename
+type TagRenameConflictError struct {
+	Names []string `json:"names"`
+}
+
+func (e *TagRenameConflictError) Error() string {
+	return fmt.Sprintf("tag names already in use: %s", strings.Join(e.Names, ", "))
+}
+
+// RenameTags renames several tags in one transaction. Names are compared without case against
+// every tag as it will be once renamed, so tags can swap names but never collide.
+func RenameTags(db *gorm.DB, renames []TagRename) ([]Tag, error) {
+	newNames := map[uint]string{}
+	for _, rename := range renames {
+		name := strings.TrimSpace(rename.Name)
+		if name == "" {
+			return nil, fmt.Errorf("the name of tag %d is empty", rename.ID)
+		}
+		if _, seen := newNames[rename.ID]; seen {
+			return nil, fmt.Errorf("tag %d is renamed twice", rename.ID)
+		}
+		newNames[rename.ID] = name
+	}
+
+	renamed := []Tag{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var tags []Tag
+		if err := tx.Find(&tags).Error; err != nil {
+			return err
+		}
+
+		found := map[uint]bool{}
+		owners := map[string]int{}
+		for _, tag := range tags {
+			name := tag.Name
+			if newName, ok := newNames[tag.ID]; ok {
+				found[tag.ID] = true
+				name = newName
+			}
+			owners[strings.ToLower(name)]++
+		}
+		for id := range newNames {
+			if !found[id] {
+				return fmt.Errorf("tag %d not found", id)
+			}
+		}
+
+		conflict := &TagRenameConflictError{Names: []string{}}
+		for _, name := range newNames {
+			if owners[strings.ToLower(name)] > 1 {
+				conflict.Names = append(conflict.Names, name)
+			}
+		}
+		if len(conflict.Names) > 0 {
+			sort.Strings(conflict.Names)
+			return conflict
+		}
+
+		// Free the old names first so tags can swap names without tripping the unique index
+		for id := range newNames {
+			if err := tx.Model(&Tag{}).Where("id = ?", id).Update("name", fmt.Sprintf(":renaming:%d", id)).Error; err != nil {
+				return err
+			}
+		}
+		for _, tag := range tags {
+			newName, ok := newNames[tag.ID]
+			if !ok {
+				continue
+			}
+			if err := tx.Model(&tag).Update("name", newName).Error; err != nil {
+				return err
+			}
+			renamed = append(renamed, tag)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return renamed, nil
+}
@@ -0,0 +1,388 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ThemeBundleFormatVersion is bumped whenever the bundle layout changes incompatibly
+const ThemeBundleFormatVersion = 1
+
+// ThemeBundle is a portable snapshot of theme configuration that can be moved between instances
+type ThemeBundle struct {
+	FormatVersion  int                    `json:"format_version"`
+	ThemeID        string                 `json:"theme_id"`
+	ThemeVersion   string                 `json:"theme_version"`
+	ExportedAt     time.Time              `json:"exported_at"`
+	Settings       map[string]interface{} `json:"settings"`        // setting ID -> value
+	StatusMappings map[string]string      `json:"status_mappings"` // status slug -> category ID
+}
+
+// Theme bundle conflict codes
+const (
+	BundleConflictThemeMismatch    = "theme_mismatch"
+	BundleConflictVersionMismatch  = "version_mismatch"
+	BundleConflictUnknownSetting   = "unknown_setting"
+	BundleConflictInvalidValue     = "invalid_value"
+	BundleConflictUnknownStatus    = "unknown_status"
+	BundleConflictUnknownCategory  = "unknown_category"
+	BundleConflictCategoryNotMulti = "category_not_multiple"
+)
+
+// ThemeBundleConflict describes a bundle entry that cannot be applied as-is
+type ThemeBundleConflict struct {
+	Code     string `json:"code"`
+	Key      string `json:"key,omitempty"` // setting ID or status slug
+	Message  string `json:"message"`
+	Blocking bool   `json:"blocking"` // blocking conflicts prevent the import entirely
+}
+
+// ThemeBundlePlan is the validated result of checking a bundle against the installed theme
+type ThemeBundlePlan struct {
+	ThemeID         string                `json:"theme_id"`
+	SettingChanges  map[string]string     `json:"setting_changes"` // setting ID -> stored value
+	MappingChanges  map[string]string     `json:"mapping_changes"` // status slug -> category ID
+	Conflicts       []ThemeBundleConflict `json:"conflicts"`
+	statusIDsBySlug map[string]uint
+}
+
+// HasBlockingConflicts reports whether the plan must not be applied
+func (p *ThemeBundlePlan) HasBlockingConflicts() bool {
+	for _, conflict := range p.Conflicts {
+		if conflict.Blocking {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseThemeSettingValue converts a stored setting value to its typed representation
+func ParseThemeSettingValue(setting ThemeSetting, raw string) interface{} {
+	switch setting.Type {
+	case "boolean":
+		return raw == "true"
+	case "number":
+		var num float64
+		fmt.Sscanf(raw, "%f", &num)
+		return num
+	case "array", "object":
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+			return setting.Default
+		}
+		return parsed
+	default:
+		return raw
+	}
+}
+
+// FormatThemeSettingValue converts a typed setting value to its stored string representation
+func FormatThemeSettingValue(setting ThemeSetting, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case bool:
+		return fmt.Sprintf("%t", v), nil
+	case float64:
+		return fmt.Sprintf("%v", v), nil
+	case string:
+		return v, nil
+	default:
+		// For arrays and objects, serialize as JSON
+		if setting.Type == "array" || setting.Type == "object" {
+			jsonBytes, err := json.Marshal(v)
+			if err != nil {
+				return "", err
+			}
+			return string(jsonBytes), nil
+		}
+		return fmt.Sprintf("%v", v), nil
+	}
+}
+
+// validateThemeSettingValue checks a typed value against the setting definition
+func validateThemeSettingValue(setting ThemeSetting, value interface{}) error {
+	switch setting.Type {
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			if s, isString := value.(string); !isString || (s != "true" && s != "false") {
+				return fmt.Errorf("expected a boolean")
+			}
+		}
+	case "number":
+		switch v := value.(type) {
+		case float64:
+		case string:
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				return fmt.Errorf("expected a number")
+			}
+		default:
+			return fmt.Errorf("expected a number")
+		}
+	case "select":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected one of the select options")
+		}
+		for _, option := range setting.Options {
+			if option.Value == s {
+				return nil
+			}
+		}
+		return fmt.Errorf("'%s' is not a valid option", s)
+	}
+	return nil
+}
+
+// ExportThemeBundle builds a bundle from the stored setting values and status mappings of a theme
+func ExportThemeBundle(db *gorm.DB, themeID, themeVersion string, manifest *ThemeManifest) (*ThemeBundle, error) {
+	bundle := &ThemeBundle{
+		FormatVersion:  ThemeBundleFormatVersion,
+		ThemeID:        themeID,
+		ThemeVersion:   themeVersion,
+		ExportedAt:     time.Now(),
+		Settings:       make(map[string]interface{}),
+		StatusMappings: make(map[string]string),
+	}
+
+	// Index settings by ID so stored values can be exported with their proper type
+	definitions := make(map[string]ThemeSetting)
+	if manifest != nil {
+		for _, group := range manifest.Settings {
+			for _, setting := range group.Settings {
+				definitions[setting.ID] = setting
+			}
+		}
+	}
+
+	var settingValues []ThemeSettingValue
+	if err := db.Where("theme_id = ?", themeID).Find(&settingValues).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch setting values: %w", err)
+	}
+	for _, sv := range settingValues {
+		if setting, ok := definitions[sv.SettingID]; ok {
+			bundle.Settings[sv.SettingID] = ParseThemeSettingValue(setting, sv.Value)
+		} else {
+			bundle.Settings[sv.SettingID] = sv.Value
+		}
+	}
+
+	var rows []struct {
+		Slug       string
+		CategoryID string
+	}
+	if err := db.Table("status_category_mappings").
+		Select("event_status_definitions.slug, status_category_mappings.category_id").
+		Joins("JOIN event_status_definitions ON event_status_definitions.id = status_category_mappings.status_definition_id").
+		Where("status_category_mappings.theme_id = ?", themeID).
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch status mappings: %w", err)
+	}
+	for _, row := range rows {
+		bundle.StatusMappings[row.Slug] = row.CategoryID
+	}
+
+	return bundle, nil
+}
+
+// PlanThemeBundleImport validates a bundle against the installed manifest and existing statuses
+func PlanThemeBundleImport(db *gorm.DB, bundle *ThemeBundle, themeID, themeVersion string, manifest *ThemeManifest) (*ThemeBundlePlan, error) {
+	plan := &ThemeBundlePlan{
+		ThemeID:         themeID,
+		SettingChanges:  make(map[string]string),
+		MappingChanges:  make(map[string]string),
+		Conflicts:       []ThemeBundleConflict{},
+		statusIDsBySlug: make(map[string]uint),
+	}
+
+	if bundle.FormatVersion > ThemeBundleFormatVersion {
+		plan.Conflicts = append(plan.Conflicts, ThemeBundleConflict{
+			Code:     BundleConflictVersionMismatch,
+			Message:  fmt.Sprintf("Bundle format version %d is newer than supported version %d", bundle.FormatVersion, ThemeBundleFormatVersion),
+			Blocking: true,
+		})
+		return plan, nil
+	}
+
+	if bundle.ThemeID != themeID {
+		plan.Conflicts = append(plan.Conflicts, ThemeBundleConflict{
+			Code:     BundleConflictThemeMismatch,
+			Message:  fmt.Sprintf("Bundle was exported from theme '%s' but '%s' is installed", bundle.ThemeID, themeID),
+			Blocking: true,
+		})
+		return plan, nil
+	}
+
+	if bundle.ThemeVersion != "" && bundle.ThemeVersion != themeVersion {
+		plan.Conflicts = append(plan.Conflicts, ThemeBundleConflict{
+			Code:    BundleConflictVersionMismatch,
+			Message: fmt.Sprintf("Bundle was exported from version %s but %s is installed", bundle.ThemeVersion, themeVersion),
+		})
+	}
+
+	// Validate settings
+	definitions := make(map[string]ThemeSetting)
+	for _, group := range manifest.Settings {
+		for _, setting := range group.Settings {
+			definitions[setting.ID] = setting
+		}
+	}
+
+	for _, settingID := range sortedKeys(bundle.Settings) {
+		value := bundle.Settings[settingID]
+		setting, exists := definitions[settingID]
+		if !exists {
+			plan.Conflicts = append(plan.Conflicts, ThemeBundleConflict{
+				Code:    BundleConflictUnknownSetting,
+				Key:     settingID,
+				Message: fmt.Sprintf("Setting '%s' does not exist in the installed theme", settingID),
+			})
+			continue
+		}
+		if err := validateThemeSettingValue(setting, value); err != nil {
+			plan.Conflicts = append(plan.Conflicts, ThemeBundleConflict{
+				Code:    BundleConflictInvalidValue,
+				Key:     settingID,
+				Message: fmt.Sprintf("Invalid value for setting '%s': %v", settingID, err),
+			})
+			continue
+		}
+		stored, err := FormatThemeSettingValue(setting, value)
+		if err != nil {
+			plan.Conflicts = append(plan.Conflicts, ThemeBundleConflict{
+				Code:    BundleConflictInvalidValue,
+				Key:     settingID,
+				Message: fmt.Sprintf("Invalid value for setting '%s': %v", settingID, err),
+			})
+			continue
+		}
+		plan.SettingChanges[settingID] = stored
+	}
+
+	// Validate mappings
+	categories := make(map[string]ThemeCategory)
+	for _, category := range manifest.Categories {
+		categories[category.ID] = category
+	}
+
+	var statuses []EventStatusDefinition
+	if err := db.Find(&statuses).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch statuses: %w", err)
+	}
+	statusesBySlug := make(map[string]EventStatusDefinition)
+	for _, status := range statuses {
+		statusesBySlug[status.Slug] = status
+	}
+
+	// Start from the current mappings so single-category checks account for statuses not in the bundle
+	var existing []StatusCategoryMapping
+	if err := db.Where("theme_id = ?", themeID).Find(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch status mappings: %w", err)
+	}
+	finalCategoryByStatus := make(map[uint]string)
+	for _, mapping := range existing {
+		finalCategoryByStatus[mapping.StatusDefinitionID] = mapping.CategoryID
+	}
+
+	for _, slug := range sortedKeys(bundle.StatusMappings) {
+		categoryID := bundle.StatusMappings[slug]
+		status, exists := statusesBySlug[slug]
+		if !exists {
+			plan.Conflicts = append(plan.Conflicts, ThemeBundleConflict{
+				Code:    BundleConflictUnknownStatus,
+				Key:     slug,
+				Message: fmt.Sprintf("Status '%s' does not exist on this instance", slug),
+			})
+			continue
+		}
+		if _, exists := categories[categoryID]; !exists {
+			plan.Conflicts = append(plan.Conflicts, ThemeBundleConflict{
+				Code:    BundleConflictUnknownCategory,
+				Key:     slug,
+				Message: fmt.Sprintf("Category '%s' does not exist in the installed theme", categoryID),
+			})
+			continue
+		}
+		plan.MappingChanges[slug] = categoryID
+		plan.statusIDsBySlug[slug] = status.ID
+		finalCategoryByStatus[status.ID] = categoryID
+	}
+
+	// Categories that don't allow multiple statuses must end up with at most one
+	statusCountByCategory := make(map[string]int)
+	for _, categoryID := range finalCategoryByStatus {
+		statusCountByCategory[categoryID]++
+	}
+	for _, slug := range sortedKeys(plan.MappingChanges) {
+		categoryID := plan.MappingChanges[slug]
+		category := categories[categoryID]
+		if !category.Multiple && statusCountByCategory[categoryID] > 1 {
+			plan.Conflicts = append(plan.Conflicts, ThemeBundleConflict{
+				Code:    BundleConflictCategoryNotMulti,
+				Key:     slug,
+				Message: fmt.Sprintf("Category '%s' does not allow multiple statuses", category.Label),
+			})
+			delete(plan.MappingChanges, slug)
+			statusCountByCategory[categoryID]--
+		}
+	}
+
+	return plan, nil
+}
+
+// ApplyThemeBundlePlan writes all changes of a validated plan in a single transaction
+func ApplyThemeBundlePlan(db *gorm.DB, plan *ThemeBundlePlan) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for settingID, value := range plan.SettingChanges {
+			var settingValue ThemeSettingValue
+			err := tx.Where("theme_id = ? AND setting_id = ?", plan.ThemeID, settingID).First(&settingValue).Error
+			if err == nil {
+				settingValue.Value = value
+				if err := tx.Save(&settingValue).Error; err != nil {
+					return fmt.Errorf("failed to update setting %s: %w", settingID, err)
+				}
+				continue
+			}
+			if err != gorm.ErrRecordNotFound {
+				return fmt.Errorf("failed to fetch setting %s: %w", settingID, err)
+			}
+			settingValue = ThemeSettingValue{
+				ThemeID:   plan.ThemeID,
+				SettingID: settingID,
+				Value:     value,
+			}
+			if err := tx.Create(&settingValue).Error; err != nil {
+				return fmt.Errorf("failed to create setting %s: %w", settingID, err)
+			}
+		}
+
+		for slug, categoryID := range plan.MappingChanges {
+			statusID := plan.statusIDsBySlug[slug]
+			mapping, err := GetOrCreateMapping(tx, statusID, plan.ThemeID, categoryID)
+			if err != nil {
+				return fmt.Errorf("failed to map status %s: %w", slug, err)
+			}
+			if mapping.CategoryID != categoryID {
+				mapping.CategoryID = categoryID
+				if err := tx.Save(mapping).Error; err != nil {
+					return fmt.Errorf("failed to map status %s: %w", slug, err)
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// sortedKeys returns map keys in a stable order so reports are deterministic
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,350 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Minimum similarity for two categories to be matched by label
+const categoryMatchThreshold = 0.6
+
+// Status migration reasons
+const (
+	MigrationReasonCategory     = "category"      // follows its old category to the matched new category
+	MigrationReasonKept         = "kept"          // already had a valid mapping for the new theme
+	MigrationReasonSuggested    = "suggested"     // was unmapped before, suggested from its name
+	MigrationReasonNoMatch      = "no_match"      // old category has no counterpart in the new theme
+	MigrationReasonSingleTarget = "single_target" // new category does not allow multiple statuses
+	MigrationReasonOverride     = "override"      // admin explicitly chose to leave it unmapped
+)
+
+// CategoryMigration describes how an old category is carried over to the new theme
+type CategoryMigration struct {
+	OldCategoryID    string  `json:"old_category_id"`
+	OldCategoryLabel string  `json:"old_category_label"`
+	NewCategoryID    string  `json:"new_category_id"` // empty when no match was found
+	NewCategoryLabel string  `json:"new_category_label"`
+	MatchedBy        string  `json:"matched_by"` // id, label, override or empty
+	Score            float64 `json:"score"`
+	StatusCount      int     `json:"status_count"`
+}
+
+// StatusMigration describes the proposed mapping of a single status in the new theme
+type StatusMigration struct {
+	StatusID           uint   `json:"status_id"`
+	StatusName         string `json:"status_name"`
+	OldCategoryID      string `json:"old_category_id"`
+	NewCategoryID      string `json:"new_category_id"` // empty when the status becomes unmapped
+	Reason             string `json:"reason"`
+	PublicEventCount   int64  `json:"public_event_count"`
+	BecomesUnmapped    bool   `json:"becomes_unmapped"`
+	statusDefinitionID uint
+}
+
+// MappingMigrationPlan is the full set of mapping changes proposed when switching themes
+type MappingMigrationPlan struct {
+	FromThemeID        string              `json:"from_theme_id"`
+	ToThemeID          string              `json:"to_theme_id"`
+	Categories         []CategoryMigration `json:"categories"`
+	Statuses           []StatusMigration   `json:"statuses"`
+	UnmappedStatuses   []StatusMigration   `json:"unmapped_statuses"`
+	HiddenPublicEvents int64               `json:"hidden_public_events"`
+}
+
+// PlanMappingMigration proposes status mappings for a new theme based on the mappings of the current one.
+// oldManifest may be nil when the current theme has no readable manifest.
+// overrides maps old category IDs to new category IDs (an empty value leaves the category unmapped).
+func PlanMappingMigration(db *gorm.DB, fromThemeID string, oldManifest *ThemeManifest, toThemeID string, newManifest *ThemeManifest, overrides map[string]string) (*MappingMigrationPlan, error) {
+	plan := &MappingMigrationPlan{
+		FromThemeID:      fromThemeID,
+		ToThemeID:        toThemeID,
+		Categories:       []CategoryMigration{},
+		Statuses:         []StatusMigration{},
+		UnmappedStatuses: []StatusMigration{},
+	}
+
+	var statuses []EventStatusDefinition
+	if err := db.Order("`order` ASC, id ASC").Find(&statuses).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch statuses: %w", err)
+	}
+
+	oldMappings, err := mappingsByStatus(db, fromThemeID)
+	if err != nil {
+		return nil, err
+	}
+	targetMappings, err := mappingsByStatus(db, toThemeID)
+	if err != nil {
+		return nil, err
+	}
+
+	newCategories := make(map[string]ThemeCategory)
+	for _, category := range newManifest.Categories {
+		newCategories[category.ID] = category
+	}
+	oldLabels := make(map[string]string)
+	if oldManifest != nil {
+		for _, category := range oldManifest.Categories {
+			oldLabels[category.ID] = category.Label
+		}
+	}
+
+	// Count how many statuses each old category currently holds
+	statusCountByOldCategory := make(map[string]int)
+	for _, status := range statuses {
+		if categoryID, ok := oldMappings[status.ID]; ok {
+			statusCountByOldCategory[categoryID]++
+		}
+	}
+
+	categoryTargets := matchCategories(statusCountByOldCategory, oldLabels, newManifest.Categories, overrides)
+	for _, oldID := range sortedKeys(statusCountByOldCategory) {
+		migration := categoryTargets[oldID]
+		migration.OldCategoryID = oldID
+		migration.OldCategoryLabel = oldLabels[oldID]
+		if migration.OldCategoryLabel == "" {
+			migration.OldCategoryLabel = oldID
+		}
+		migration.StatusCount = statusCountByOldCategory[oldID]
+		if migration.NewCategoryID != "" {
+			migration.NewCategoryLabel = newCategories[migration.NewCategoryID].Label
+		}
+		categoryTargets[oldID] = migration
+		plan.Categories = append(plan.Categories, migration)
+	}
+
+	// Propose a category for every status, in display order
+	singleCategoryTaken := make(map[string]bool)
+	for _, status := range statuses {
+		migration := StatusMigration{
+			StatusID:           status.ID,
+			StatusName:         status.DisplayName,
+			statusDefinitionID: status.ID,
+		}
+
+		oldCategoryID, wasMapped := oldMappings[status.ID]
+		migration.OldCategoryID = oldCategoryID
+
+		switch {
+		case wasMapped:
+			target := categoryTargets[oldCategoryID]
+			migration.NewCategoryID = target.NewCategoryID
+			migration.Reason = MigrationReasonCategory
+			if target.NewCategoryID == "" {
+				migration.Reason = MigrationReasonNoMatch
+				if target.MatchedBy == "override" {
+					migration.Reason = MigrationReasonOverride
+				}
+			}
+		default:
+			// Keep a valid mapping from a previous use of the new theme, otherwise suggest one
+			if categoryID, ok := targetMappings[status.ID]; ok {
+				if _, valid := newCategories[categoryID]; valid {
+					migration.NewCategoryID = categoryID
+					migration.Reason = MigrationReasonKept
+					break
+				}
+			}
+			migration.NewCategoryID = SuggestCategoryForStatus(status.DisplayName, newManifest.Categories)
+			migration.Reason = MigrationReasonSuggested
+		}
+
+		// Categories without multiple support only take the first status in display order
+		if migration.NewCategoryID != "" && !newCategories[migration.NewCategoryID].Multiple {
+			if singleCategoryTaken[migration.NewCategoryID] {
+				migration.NewCategoryID = ""
+				migration.Reason = MigrationReasonSingleTarget
+			} else {
+				singleCategoryTaken[migration.NewCategoryID] = true
+			}
+		}
+
+		if migration.NewCategoryID == "" {
+			if err := db.Model(&Event{}).
+				Where("status_id = ? AND is_public = ?", status.ID, true).
+				Count(&migration.PublicEventCount).Error; err != nil {
+				return nil, fmt.Errorf("failed to count events for status %s: %w", status.DisplayName, err)
+			}
+			// Only statuses that were visible before are newly hidden
+			migration.BecomesUnmapped = wasMapped
+			if migration.BecomesUnmapped {
+				plan.HiddenPublicEvents += migration.PublicEventCount
+			}
+			plan.UnmappedStatuses = append(plan.UnmappedStatuses, migration)
+		}
+
+		plan.Statuses = append(plan.Statuses, migration)
+	}
+
+	return plan, nil
+}
+
+// ApplyMappingMigration replaces the new theme's mappings with the ones in the plan atomically
+func ApplyMappingMigration(db *gorm.DB, plan *MappingMigrationPlan) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("theme_id = ?", plan.ToThemeID).Delete(&StatusCategoryMapping{}).Error; err != nil {
+			return fmt.Errorf("failed to clear existing mappings: %w", err)
+		}
+
+		for _, status := range plan.Statuses {
+			if status.NewCategoryID == "" {
+				continue
+			}
+			mapping := StatusCategoryMapping{
+				StatusDefinitionID: status.statusDefinitionID,
+				ThemeID:            plan.ToThemeID,
+				CategoryID:         status.NewCategoryID,
+			}
+			if err := tx.Create(&mapping).Error; err != nil {
+				return fmt.Errorf("failed to create mapping for status %s: %w", status.StatusName, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// mappingsByStatus returns status definition ID -> category ID for a theme
+func mappingsByStatus(db *gorm.DB, themeID string) (map[uint]string, error) {
+	result := make(map[uint]string)
+	if themeID == "" {
+		return result, nil
+	}
+
+	var mappings []StatusCategoryMapping
+	if err := db.Where("theme_id = ?", themeID).Find(&mappings).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch mappings for theme %s: %w", themeID, err)
+	}
+	for _, mapping := range mappings {
+		result[mapping.StatusDefinitionID] = mapping.CategoryID
+	}
+	return result, nil
+}
+
+// matchCategories pairs old categories with new ones, best matches first.
+// A new category that doesn't allow multiple statuses can only receive one old category,
+// and is never proposed for an old category that holds several statuses unless nothing else fits.
+func matchCategories(statusCounts map[string]int, oldLabels map[string]string, newCategories []ThemeCategory, overrides map[string]string) map[string]CategoryMigration {
+	result := make(map[string]CategoryMigration)
+	claimed := make(map[string]bool)
+
+	newByID := make(map[string]ThemeCategory)
+	for _, category := range newCategories {
+		newByID[category.ID] = category
+	}
+
+	// Explicit overrides win over any computed match
+	for oldID := range statusCounts {
+		target, ok := overrides[oldID]
+		if !ok {
+			continue
+		}
+		if _, valid := newByID[target]; target != "" && !valid {
+			continue
+		}
+		result[oldID] = CategoryMigration{NewCategoryID: target, MatchedBy: "override", Score: 1}
+		if target != "" && !newByID[target].Multiple {
+			claimed[target] = true
+		}
+	}
+
+	type candidate struct {
+		oldID     string
+		newID     string
+		score     float64
+		matchedBy string
+	}
+	var candidates []candidate
+
+	for oldID, count := range statusCounts {
+		if _, done := result[oldID]; done {
+			continue
+		}
+		oldLabel := oldLabels[oldID]
+		for _, category := range newCategories {
+			score, matchedBy := 0.0, ""
+			if category.ID == oldID {
+				score, matchedBy = 1.0, "id"
+			} else {
+				score = stringSimilarity(oldID, category.ID)
+				if oldLabel != "" {
+					if labelScore := stringSimilarity(oldLabel, category.Label); labelScore > score {
+						score = labelScore
+					}
+				}
+				matchedBy = "label"
+			}
+			if score < categoryMatchThreshold {
+				continue
+			}
+			// Prefer targets that can hold every status of the old category
+			if count > 1 && !category.Multiple {
+				score -= 0.25
+			}
+			candidates = append(candidates, candidate{oldID, category.ID, score, matchedBy})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		if candidates[i].oldID != candidates[j].oldID {
+			return candidates[i].oldID < candidates[j].oldID
+		}
+		return candidates[i].newID < candidates[j].newID
+	})
+
+	for _, cand := range candidates {
+		if _, done := result[cand.oldID]; done {
+			continue
+		}
+		if claimed[cand.newID] {
+			continue
+		}
+		result[cand.oldID] = CategoryMigration{NewCategoryID: cand.newID, MatchedBy: cand.matchedBy, Score: cand.score}
+		if !newByID[cand.newID].Multiple {
+			claimed[cand.newID] = true
+		}
+	}
+
+	return result
+}
+
+// stringSimilarity returns a 0..1 similarity based on the Levenshtein distance of normalized strings
+func stringSimilarity(a, b string) float64 {
+	a = strings.ToLower(strings.TrimSpace(a))
+	b = strings.ToLower(strings.TrimSpace(b))
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
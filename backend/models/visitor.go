@@ -0,0 +1,102 @@
+package models
+
+import (
+	"sync"
+
+	"shipshipship/utils"
+
+	"gorm.io/gorm"
+)
+
+// visitorHashPurpose names the hash key of the hashes stored in place of visitor IDs
+const visitorHashPurpose = "visitor-hash"
+
+// rehashedVisitors holds the visitor hashes whose records no longer need moving from the legacy hash
+var rehashedVisitors sync.Map
+
+// IPVoteLimit caps the votes on an event, and the reactions of each type on it, that can come from
+// one IP address. Visitors sharing an address, such as an office behind NAT, share the limit, so it
+// only stops someone clearing their cookie to vote again and again.
+const IPVoteLimit = 25
+
+// VisitorHash returns the keyed hash of a visitor ID stored with reactions and votes, so the
+// database never holds the cookie value itself. Records stored under the JWT_SECRET signature
+// used before are moved to it the first time the visitor is seen.
+func VisitorHash(db *gorm.DB, visitorID string) (string, error) {
+	db = db.Session(&gorm.Session{NewDB: true})
+	hash, err := KeyedHash(db, visitorHashPurpose, visitorID)
+	if err != nil {
+		return "", err
+	}
+	if _, done := rehashedVisitors.Load(hash); done {
+		return hash, nil
+	}
+
+	legacyHash := utils.SignValue(visitorHashPurpose, visitorID)
+	for _, model := range []interface{}{&EventReaction{}, &Vote{}} {
+		if err := db.Unscoped().Model(model).Where("visitor_hash = ?", legacyHash).
+			UpdateColumn("visitor_hash", hash).Error; err != nil {
+			return "", err
+		}
+	}
+	rehashedVisitors.Store(hash, true)
+	return hash, nil
+}
+
+// VisitorIdentity identifies who a reaction or vote belongs to: a visitor ID hash, or for records
+// made before visitor IDs, an IP address. IP and IPHash are the address a new reaction or vote
+// comes from and its keyed hash, used by ClaimIPRecord and IPVoteLimit.
+type VisitorIdentity struct {
+	Hash   string
+	IP     string
+	IPHash string
+}
+
+// Match restricts a query on reactions or votes to those of the visitor. Records keyed by IP from
+// before visitor IDs are not handed over to a visitor ID in bulk, since everyone behind the same
+// address would otherwise get them; ClaimIPRecord hands them over one at a time. Until then they
+// only match an identity without a hash.
+func (v VisitorIdentity) Match(query *gorm.DB) *gorm.DB {
+	if v.Hash != "" {
+		return query.Where("visitor_hash = ?", v.Hash)
+	}
+	if v.IP == "" {
+		return query.Where("1 = 0")
+	}
+	return query.Where("visitor_hash = '' AND ip_address IN ?", IPAddressKeys(query, v.IP))
+}
+
+// ClaimIPRecord hands the visitor a record made from their IP address before visitor IDs, matching
+// conditions, and reports whether there was one. It is used in place of adding a new reaction or
+// vote: the legacy record is the one the address already made, so it is taken over rather than
+// counted twice. Only that record changes hands, never every record of the address.
+func (v VisitorIdentity) ClaimIPRecord(db *gorm.DB, model interface{}, conditions string, args ...interface{}) (bool, error) {
+	if v.Hash == "" || v.IP == "" {
+		return false, nil
+	}
+	var ids []uint
+	if err := db.Model(model).Where(conditions, args...).
+		Where("visitor_hash = '' AND ip_address IN ?", IPAddressKeys(db, v.IP)).
+		Limit(1).Pluck("id", &ids).Error; err != nil {
+		return false, err
+	}
+	if len(ids) == 0 {
+		return false, nil
+	}
+	claimed := db.Model(model).Where("id = ? AND visitor_hash = ''", ids[0]).
+		Updates(map[string]interface{}{"visitor_hash": v.Hash, "ip_address": "", "ip_hash": v.IPHash})
+	return claimed.RowsAffected > 0, claimed.Error
+}
+
+// IPLimitReached reports whether a query on reactions or votes already holds IPVoteLimit records
+// from the visitor's IP address
+func (v VisitorIdentity) IPLimitReached(query *gorm.DB) (bool, error) {
+	if v.IPHash == "" {
+		return false, nil
+	}
+	var count int64
+	if err := query.Where("ip_hash = ?", v.IPHash).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count >= IPVoteLimit, nil
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"testing"
+
+	"shipshipship/utils"
+)
+
+func TestVisitorIdentity(t *testing.T) {
+	db := newPrivacyTestDB(t, &Vote{})
+
+	db.Create(&Vote{EventID: 1, IPAddress: "192.0.2.1"})
+	db.Create(&Vote{EventID: 1, VisitorHash: "visitor", IPHash: "network"})
+	for i := 0; i < IPVoteLimit; i++ {
+		db.Create(&Vote{EventID: 2, VisitorHash: "other", IPHash: "network"})
+	}
+
+	matches := []struct {
+		name     string
+		identity VisitorIdentity
+		want     int64
+	}{
+		{"visitor ID", VisitorIdentity{Hash: "visitor", IP: "192.0.2.1"}, 1},
+		{"legacy IP record", VisitorIdentity{IP: "192.0.2.1"}, 1},
+		{"nobody", VisitorIdentity{}, 0},
+	}
+	for _, tt := range matches {
+		t.Run(tt.name, func(t *testing.T) {
+			var count int64
+			tt.identity.Match(db.Model(&Vote{}).Where("event_id = ?", 1)).Count(&count)
+			if count != tt.want {
+				t.Errorf("Match found %d votes, want %d", count, tt.want)
+			}
+		})
+	}
+
+	limits := []struct {
+		name     string
+		identity VisitorIdentity
+		eventID  uint
+		want     bool
+	}{
+		{"under the limit", VisitorIdentity{IPHash: "network"}, 1, false},
+		{"at the limit", VisitorIdentity{IPHash: "network"}, 2, true},
+		{"another network", VisitorIdentity{IPHash: "elsewhere"}, 2, false},
+		{"unknown network", VisitorIdentity{}, 2, false},
+	}
+	for _, tt := range limits {
+		t.Run(tt.name, func(t *testing.T) {
+			limited, err := tt.identity.IPLimitReached(db.Model(&Vote{}).Where("event_id = ?", tt.eventID))
+			if err != nil || limited != tt.want {
+				t.Errorf("IPLimitReached = %v, %v, want %v", limited, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVisitorHashMovesLegacyRecords(t *testing.T) {
+	db := newPrivacyTestDB(t, &EventReaction{}, &Vote{})
+
+	legacyHash := utils.SignValue(visitorHashPurpose, "returning")
+	db.Create(&EventReaction{EventID: 1, ReactionType: ReactionHeart, VisitorHash: legacyHash})
+	db.Create(&Vote{EventID: 1, VisitorHash: legacyHash})
+
+	hash, err := VisitorHash(db, "returning")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash == legacyHash {
+		t.Fatal("VisitorHash still uses the JWT_SECRET signature")
+	}
+	if again, _ := VisitorHash(db, "returning"); again != hash {
+		t.Errorf("VisitorHash is not stable: %q, then %q", hash, again)
+	}
+
+	for _, model := range []interface{}{&EventReaction{}, &Vote{}} {
+		var count int64
+		VisitorIdentity{Hash: hash}.Match(db.Model(model)).Count(&count)
+		if count != 1 {
+			t.Errorf("%T: %d records under the new hash, want 1", model, count)
+		}
+	}
+}
+
+func TestClaimIPRecord(t *testing.T) {
+	db := newPrivacyTestDB(t, &Vote{})
+
+	db.Create(&[]Vote{
+		{EventID: 1, IPAddress: "192.0.2.1"},
+		{EventID: 2, IPAddress: "192.0.2.1"},
+		{EventID: 1, IPAddress: "198.51.100.1"},
+	})
+
+	visitor := VisitorIdentity{Hash: "visitor", IP: "192.0.2.1", IPHash: "network"}
+	other := VisitorIdentity{Hash: "other", IP: "192.0.2.1", IPHash: "network"}
+	tests := []struct {
+		name     string
+		identity VisitorIdentity
+		eventID  uint
+		want     bool
+	}{
+		{"legacy vote of the address", visitor, 1, true},
+		{"already handed over", other, 1, false},
+		{"no legacy vote", visitor, 3, false},
+		{"no visitor ID", VisitorIdentity{IP: "192.0.2.1"}, 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claimed, err := tt.identity.ClaimIPRecord(db, &Vote{}, "event_id = ?", tt.eventID)
+			if err != nil || claimed != tt.want {
+				t.Errorf("ClaimIPRecord = %v, %v, want %v", claimed, err, tt.want)
+			}
+		})
+	}
+
+	// Only the one record changes hands
+	var owned, legacy int64
+	visitor.Match(db.Model(&Vote{})).Count(&owned)
+	db.Model(&Vote{}).Where("visitor_hash = ''").Count(&legacy)
+	if owned != 1 || legacy != 2 {
+		t.Errorf("visitor owns %d votes with %d legacy votes left, want 1 and 2", owned, legacy)
+	}
+}
@@ -7,12 +7,14 @@ import (
 )
 
 type Vote struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	EventID   uint           `json:"event_id" gorm:"not null;index"`
-	IPAddress string         `json:"ip_address" gorm:"not null;index"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	EventID     uint           `json:"event_id" gorm:"not null;index"`
+	VisitorHash string         `json:"-" gorm:"not null;default:'';index"` // Hash of the visitor ID cookie
+	IPAddress   string         `json:"ip_address" gorm:"not null;index"`   // Only set on votes made before visitor IDs
+	IPHash      string         `json:"-" gorm:"not null;default:'';index"` // Keyed hash of the voter's IP address, for IPVoteLimit
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relationship
 	Event Event `json:"event" gorm:"foreignKey:EventID"`
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"shipshipship/database"
+	"shipshipship/utils"
+)
+
+// runRekey moves the stored secrets to a new master key. Stop the server first, as it caches the current key.
+//
+//	main rekey                      generate a new key and replace the key file
+//	main rekey -new-key-file path   use (or create) another key file
+//	main rekey -new-key <base64>    use the given key, e.g. for SECRETS_KEY
+func runRekey(args []string) {
+	flags := flag.NewFlagSet("rekey", flag.ExitOnError)
+	newKeyValue := flags.String("new-key", "", "new master key, base64-encoded")
+	newKeyFile := flags.String("new-key-file", "", "file holding the new master key; created with a new key if missing")
+	flags.Parse(args)
+
+	oldKey, err := utils.SecretsKey()
+	if err != nil {
+		log.Fatalf("Failed to load the current secrets key: %v", err)
+	}
+
+	// replaceKeyFile is set when the new key goes into the current key file, once the secrets are rewrapped
+	replaceKeyFile := ""
+	var newKey []byte
+	switch {
+	case *newKeyValue != "":
+		if newKey, err = utils.ParseSecretsKey(*newKeyValue); err != nil {
+			log.Fatalf("Invalid -new-key: %v", err)
+		}
+	case *newKeyFile != "":
+		content, err := os.ReadFile(*newKeyFile)
+		if os.IsNotExist(err) {
+			encoded, err := utils.GenerateSecretsKey()
+			if err != nil {
+				log.Fatalf("Failed to generate a secrets key: %v", err)
+			}
+			if err := utils.WriteSecretsKeyFile(*newKeyFile, encoded); err != nil {
+				log.Fatalf("Failed to write %s: %v", *newKeyFile, err)
+			}
+			content = []byte(encoded)
+		} else if err != nil {
+			log.Fatalf("Failed to read %s: %v", *newKeyFile, err)
+		}
+		if newKey, err = utils.ParseSecretsKey(string(content)); err != nil {
+			log.Fatalf("%s: %v", *newKeyFile, err)
+		}
+	case os.Getenv("SECRETS_KEY") != "":
+		log.Fatal("The current key comes from SECRETS_KEY: pass the new key with -new-key or -new-key-file")
+	default:
+		encoded, err := utils.GenerateSecretsKey()
+		if err != nil {
+			log.Fatalf("Failed to generate a secrets key: %v", err)
+		}
+		newKey, _ = utils.ParseSecretsKey(encoded)
+		replaceKeyFile = utils.SecretsKeyFile()
+		// Written next to the current file first, so the key is never lost if the swap fails
+		if err := utils.WriteSecretsKeyFile(replaceKeyFile+".new", encoded); err != nil {
+			log.Fatalf("Failed to write the new key: %v", err)
+		}
+	}
+
+	database.InitDatabase()
+	count, err := database.RekeySecrets(database.GetDB(), oldKey, newKey)
+	if err != nil {
+		log.Fatalf("Re-keying failed, no secrets were changed: %v", err)
+	}
+	fmt.Printf("Re-encrypted %d secrets with the new key.\n", count)
+
+	if replaceKeyFile == "" {
+		fmt.Println("Point SECRETS_KEY or SECRETS_KEY_FILE at the new key before restarting the server.")
+		return
+	}
+	// The rename replaces the old key, which no stored secret needs any more
+	if err := os.Rename(replaceKeyFile+".new", replaceKeyFile); err != nil {
+		log.Fatalf("Secrets now use the key in %s.new, but the key file couldn't be replaced: %v", replaceKeyFile, err)
+	}
+	fmt.Printf("Replaced %s. The previous key was discarded; backups of the database made before now still need it.\n", replaceKeyFile)
+}
@@ -7,6 +7,8 @@ import (
 	"strings"
 	"time"
 
+	"shipshipship/models"
+
 	"gorm.io/gorm"
 )
 
@@ -39,6 +41,7 @@ func (cs *CleanupService) Start() {
 
 	// Run immediately on start
 	cs.runCleanup()
+	cs.runIPRetention()
 
 	// Then run periodically
 	ticker := time.NewTicker(cleanupInterval)
@@ -47,6 +50,7 @@ func (cs *CleanupService) Start() {
 			select {
 			case <-ticker.C:
 				cs.runCleanup()
+				cs.runIPRetention()
 			case <-cs.stopChan:
 				ticker.Stop()
 				fmt.Println("Cleanup service stopped")
@@ -121,6 +125,22 @@ func (cs *CleanupService) runCleanup() {
 	fmt.Printf("Cleanup complete: %d deleted, %d kept, %d errors\n", deletedCount, skippedCount, errorCount)
 }
 
+// runIPRetention hashes the reaction and vote IP addresses, and deletes the email delivery records,
+// older than the configured retention period
+func (cs *CleanupService) runIPRetention() {
+	result, err := models.ApplyRetention(cs.db, time.Now())
+	if err != nil {
+		fmt.Printf("Error applying the retention period: %v\n", err)
+		return
+	}
+	if result.AnonymizedIPs > 0 {
+		fmt.Printf("Anonymized the IP address of %d reactions and votes\n", result.AnonymizedIPs)
+	}
+	if result.DeletedDeliveries > 0 {
+		fmt.Printf("Deleted %d email delivery records\n", result.DeletedDeliveries)
+	}
+}
+
 // getReferencedFiles retrieves all filenames referenced in events
 func (cs *CleanupService) getReferencedFiles() map[string]bool {
 	referenced := make(map[string]bool)
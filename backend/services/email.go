@@ -2,16 +2,18 @@ package services
 
 import (
 	"fmt"
-	"net/smtp"
-	"strings"
+	"log"
 
 	"shipshipship/database"
+	"shipshipship/email"
 	"shipshipship/models"
-	"shipshipship/utils"
 )
 
 type EmailService struct {
-	mailSettings *models.MailSettings
+	mailSettings   *models.MailSettings
+	loadedSettings bool // Settings came from the database and are reloaded after Close
+	transport      Transport
+	signer         *email.DKIMSigner
 }
 
 // NewEmailService creates a new email service instance
@@ -19,8 +21,28 @@ func NewEmailService() *EmailService {
 	return &EmailService{}
 }
 
-// SendEmail sends an email to a single recipient
+// NewEmailServiceWithSettings creates an email service that sends with the given settings
+func NewEmailServiceWithSettings(settings *models.MailSettings) *EmailService {
+	return &EmailService{mailSettings: settings}
+}
+
+// SendEmail sends an email to a single recipient, with a plain-text part derived from the HTML
 func (es *EmailService) SendEmail(to, subject, htmlContent string) error {
+	return es.SendEmailWithText(to, subject, htmlContent, "")
+}
+
+// SendEmailWithText sends an email with its own plain-text part; an empty text is derived from the HTML
+func (es *EmailService) SendEmailWithText(to, subject, htmlContent, textContent string) error {
+	return es.SendMessage(&email.Message{
+		To:      to,
+		Subject: subject,
+		HTML:    htmlContent,
+		Text:    textContent,
+	})
+}
+
+// SendMessage sends a message through the configured transport. The sender defaults to the mail settings.
+func (es *EmailService) SendMessage(message *email.Message) error {
 	// Get mail settings
 	if es.mailSettings == nil {
 		db := database.GetDB()
@@ -29,45 +51,86 @@ func (es *EmailService) SendEmail(to, subject, htmlContent string) error {
 			return fmt.Errorf("failed to get mail settings: %v", err)
 		}
 		es.mailSettings = settings
+		es.loadedSettings = true
+	}
+
+	if es.transport == nil {
+		transport, err := NewTransport(es.mailSettings)
+		if err != nil {
+			return err
+		}
+		es.transport = transport
+
+		signer, err := NewDKIMSigner(es.mailSettings)
+		if err != nil {
+			return err
+		}
+		es.signer = signer
 	}
 
-	// Validate settings
-	if es.mailSettings.SMTPHost == "" || es.mailSettings.FromEmail == "" {
-		return fmt.Errorf("SMTP host and from email must be configured")
+	if message.FromEmail == "" {
+		message.FromEmail = es.mailSettings.FromEmail
+	}
+	if message.FromName == "" {
+		message.FromName = es.mailSettings.FromName
+	}
+	if message.FromName == "" {
+		message.FromName = "ShipShipShip"
 	}
 
-	// Prepare email content
-	fromName := es.mailSettings.FromName
-	if fromName == "" {
-		fromName = "ShipShipShip"
+	raw, err := message.Bytes()
+	if err != nil {
+		return fmt.Errorf("failed to build email: %v", err)
 	}
 
-	from := fmt.Sprintf("%s <%s>", fromName, es.mailSettings.FromEmail)
+	if es.signer != nil {
+		if raw, err = es.signer.Sign(raw); err != nil {
+			return fmt.Errorf("failed to DKIM-sign email: %v", err)
+		}
+	}
 
-	// Create email message
-	message := fmt.Sprintf("From: %s\r\n", from)
-	message += fmt.Sprintf("To: %s\r\n", to)
-	message += fmt.Sprintf("Subject: %s\r\n", subject)
-	message += "Content-Type: text/html; charset=UTF-8\r\n"
-	message += "\r\n"
-	message += htmlContent
+	sendErr := es.transport.Send(message, raw)
+	if db := database.GetDB(); db != nil {
+		if err := models.RecordEmailDelivery(db, message.To, message.Subject, sendErr); err != nil {
+			log.Printf("Failed to record email delivery to %s: %v", message.To, err)
+		}
+	}
+	return sendErr
+}
 
-	// Determine authentication
-	var auth smtp.Auth
-	if es.mailSettings.SMTPUsername != "" {
-		auth = smtp.PlainAuth("", es.mailSettings.SMTPUsername, es.mailSettings.SMTPPassword, es.mailSettings.SMTPHost)
+// Close ends the transport session, such as an open SMTP connection. Call it once a batch is sent;
+// the next message reconnects, with the latest mail settings.
+func (es *EmailService) Close() error {
+	if es.loadedSettings {
+		es.mailSettings = nil
+		es.loadedSettings = false
+	}
+	if es.transport == nil {
+		return nil
+	}
+	err := es.transport.Close()
+	es.transport = nil
+	es.signer = nil
+	return err
+}
+
+// NewDKIMSigner returns the signer configured in the mail settings, or nil when DKIM is off
+func NewDKIMSigner(settings *models.MailSettings) (*email.DKIMSigner, error) {
+	if !settings.DKIMEnabled {
+		return nil, nil
 	}
 
-	// Send email based on encryption type
-	addr := fmt.Sprintf("%s:%d", es.mailSettings.SMTPHost, es.mailSettings.SMTPPort)
-
-	switch strings.ToLower(es.mailSettings.SMTPEncryption) {
-	case "ssl":
-		return utils.SendMailWithSSL(addr, auth, es.mailSettings.FromEmail, []string{to}, []byte(message))
-	case "tls":
-		return utils.SendMailWithTLS(addr, auth, es.mailSettings.FromEmail, []string{to}, []byte(message))
-	default:
-		// No encryption
-		return smtp.SendMail(addr, auth, es.mailSettings.FromEmail, []string{to}, []byte(message))
+	key, err := email.ParseDKIMPrivateKey(settings.DKIMPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	signer := &email.DKIMSigner{
+		Domain:     settings.DKIMSigningDomain(),
+		Selector:   settings.DKIMSelector,
+		PrivateKey: key,
+	}
+	if signer.Domain == "" || signer.Selector == "" {
+		return nil, fmt.Errorf("DKIM signing needs a domain and a selector")
 	}
+	return signer, nil
 }
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"shipshipship/email"
+	"shipshipship/models"
+	"shipshipship/utils"
+)
+
+// Transport delivers an encoded email. The message is passed along for transports
+// that post structured fields rather than the raw RFC 5322 bytes.
+type Transport interface {
+	Send(message *email.Message, raw []byte) error
+	Close() error // Releases connections held between messages
+}
+
+// NewTransport returns the transport selected in the mail settings
+func NewTransport(settings *models.MailSettings) (Transport, error) {
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch settings.TransportName() {
+	case models.MailTransportSendmail:
+		return &SendmailTransport{Path: settings.SendmailPath}, nil
+	case models.MailTransportFile:
+		return &FileTransport{Directory: settings.FileDirectory}, nil
+	case models.MailTransportMaildir:
+		return &FileTransport{Directory: settings.FileDirectory, Maildir: true}, nil
+	case models.MailTransportAPI:
+		return &APITransport{URL: settings.APIURL, APIKey: settings.APIKey}, nil
+	default:
+		return &SMTPTransport{
+			Host:             settings.SMTPHost,
+			Port:             settings.SMTPPort,
+			Username:         settings.SMTPUsername,
+			Password:         settings.SMTPPassword,
+			Encryption:       settings.SMTPEncryption,
+			MaxPerConnection: settings.SMTPMaxPerConnection,
+			Delay:            time.Duration(settings.SMTPMessageDelayMs) * time.Millisecond,
+		}, nil
+	}
+}
+
+// smtpIdleTimeout is how long an SMTP session may sit unused before it's replaced, as servers drop idle clients
+const smtpIdleTimeout = 30 * time.Second
+
+// SMTPTransport sends through an SMTP server, keeping the session open between messages
+type SMTPTransport struct {
+	Host             string
+	Port             int
+	Username         string
+	Password         string
+	Encryption       string        // ssl, tls (STARTTLS) or none
+	MaxPerConnection int           // Messages sent before reconnecting; 0 for no limit
+	Delay            time.Duration // Pause between messages
+
+	mutex            sync.Mutex
+	client           *smtp.Client
+	sentOnConnection int
+	lastSend         time.Time
+}
+
+// Send delivers the message over SMTP. A failure on a reused session, or a 421 from the server,
+// is retried once on a fresh connection.
+func (t *SMTPTransport) Send(message *email.Message, raw []byte) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.Delay > 0 && !t.lastSend.IsZero() {
+		if wait := t.Delay - time.Since(t.lastSend); wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+	defer func() { t.lastSend = time.Now() }()
+
+	if t.client != nil && time.Since(t.lastSend) > smtpIdleTimeout {
+		t.closeSession()
+	}
+	reused := t.client != nil
+
+	err := t.send(message, raw)
+	if err != nil && (isSMTPCode(err, 421) || (reused && !isSMTPReply(err))) {
+		t.closeSession()
+		err = t.send(message, raw)
+	}
+	if err != nil {
+		// The session state is unknown after a failure
+		t.closeSession()
+	}
+	return err
+}
+
+func (t *SMTPTransport) send(message *email.Message, raw []byte) error {
+	if t.client == nil {
+		// Determine authentication
+		var auth smtp.Auth
+		if t.Username != "" {
+			auth = smtp.PlainAuth("", t.Username, t.Password, t.Host)
+		}
+
+		client, err := utils.DialSMTP(fmt.Sprintf("%s:%d", t.Host, t.Port), t.Encryption, auth)
+		if err != nil {
+			return err
+		}
+		t.client = client
+		t.sentOnConnection = 0
+	}
+
+	if err := utils.SendWithClient(t.client, message.FromEmail, []string{message.To}, raw); err != nil {
+		return err
+	}
+
+	t.sentOnConnection++
+	if t.MaxPerConnection > 0 && t.sentOnConnection >= t.MaxPerConnection {
+		t.closeSession()
+	}
+	return nil
+}
+
+// closeSession ends the SMTP session, dropping the connection if QUIT fails
+func (t *SMTPTransport) closeSession() {
+	if t.client == nil {
+		return
+	}
+	if err := t.client.Quit(); err != nil {
+		t.client.Close()
+	}
+	t.client = nil
+}
+
+// Close ends the SMTP session
+func (t *SMTPTransport) Close() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.closeSession()
+	return nil
+}
+
+// isSMTPReply reports whether err is a reply from the server rather than a connection failure
+func isSMTPReply(err error) bool {
+	var reply *textproto.Error
+	return errors.As(err, &reply)
+}
+
+func isSMTPCode(err error, code int) bool {
+	var reply *textproto.Error
+	return errors.As(err, &reply) && reply.Code == code
+}
+
+// SendmailTransport pipes messages to the local sendmail binary
+type SendmailTransport struct {
+	Path string
+}
+
+// Send delivers the message with sendmail
+func (t *SendmailTransport) Send(message *email.Message, raw []byte) error {
+	path := t.Path
+	if path == "" {
+		path = "/usr/sbin/sendmail"
+	}
+
+	// -i keeps a line with a single dot from ending the message
+	cmd := exec.Command(path, "-i", "-f", message.FromEmail, "--", message.To)
+	cmd.Stdin = bytes.NewReader(raw)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if output := strings.TrimSpace(stderr.String()); output != "" {
+			return fmt.Errorf("sendmail failed: %v: %s", err, output)
+		}
+		return fmt.Errorf("sendmail failed: %v", err)
+	}
+	return nil
+}
+
+// Close does nothing, as each message runs its own sendmail
+func (t *SendmailTransport) Close() error {
+	return nil
+}
+
+// FileTransport writes messages as .eml files, or into a maildir, for development and testing
+type FileTransport struct {
+	Directory string
+	Maildir   bool
+}
+
+// Send writes the message to the directory
+func (t *FileTransport) Send(message *email.Message, raw []byte) error {
+	random := make([]byte, 6)
+	if _, err := rand.Read(random); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d.%s", time.Now().UnixNano(), hex.EncodeToString(random))
+
+	if !t.Maildir {
+		if err := os.MkdirAll(t.Directory, 0755); err != nil {
+			return fmt.Errorf("failed to create mail directory: %v", err)
+		}
+		return os.WriteFile(filepath.Join(t.Directory, name+".eml"), raw, 0644)
+	}
+
+	// Maildir delivery writes to tmp and then moves the file to new, so readers never see partial messages
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(t.Directory, sub), 0755); err != nil {
+			return fmt.Errorf("failed to create maildir: %v", err)
+		}
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "localhost"
+	}
+	name = name + "." + strings.NewReplacer("/", "_", ":", "_").Replace(hostname)
+
+	tmpPath := filepath.Join(t.Directory, "tmp", name)
+	if err := os.WriteFile(tmpPath, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filepath.Join(t.Directory, "new", name))
+}
+
+// Close does nothing, as files are written whole
+func (t *FileTransport) Close() error {
+	return nil
+}
+
+// APITransport posts messages as JSON to a transactional mail provider or any compatible endpoint
+type APITransport struct {
+	URL    string
+	APIKey string
+	Client *http.Client
+}
+
+// apiMessage is the JSON body posted by APITransport
+type apiMessage struct {
+	From     string `json:"from"`
+	FromName string `json:"from_name,omitempty"`
+	To       string `json:"to"`
+	Subject  string `json:"subject"`
+	HTML     string `json:"html,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Raw      string `json:"raw"` // Base64 of the full MIME message, for providers that accept raw mail
+}
+
+// Send posts the message to the API
+func (t *APITransport) Send(message *email.Message, raw []byte) error {
+	text := message.Text
+	if text == "" && message.HTML != "" {
+		text = email.HTMLToText(message.HTML)
+	}
+
+	body, err := json.Marshal(apiMessage{
+		From:     message.FromEmail,
+		FromName: message.FromName,
+		To:       message.To,
+		Subject:  message.Subject,
+		HTML:     message.HTML,
+		Text:     text,
+		Raw:      base64.StdEncoding.EncodeToString(raw),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if t.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.APIKey)
+	}
+
+	client := t.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("mail API request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("mail API returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// Close releases idle HTTP connections
+func (t *APITransport) Close() error {
+	if t.Client != nil {
+		t.Client.CloseIdleConnections()
+	}
+	return nil
+}
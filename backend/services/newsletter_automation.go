@@ -1,11 +1,9 @@
 package services
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
 	"os"
-	"strings"
 	"time"
 
 	"shipshipship/constants"
@@ -45,14 +43,15 @@ func (nas *NewsletterAutomationService) getBaseURL() string {
 // (legacy constants still referenced elsewhere for backward compatibility)
 // Placeholder kept to preserve line numbers.
 
-// ProcessStatusChange checks if automation should be triggered and sends newsletters
-func (nas *NewsletterAutomationService) ProcessStatusChange(eventID uint, oldStatus, newStatus models.EventStatus) error {
+// ProcessStatusChange checks if automation should be triggered and sends newsletters.
+// It reports whether an automated newsletter was sent.
+func (nas *NewsletterAutomationService) ProcessStatusChange(eventID uint, oldStatusID, newStatusID uint) (bool, error) {
 	// Skip if status hasn't actually changed
-	if oldStatus == newStatus {
-		return nil
+	if oldStatusID == newStatusID {
+		return false, nil
 	}
 
-	log.Printf("Processing status change for event %d: %s -> %s", eventID, oldStatus, newStatus)
+	log.Printf("Processing status change for event %d: %d -> %d", eventID, oldStatusID, newStatusID)
 
 	// Safety check: prevent automation for rapid successive changes
 	// Check if an email was sent for this event in the last 30 seconds
@@ -64,60 +63,48 @@ func (nas *NewsletterAutomationService) ProcessStatusChange(eventID uint, oldSta
 
 	if recentEmailCount > 0 {
 		log.Printf("Skipping automation for event %d - email sent recently (within 30 seconds)", eventID)
-		return nil
+		return false, nil
 	}
 
 	// Get automation settings
 	automationSettings, err := models.GetOrCreateAutomationSettings(nas.db)
 	if err != nil {
-		return fmt.Errorf("failed to get automation settings: %v", err)
+		return false, fmt.Errorf("failed to get automation settings: %v", err)
 	}
 
 	// Check if automation is enabled
 	if !automationSettings.Enabled {
 		log.Printf("Newsletter automation is disabled, skipping event %d", eventID)
-		return nil
-	}
-
-	// Parse trigger statuses
-	var triggerStatuses []string
-	if err := json.Unmarshal([]byte(automationSettings.TriggerStatuses), &triggerStatuses); err != nil {
-		return fmt.Errorf("failed to parse trigger statuses: %v", err)
+		return false, nil
 	}
 
 	// Check if new status is in trigger list
-	shouldTrigger := false
-	for _, status := range triggerStatuses {
-		if string(newStatus) == status {
-			shouldTrigger = true
-			break
-		}
-	}
-
-	if !shouldTrigger {
-		log.Printf("Status %s is not in trigger list for event %d, skipping", newStatus, eventID)
-		return nil
+	if !automationSettings.HasTriggerStatus(newStatusID) {
+		log.Printf("Status %d is not in trigger list for event %d, skipping", newStatusID, eventID)
+		return false, nil
 	}
 
-	log.Printf("Triggering automated newsletter for event %d with status %s", eventID, newStatus)
+	log.Printf("Triggering automated newsletter for event %d with status %d", eventID, newStatusID)
 
 	// Send automated newsletter
-	return nas.sendAutomatedNewsletter(eventID, newStatus)
+	if err := nas.sendAutomatedNewsletter(eventID); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 // sendAutomatedNewsletter sends a newsletter for an event based on its status
-func (nas *NewsletterAutomationService) sendAutomatedNewsletter(eventID uint, status models.EventStatus) error {
-	// Get the event with tags
+func (nas *NewsletterAutomationService) sendAutomatedNewsletter(eventID uint) error {
+	// Get the event with tags and status definition
 	var event models.Event
-	if err := nas.db.Preload("Tags").First(&event, eventID).Error; err != nil {
+	if err := nas.db.Preload("Tags").Preload("StatusDefinition").First(&event, eventID).Error; err != nil {
 		return fmt.Errorf("failed to get event: %v", err)
 	}
 
-	// Get status definition for color
-	var statusDef models.EventStatusDefinition
-	if err := nas.db.Where("display_name = ?", event.Status).First(&statusDef).Error; err != nil {
-		return fmt.Errorf("failed to get status definition: %v", err)
+	if event.StatusDefinition == nil {
+		return fmt.Errorf("failed to get status definition for event %d", eventID)
 	}
+	statusDef := event.StatusDefinition
 
 	// Get branding settings with base URL
 	baseURL := nas.getBaseURL()
@@ -127,7 +114,7 @@ func (nas *NewsletterAutomationService) sendAutomatedNewsletter(eventID uint, st
 	}
 
 	// Get the email template from database or use default
-	template, err := models.GetEmailTemplate(nas.db, "event")
+	template, err := models.GetEmailTemplateForStatus(nas.db, statusDef.ID)
 	if err != nil {
 		// If template doesn't exist, use default content
 		log.Printf("Template not found in DB, using defaults for type: event")
@@ -146,36 +133,52 @@ func (nas *NewsletterAutomationService) sendAutomatedNewsletter(eventID uint, st
 		}
 	}
 
-	// Generate the email content with variable replacements
-	subject, content, err := email.GenerateEmailContent(nas.db, template, &event, &statusDef, branding)
+	// Render the template; the copy without personal links is kept in the publication record
+	templateContext := email.NewEventContext(&event, statusDef, branding)
+	subject, content, err := email.RenderEmailTemplate(template, templateContext)
 	if err != nil {
 		return fmt.Errorf("failed to generate email content: %v", err)
 	}
 
-	// Get active newsletter subscribers
-	subscribers, err := models.GetActiveNewsletterSubscribers(nas.db)
+	// Get the subscribers whose topics match the event's tags; digest subscribers get it in their next digest
+	followers, err := models.GetNewsletterSubscribersForEvent(nas.db, &event)
 	if err != nil {
 		return fmt.Errorf("failed to get newsletter subscribers: %v", err)
 	}
+	subscribers := []models.NewsletterSubscriber{}
+	for _, subscriber := range followers {
+		if subscriber.ReceivesInstantNewsletters() {
+			subscribers = append(subscribers, subscriber)
+		}
+	}
 
 	if len(subscribers) == 0 {
-		log.Printf("No active newsletter subscribers found for event %d", eventID)
+		log.Printf("No newsletter subscribers follow the topics of event %d", eventID)
 		return nil
 	}
 
-	// Send emails to all subscribers
+	// Send emails to all subscribers over one session
+	defer nas.emailService.Close()
 	sentCount := 0
 	var sendErrors []string
 
-	for _, subscriber := range subscribers {
-		// Personalize unsubscribe URL for each subscriber (use BaseURL, not ProjectURL)
-		unsubscribeURL := fmt.Sprintf("%s/unsubscribe?email=%s", branding.BaseURL, subscriber.Email)
-		if branding.BaseURL == "" {
-			unsubscribeURL = fmt.Sprintf("/unsubscribe?email=%s", subscriber.Email)
+	for i := range subscribers {
+		subscriber := &subscribers[i]
+		localizedTemplate, localizedBranding := email.Localize(template, branding, subscriber.Language)
+		personalContext := email.NewEventContext(&event, statusDef, localizedBranding).ForSubscriber(subscriber)
+		personalSubject, personalContent, err := email.RenderEmailTemplate(localizedTemplate, personalContext)
+		var personalText string
+		if err == nil {
+			personalText, err = email.RenderEmailText(localizedTemplate, personalContext)
+		}
+		if err != nil {
+			errorMsg := fmt.Sprintf("failed to generate email content for %s: %v", subscriber.Email, err)
+			sendErrors = append(sendErrors, errorMsg)
+			log.Printf("Newsletter automation error: %s", errorMsg)
+			continue
 		}
-		personalizedContent := strings.ReplaceAll(content, "{{unsubscribe_url}}", unsubscribeURL)
 
-		err := nas.emailService.SendEmail(subscriber.Email, subject, personalizedContent)
+		err = nas.emailService.SendEmailWithText(subscriber.Email, personalSubject, personalContent, personalText)
 		if err != nil {
 			errorMsg := fmt.Sprintf("failed to send to %s: %v", subscriber.Email, err)
 			sendErrors = append(sendErrors, errorMsg)
@@ -189,9 +192,9 @@ func (nas *NewsletterAutomationService) sendAutomatedNewsletter(eventID uint, st
 	now := time.Now()
 	historyRecord := &models.EventEmailHistory{
 		EventID:         eventID,
-		EventStatus:     string(status),
+		EventStatus:     statusDef.DisplayName,
 		EmailSubject:    subject,
-		EmailTemplate:   template.Type,
+		EmailTemplate:   template.DisplayName(),
 		SubscriberCount: sentCount,
 		SentAt:          now,
 	}
@@ -212,7 +215,7 @@ func (nas *NewsletterAutomationService) sendAutomatedNewsletter(eventID uint, st
 				EmailSent:       true,
 				EmailSubject:    subject,
 				EmailContent:    content,
-				EmailTemplate:   template.Type,
+				EmailTemplate:   template.DisplayName(),
 				EmailSentAt:     &now,
 				SubscriberCount: sentCount,
 			}
@@ -228,7 +231,7 @@ func (nas *NewsletterAutomationService) sendAutomatedNewsletter(eventID uint, st
 			"email_sent":       true,
 			"email_subject":    subject,
 			"email_content":    content,
-			"email_template":   template.Type,
+			"email_template":   template.DisplayName(),
 			"email_sent_at":    &now,
 			"subscriber_count": sentCount,
 		}
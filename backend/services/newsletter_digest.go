@@ -0,0 +1,243 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"shipshipship/constants"
+	"shipshipship/email"
+	"shipshipship/models"
+
+	"gorm.io/gorm"
+)
+
+// How often to check whether digests are due
+const digestCheckInterval = time.Hour
+
+// DigestService periodically sends weekly and monthly digest newsletters
+type DigestService struct {
+	db           *gorm.DB
+	emailService *EmailService
+	stopChan     chan struct{}
+}
+
+// NewDigestService creates a new digest service
+func NewDigestService(db *gorm.DB) *DigestService {
+	return &DigestService{
+		db:           db,
+		emailService: NewEmailService(),
+		stopChan:     make(chan struct{}),
+	}
+}
+
+// Start begins the periodic digest checks
+func (ds *DigestService) Start() {
+	fmt.Println("Digest service started")
+
+	ticker := time.NewTicker(digestCheckInterval)
+	go func() {
+		// Catch up on digests that fell due while the server was down
+		ds.SendDueDigests(time.Now())
+
+		for {
+			select {
+			case <-ticker.C:
+				ds.SendDueDigests(time.Now())
+			case <-ds.stopChan:
+				ticker.Stop()
+				fmt.Println("Digest service stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the digest service
+func (ds *DigestService) Stop() {
+	close(ds.stopChan)
+}
+
+// getDigestTemplate returns the digest email template from the database or the default one
+func (ds *DigestService) getDigestTemplate() *models.EmailTemplate {
+	template, err := models.GetEmailTemplate(ds.db, constants.TemplateTypeDigest)
+	if err == nil {
+		return template
+	}
+	defaultTemplate := constants.GetTemplateByType(constants.TemplateTypeDigest)
+	return &models.EmailTemplate{
+		Type:    defaultTemplate.Type,
+		Subject: defaultTemplate.Subject,
+		Content: defaultTemplate.Content,
+	}
+}
+
+// SendDueDigests sends the digests of the periods that ended before now
+func (ds *DigestService) SendDueDigests(now time.Time) {
+	automationSettings, err := models.GetOrCreateAutomationSettings(ds.db)
+	if err != nil {
+		log.Printf("Digest: failed to get automation settings: %v", err)
+		return
+	}
+	if !automationSettings.Enabled {
+		return
+	}
+
+	for _, frequency := range []string{models.DigestFrequencyWeekly, models.DigestFrequencyMonthly} {
+		if err := ds.sendDigests(frequency, automationSettings.GetTriggerStatusIDs(), now); err != nil {
+			log.Printf("Digest: failed to send %s digests: %v", frequency, err)
+		}
+	}
+}
+
+// sendDigests sends the digest of the last complete period to the subscribers of a frequency
+func (ds *DigestService) sendDigests(frequency string, triggerStatusIDs []uint, now time.Time) error {
+	periodEnd := models.DigestPeriodStart(frequency, now)
+	periodStart := models.PreviousDigestPeriodStart(frequency, periodEnd)
+
+	subscribers, err := models.GetDigestSubscribersDue(ds.db, frequency, periodEnd)
+	if err != nil {
+		return fmt.Errorf("failed to get subscribers: %v", err)
+	}
+	if len(subscribers) == 0 {
+		return nil
+	}
+
+	branding, err := models.GetBrandingSettingsWithBaseURL(ds.db, os.Getenv("BASE_URL"))
+	if err != nil {
+		return fmt.Errorf("failed to get branding settings: %v", err)
+	}
+
+	index, err := models.LoadTopicIndex(ds.db)
+	if err != nil {
+		return fmt.Errorf("failed to load topics: %v", err)
+	}
+
+	template := ds.getDigestTemplate()
+
+	// Send every digest of the run over one session
+	defer ds.emailService.Close()
+
+	// Subscribers who missed earlier periods only get the last one; items are shared between them
+	itemsByStart := make(map[int64][]models.DigestItem)
+	sentCount, skippedCount := 0, 0
+	// History records the batch: every item sent to anyone, over the longest period covered
+	var historyItems []models.DigestItem
+	historyEventIDs := make(map[uint]bool)
+	historyFrom := periodEnd
+
+	for i := range subscribers {
+		subscriber := &subscribers[i]
+
+		from := periodStart
+		if subscriber.LastDigestAt != nil && subscriber.LastDigestAt.After(from) {
+			from = *subscriber.LastDigestAt
+		}
+
+		items, ok := itemsByStart[from.Unix()]
+		if !ok {
+			items, err = models.GetDigestItems(ds.db, triggerStatusIDs, from, periodEnd)
+			if err != nil {
+				// Digests already went to others, so carry on and record the run
+				log.Printf("Digest: failed to get items for %s: %v", subscriber.Email, err)
+				continue
+			}
+			itemsByStart[from.Unix()] = items
+		}
+
+		items = models.FilterDigestItems(index, subscriber, items)
+		if len(items) == 0 {
+			skippedCount++
+			if err := models.MarkDigestSent(ds.db, subscriber, periodEnd); err != nil {
+				log.Printf("Digest: failed to update %s: %v", subscriber.Email, err)
+			}
+			continue
+		}
+
+		localizedTemplate, localizedBranding := email.Localize(template, branding, subscriber.Language)
+		digestContext := email.NewDigestContext(items, frequency, from, periodEnd, localizedBranding).ForSubscriber(subscriber)
+		// Leave the subscriber due on failure so the next check retries
+		subject, content, err := email.RenderEmailTemplate(localizedTemplate, digestContext)
+		if err != nil {
+			log.Printf("Digest: failed to render for %s: %v", subscriber.Email, err)
+			continue
+		}
+		text, err := email.RenderEmailText(localizedTemplate, digestContext)
+		if err != nil {
+			log.Printf("Digest: failed to render for %s: %v", subscriber.Email, err)
+			continue
+		}
+
+		if err := ds.emailService.SendEmailWithText(subscriber.Email, subject, content, text); err != nil {
+			log.Printf("Digest: failed to send to %s: %v", subscriber.Email, err)
+			continue
+		}
+		if err := models.MarkDigestSent(ds.db, subscriber, periodEnd); err != nil {
+			log.Printf("Digest: failed to update %s: %v", subscriber.Email, err)
+		}
+		sentCount++
+		for _, item := range items {
+			if !historyEventIDs[item.Event.ID] {
+				historyEventIDs[item.Event.ID] = true
+				historyItems = append(historyItems, item)
+			}
+		}
+		if from.Before(historyFrom) {
+			historyFrom = from
+		}
+	}
+
+	if sentCount > 0 {
+		sort.SliceStable(historyItems, func(i, j int) bool {
+			return historyItems[i].ChangedAt.After(historyItems[j].ChangedAt)
+		})
+		// History keeps a copy without any subscriber's personal links
+		subject, content, err := email.GenerateDigestEmailContent(template, historyItems, frequency, historyFrom, periodEnd, branding, nil)
+		if err != nil {
+			log.Printf("Digest: failed to render newsletter history: %v", err)
+		}
+		sentAt := time.Now()
+		history := &models.NewsletterHistory{
+			Subject:        subject,
+			Content:        content,
+			Status:         "sent",
+			RecipientCount: sentCount,
+			SentAt:         &sentAt,
+		}
+		if err := ds.db.Create(history).Error; err != nil {
+			log.Printf("Digest: failed to save newsletter history: %v", err)
+		}
+	}
+
+	log.Printf("Digest: %s digests sent to %d subscribers, %d skipped with nothing new", frequency, sentCount, skippedCount)
+	return nil
+}
+
+// PreviewDigest renders the digest of the period in progress with every item, as an admin preview
+func (ds *DigestService) PreviewDigest(frequency string, now time.Time) (string, string, []models.DigestItem, error) {
+	automationSettings, err := models.GetOrCreateAutomationSettings(ds.db)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	branding, err := models.GetBrandingSettingsWithBaseURL(ds.db, os.Getenv("BASE_URL"))
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	periodStart := models.DigestPeriodStart(frequency, now)
+	periodEnd := models.NextDigestPeriodStart(frequency, periodStart)
+
+	items, err := models.GetDigestItems(ds.db, automationSettings.GetTriggerStatusIDs(), periodStart, periodEnd)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	subject, content, err := email.GenerateDigestEmailContent(ds.getDigestTemplate(), items, frequency, periodStart, periodEnd, branding, nil)
+	if err != nil {
+		return "", "", nil, err
+	}
+	return subject, content, items, nil
+}
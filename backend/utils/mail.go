@@ -2,89 +2,67 @@ package utils
 
 import (
 	"crypto/tls"
+	"net"
 	"net/smtp"
 	"strings"
 )
 
-// SendMailWithTLS sends email using STARTTLS
-func SendMailWithTLS(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
-	client, err := smtp.Dial(addr)
-	if err != nil {
-		return err
-	}
-	defer client.Close()
-
-	// Start TLS
-	if err = client.StartTLS(&tls.Config{ServerName: strings.Split(addr, ":")[0]}); err != nil {
-		return err
-	}
-
-	// Authenticate
-	if auth != nil {
-		if err = client.Auth(auth); err != nil {
-			return err
+// DialSMTP connects to an SMTP server and authenticates. Encryption is "ssl" for implicit TLS,
+// "tls" for STARTTLS, or anything else for a plain connection that upgrades when the server offers STARTTLS.
+func DialSMTP(addr, encryption string, auth smtp.Auth) (*smtp.Client, error) {
+	host := strings.Split(addr, ":")[0]
+	tlsConfig := &tls.Config{ServerName: host}
+
+	var client *smtp.Client
+	switch strings.ToLower(encryption) {
+	case "ssl":
+		conn, err := tls.Dial("tcp", addr, tlsConfig)
+		if err != nil {
+			return nil, err
 		}
-	}
-
-	// Send email
-	if err = client.Mail(from); err != nil {
-		return err
-	}
-
-	for _, recipient := range to {
-		if err = client.Rcpt(recipient); err != nil {
-			return err
+		client, err = smtp.NewClient(conn, host)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	default:
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		client, err = smtp.NewClient(conn, host)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		startTLS, _ := client.Extension("STARTTLS")
+		if strings.ToLower(encryption) == "tls" || startTLS {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				client.Close()
+				return nil, err
+			}
 		}
 	}
 
-	writer, err := client.Data()
-	if err != nil {
-		return err
-	}
-
-	_, err = writer.Write(msg)
-	if err != nil {
-		return err
-	}
-
-	return writer.Close()
-}
-
-// SendMailWithSSL sends email using SSL/TLS
-func SendMailWithSSL(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
-	host := strings.Split(addr, ":")[0]
-
-	// Create TLS connection
-	tlsConfig := &tls.Config{
-		ServerName: host,
-	}
-
-	conn, err := tls.Dial("tcp", addr, tlsConfig)
-	if err != nil {
-		return err
-	}
-	defer conn.Close()
-
-	client, err := smtp.NewClient(conn, host)
-	if err != nil {
-		return err
-	}
-	defer client.Close()
-
 	// Authenticate
 	if auth != nil {
-		if err = client.Auth(auth); err != nil {
-			return err
+		if err := client.Auth(auth); err != nil {
+			client.Close()
+			return nil, err
 		}
 	}
 
-	// Send email
-	if err = client.Mail(from); err != nil {
+	return client, nil
+}
+
+// SendWithClient sends one message over an open SMTP session, which can then send the next one
+func SendWithClient(client *smtp.Client, from string, to []string, msg []byte) error {
+	if err := client.Mail(from); err != nil {
 		return err
 	}
 
 	for _, recipient := range to {
-		if err = client.Rcpt(recipient); err != nil {
+		if err := client.Rcpt(recipient); err != nil {
 			return err
 		}
 	}
@@ -94,8 +72,7 @@ func SendMailWithSSL(addr string, auth smtp.Auth, from string, to []string, msg
 		return err
 	}
 
-	_, err = writer.Write(msg)
-	if err != nil {
+	if _, err := writer.Write(msg); err != nil {
 		return err
 	}
 
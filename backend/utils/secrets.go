@@ -0,0 +1,293 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Secrets are stored as "enc:v1:<key id>:<wrapped data key>:<ciphertext>". Each value has its own
+// AES-256-GCM data key, wrapped with the master key, so rotating the master key only rewraps data keys.
+const secretPrefix = "enc:v1:"
+
+// DefaultSecretsKeyFile is where a master key is generated when none is configured. It is kept out
+// of ./data, so copies of the data directory don't carry the key that decrypts them.
+const DefaultSecretsKeyFile = "./secrets/secrets.key"
+
+// legacySecretsKeyFile is where earlier versions generated the master key
+const legacySecretsKeyFile = "./data/secrets.key"
+
+var (
+	secretsKeyMutex sync.Mutex
+	secretsKey      []byte
+)
+
+// ParseSecretsKey decodes a base64 master key of 32 bytes
+func ParseSecretsKey(encoded string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if key, err := encoding.DecodeString(encoded); err == nil && len(key) == 32 {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("the secrets key must be 32 random bytes, base64-encoded (openssl rand -base64 32)")
+}
+
+// GenerateSecretsKey returns a new base64 master key
+func GenerateSecretsKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// SecretsKeyFile returns the path of the master key file (SECRETS_KEY_FILE). Without it, a key
+// generated by an earlier version in the data directory is still used until it is moved.
+func SecretsKeyFile() string {
+	if path := os.Getenv("SECRETS_KEY_FILE"); path != "" {
+		return path
+	}
+	if _, err := os.Stat(DefaultSecretsKeyFile); os.IsNotExist(err) {
+		if _, err := os.Stat(legacySecretsKeyFile); err == nil {
+			return legacySecretsKeyFile
+		}
+	}
+	return DefaultSecretsKeyFile
+}
+
+// warnIfKeyNextToDatabase logs a warning when the key file is inside the database's directory,
+// where backups of the database would include it
+func warnIfKeyNextToDatabase(keyPath string) {
+	dbPath := os.Getenv("DB_PATH")
+	if dbPath == "" {
+		dbPath = "./data/changelog.db"
+	}
+	keyDir, err := filepath.Abs(filepath.Dir(keyPath))
+	if err != nil {
+		return
+	}
+	dbDir, err := filepath.Abs(filepath.Dir(dbPath))
+	if err != nil {
+		return
+	}
+	if keyDir == dbDir || strings.HasPrefix(keyDir, dbDir+string(filepath.Separator)) {
+		log.Printf("WARNING: the secrets key %s is stored with the database in %s. Anyone with a copy of that directory "+
+			"can decrypt the stored credentials: move the key elsewhere and set SECRETS_KEY_FILE, or use SECRETS_KEY.", keyPath, dbDir)
+	}
+}
+
+// WriteSecretsKeyFile writes a master key readable only by its owner
+func WriteSecretsKeyFile(path, encoded string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(encoded+"\n"), 0600)
+}
+
+// SecretsKey returns the master key from SECRETS_KEY, or from the key file. A key file is
+// generated on first use when neither exists.
+func SecretsKey() ([]byte, error) {
+	secretsKeyMutex.Lock()
+	defer secretsKeyMutex.Unlock()
+
+	if secretsKey != nil {
+		return secretsKey, nil
+	}
+
+	if encoded := os.Getenv("SECRETS_KEY"); encoded != "" {
+		key, err := ParseSecretsKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("SECRETS_KEY: %v", err)
+		}
+		secretsKey = key
+		return secretsKey, nil
+	}
+
+	path := SecretsKeyFile()
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		encoded, err := GenerateSecretsKey()
+		if err != nil {
+			return nil, err
+		}
+		if err := WriteSecretsKeyFile(path, encoded); err != nil {
+			return nil, fmt.Errorf("failed to write secrets key file: %v", err)
+		}
+		log.Printf("Generated a secrets key in %s. Back it up: stored credentials can't be read without it.", path)
+		content = []byte(encoded)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read secrets key file: %v", err)
+	}
+
+	key, err := ParseSecretsKey(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	warnIfKeyNextToDatabase(path)
+	secretsKey = key
+	return secretsKey, nil
+}
+
+// SetSecretsKey replaces the master key used by EncryptSecret and DecryptSecret
+func SetSecretsKey(key []byte) {
+	secretsKeyMutex.Lock()
+	defer secretsKeyMutex.Unlock()
+	secretsKey = key
+}
+
+// secretsKeyID identifies a master key without revealing it
+func secretsKeyID(key []byte) string {
+	sum := sha256.Sum256(append([]byte("shipshipship-secrets-key:"), key...))
+	return hex.EncodeToString(sum[:4])
+}
+
+func seal(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func unseal(key, sealed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted value is truncated")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+}
+
+// IsEncryptedSecret reports whether a stored value was produced by EncryptSecret
+func IsEncryptedSecret(value string) bool {
+	return strings.HasPrefix(value, secretPrefix)
+}
+
+// EncryptSecretWithKey encrypts a value under the given master key
+func EncryptSecretWithKey(plaintext string, masterKey []byte) (string, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	wrappedKey, err := seal(masterKey, dataKey)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return secretPrefix + secretsKeyID(masterKey) + ":" +
+		base64.RawStdEncoding.EncodeToString(wrappedKey) + ":" +
+		base64.RawStdEncoding.EncodeToString(ciphertext), nil
+}
+
+// parseSecret splits a stored value into its key ID, wrapped data key and ciphertext
+func parseSecret(value string) (string, []byte, []byte, error) {
+	parts := strings.Split(strings.TrimPrefix(value, secretPrefix), ":")
+	if !IsEncryptedSecret(value) || len(parts) != 3 {
+		return "", nil, nil, fmt.Errorf("malformed encrypted value")
+	}
+	wrappedKey, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("malformed encrypted value")
+	}
+	ciphertext, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("malformed encrypted value")
+	}
+	return parts[0], wrappedKey, ciphertext, nil
+}
+
+// unwrapDataKey decrypts the data key of a stored value with the master key
+func unwrapDataKey(keyID string, wrappedKey, masterKey []byte) ([]byte, error) {
+	if keyID != secretsKeyID(masterKey) {
+		return nil, fmt.Errorf("value was encrypted with another secrets key (%s, current key is %s)", keyID, secretsKeyID(masterKey))
+	}
+	dataKey, err := unseal(masterKey, wrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %v", err)
+	}
+	return dataKey, nil
+}
+
+// DecryptSecretWithKey decrypts a value stored by EncryptSecretWithKey
+func DecryptSecretWithKey(value string, masterKey []byte) (string, error) {
+	keyID, wrappedKey, ciphertext, err := parseSecret(value)
+	if err != nil {
+		return "", err
+	}
+	dataKey, err := unwrapDataKey(keyID, wrappedKey, masterKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := unseal(dataKey, ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %v", err)
+	}
+	return string(plaintext), nil
+}
+
+// RewrapSecret moves a stored value to a new master key. Only its data key is re-encrypted.
+func RewrapSecret(value string, oldKey, newKey []byte) (string, error) {
+	keyID, wrappedKey, ciphertext, err := parseSecret(value)
+	if err != nil {
+		return "", err
+	}
+	if keyID == secretsKeyID(newKey) {
+		return value, nil
+	}
+	dataKey, err := unwrapDataKey(keyID, wrappedKey, oldKey)
+	if err != nil {
+		return "", err
+	}
+	rewrapped, err := seal(newKey, dataKey)
+	if err != nil {
+		return "", err
+	}
+	return secretPrefix + secretsKeyID(newKey) + ":" +
+		base64.RawStdEncoding.EncodeToString(rewrapped) + ":" +
+		base64.RawStdEncoding.EncodeToString(ciphertext), nil
+}
+
+// EncryptSecret encrypts a value under the master key
+func EncryptSecret(plaintext string) (string, error) {
+	key, err := SecretsKey()
+	if err != nil {
+		return "", err
+	}
+	return EncryptSecretWithKey(plaintext, key)
+}
+
+// DecryptSecret decrypts a value stored by EncryptSecret
+func DecryptSecret(value string) (string, error) {
+	key, err := SecretsKey()
+	if err != nil {
+		return "", err
+	}
+	return DecryptSecretWithKey(value, key)
+}
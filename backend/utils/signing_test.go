@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpiringValue(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	now := time.Unix(1700000000, 0)
+	token, err := SignExpiringValue("preferences", "user@example.com", now, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := SignExpiringValue("confirm", "user@example.com", now, time.Hour)
+	future, _ := SignExpiringValue("preferences", "user@example.com", now.Add(time.Hour), time.Hour)
+	parts := strings.Split(token, ".")
+	extended := parts[0] + "." + strconv.FormatInt(now.Add(24*time.Hour).Unix(), 36) + "." + parts[2]
+
+	tests := []struct {
+		name  string
+		value string
+		token string
+		at    time.Time
+		want  bool
+	}{
+		{"valid", "user@example.com", token, now.Add(time.Minute), true},
+		{"at expiry", "user@example.com", token, now.Add(time.Hour), true},
+		{"expired", "user@example.com", token, now.Add(time.Hour + time.Second), false},
+		{"another value", "other@example.com", token, now, false},
+		{"another purpose", "user@example.com", other, now, false},
+		{"issued in the future", "user@example.com", future, now, false},
+		{"extended expiry", "user@example.com", extended, now, false},
+		{"unsigned", "user@example.com", "", now, false},
+		{"legacy token without times", "user@example.com", SignValue("preferences", "user@example.com"), now, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyExpiringValue("preferences", tt.value, tt.token, tt.at); got != tt.want {
+				t.Errorf("VerifyExpiringValue(%q) = %v, want %v", tt.token, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpiringValueDefaultSecret(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	now := time.Now()
+	token, _ := SignExpiringValue("preferences", "user@example.com", now, time.Hour)
+
+	for _, secret := range []string{"", defaultSigningSecret} {
+		t.Setenv("JWT_SECRET", secret)
+		if _, err := SignExpiringValue("preferences", "user@example.com", now, time.Hour); !errors.Is(err, ErrDefaultSigningSecret) {
+			t.Errorf("JWT_SECRET=%q: SignExpiringValue err = %v, want ErrDefaultSigningSecret", secret, err)
+		}
+		times := strings.Join(strings.Split(token, ".")[:2], ".")
+		forged := times + "." + SignValue("preferences", times+":user@example.com")
+		if VerifyExpiringValue("preferences", "user@example.com", forged, now) {
+			t.Errorf("JWT_SECRET=%q: accepted a token signed with the default secret", secret)
+		}
+	}
+}